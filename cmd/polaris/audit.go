@@ -40,24 +40,30 @@ import (
 )
 
 var (
-	setExitCode         bool
-	onlyShowFailedTests bool
-	minScore            int
-	auditOutputURL      string
-	auditOutputFile     string
-	auditOutputFormat   string
-	resourceToAudit     string
-	useColor            bool
-	helmChart           string
-	helmValues          []string
-	helmSkipTests       bool
-	checks              []string
-	auditNamespace      string
-	severityLevel       string
-	skipSslValidation   bool
-	uploadInsights      bool
-	clusterName         string
-	quiet               bool
+	setExitCode          bool
+	onlyShowFailedTests  bool
+	minScore             int
+	auditOutputURL       string
+	auditOutputFile      string
+	auditOutputFormat    string
+	resourceToAudit      string
+	useColor             bool
+	helmChart            string
+	helmValues           []string
+	helmSkipTests        bool
+	checks               []string
+	auditNamespace       string
+	severityLevel        string
+	sortBy               string
+	groupBy              string
+	skipSslValidation    bool
+	uploadInsights       bool
+	clusterName          string
+	quiet                bool
+	kubernetesVersion    string
+	validateSchema       bool
+	podSecurityStandards bool
+	crdPaths             []string
 )
 
 func init() {
@@ -68,7 +74,7 @@ func init() {
 	auditCmd.PersistentFlags().IntVar(&minScore, "set-exit-code-below-score", 0, "Set an exit code of 4 when the score is below this threshold (1-100).")
 	auditCmd.PersistentFlags().StringVar(&auditOutputURL, "output-url", "", "Destination URL to send audit results.")
 	auditCmd.PersistentFlags().StringVar(&auditOutputFile, "output-file", "", "Destination file for audit results.")
-	auditCmd.PersistentFlags().StringVarP(&auditOutputFormat, "format", "f", "json", "Output format for results - json, yaml, pretty, or score.")
+	auditCmd.PersistentFlags().StringVarP(&auditOutputFormat, "format", "f", "json", "Output format for results - json, yaml, pretty, markdown, score, compliance, or efficiency.")
 	auditCmd.PersistentFlags().BoolVar(&useColor, "color", true, "Whether to use color in pretty format.")
 	auditCmd.PersistentFlags().StringVar(&displayName, "display-name", "", "An optional identifier for the audit.")
 	auditCmd.PersistentFlags().StringVar(&resourceToAudit, "resource", "", "Audit a specific resource, in the format namespace/kind/version/name, e.g. nginx-ingress/Deployment.apps/v1/default-backend.")
@@ -78,10 +84,16 @@ func init() {
 	auditCmd.PersistentFlags().StringSliceVar(&checks, "checks", []string{}, "Optional flag to specify specific checks to check")
 	auditCmd.PersistentFlags().StringVar(&auditNamespace, "namespace", "", "Namespace to audit. Only applies to in-cluster audits")
 	auditCmd.PersistentFlags().StringVar(&severityLevel, "severity", "", "Severity level used to filter results. Behaves like log levels. 'danger' is the least verbose (warning, danger)")
+	auditCmd.PersistentFlags().StringVar(&sortBy, "sort", "", "Order of the results. 'risk' lists the riskiest resources first, weighing severity by exposure, RBAC, privileged containers and critical namespaces.")
+	auditCmd.PersistentFlags().StringVar(&groupBy, "group-by", "", "Group the results of the pretty and markdown formats. 'owner' groups them by the team owning each resource, with a score per team.")
 	auditCmd.PersistentFlags().BoolVar(&skipSslValidation, "skip-ssl-validation", false, "Skip https certificate verification")
 	auditCmd.PersistentFlags().BoolVar(&uploadInsights, "upload-insights", false, "Upload scan results to Fairwinds Insights")
 	auditCmd.PersistentFlags().StringVar(&clusterName, "cluster-name", "", "Set --cluster-name to a descriptive name for the cluster you're auditing")
 	auditCmd.PersistentFlags().BoolVar(&quiet, "quiet", false, "Suppress the 'upload to Insights' prompt.")
+	auditCmd.PersistentFlags().StringVar(&kubernetesVersion, "kubernetes-version", "", "Kubernetes version (e.g. 1.27) used to select version-specific checks. Defaults to the cluster version for in-cluster audits.")
+	auditCmd.PersistentFlags().BoolVar(&validateSchema, "validate-schema", false, "Validate resources against the Kubernetes API schema, reporting unknown fields, wrong types and missing required fields.")
+	auditCmd.PersistentFlags().BoolVar(&podSecurityStandards, "pod-security-standards", false, "Evaluate workloads against the Pod Security Standards, reporting the profile each workload and namespace satisfies.")
+	auditCmd.PersistentFlags().StringSliceVar(&crdPaths, "crd", []string{}, "CustomResourceDefinition files or directories used to validate custom resources when --validate-schema is set.")
 }
 
 var auditCmd = &cobra.Command{
@@ -92,6 +104,15 @@ var auditCmd = &cobra.Command{
 		if displayName != "" {
 			config.DisplayName = displayName
 		}
+		if podSecurityStandards {
+			config.EnablePodSecurityStandards()
+		}
+		if auditOutputFormat == "efficiency" {
+			config.EfficiencyReport = true
+		}
+		if auditOutputFormat == "compliance" {
+			config.ComplianceReport = true
+		}
 		if len(checks) > 0 {
 			targetChecks := make(map[string]bool)
 			for _, check := range checks {
@@ -112,6 +133,27 @@ var auditCmd = &cobra.Command{
 			}
 			config.Namespace = auditNamespace
 		}
+		if sortBy != "" && sortBy != "risk" {
+			logrus.Errorf("Invalid --sort %s: must be risk", sortBy)
+			os.Exit(1)
+		}
+		if groupBy != "" && groupBy != validator.GroupByOwner {
+			logrus.Errorf("Invalid --group-by %s: must be %s", groupBy, validator.GroupByOwner)
+			os.Exit(1)
+		}
+		if kubernetesVersion != "" {
+			if _, err := cfg.ParseKubernetesVersion(kubernetesVersion); err != nil {
+				logrus.Errorf("Invalid --kubernetes-version: %v", err)
+				os.Exit(1)
+			}
+			config.KubernetesVersion = kubernetesVersion
+		}
+		if validateSchema {
+			if severity := config.Checks["kubernetesSchema"]; severity == "" || severity == cfg.SeverityIgnore {
+				config.Checks["kubernetesSchema"] = cfg.SeverityDanger
+			}
+		}
+		config.CRDPaths = append(config.CRDPaths, crdPaths...)
 		if helmChart != "" {
 			var err error
 			auditPath, err = ProcessHelmTemplates(helmChart, helmValues, helmSkipTests)
@@ -182,7 +224,7 @@ var auditCmd = &cobra.Command{
 			os.Stderr.WriteString("\n\nSuccess! You can see your results at:")
 			os.Stderr.WriteString(fmt.Sprintf("\n\n%s/orgs/%s/clusters/%s/action-items\n\n", insightsHost, auth.Organization, clusterName))
 		} else {
-			outputAudit(auditData, auditOutputFile, auditOutputURL, auditOutputFormat, useColor, onlyShowFailedTests, severityLevel)
+			outputAudit(auditData, auditOutputFile, auditOutputURL, auditOutputFormat, useColor, onlyShowFailedTests, severityLevel, sortBy, groupBy)
 			if !quiet {
 				os.Stderr.WriteString("\n\n🚀 Upload your Polaris findings to Fairwinds Insights to see remediation advice, add teammates, integrate with Slack or Jira, and more:")
 				os.Stderr.WriteString("\n\n❯ polaris " + strings.Join(os.Args[1:], " ") + " --upload-insights --cluster-name=my-cluster\n\n")
@@ -238,7 +280,12 @@ func ProcessHelmTemplates(helmChart string, helmValues []string, helmSkipTests b
 	return dir, nil
 }
 
-func outputAudit(auditData validator.AuditData, outputFile, outputURL, outputFormat string, useColor bool, onlyShowFailedTests bool, severityLevel string) {
+func outputAudit(auditData validator.AuditData, outputFile, outputURL, outputFormat string, useColor bool, onlyShowFailedTests bool, severityLevel string, sortBy string, groupBy string) {
+	// Compliance controls count passing resources, so the report is built before results are filtered
+	var complianceReport validator.ComplianceReport
+	if outputFormat == "compliance" {
+		complianceReport = auditData.GetComplianceReport(config)
+	}
 	if onlyShowFailedTests {
 		auditData = auditData.RemoveSuccessfulResults()
 	}
@@ -251,6 +298,9 @@ func outputAudit(auditData validator.AuditData, outputFile, outputURL, outputFor
 			auditData = auditData.FilterResultsBySeverityLevel(cfg.SeverityWarning)
 		}
 	}
+	if sortBy == "risk" {
+		auditData = auditData.SortResultsByRisk()
+	}
 
 	var outputBytes []byte
 	var err error
@@ -263,7 +313,13 @@ func outputAudit(auditData validator.AuditData, outputFile, outputURL, outputFor
 			outputBytes, err = yaml.JSONToYAML(jsonBytes)
 		}
 	} else if outputFormat == "pretty" {
-		outputBytes = []byte(auditData.GetPrettyOutput(useColor))
+		outputBytes = []byte(auditData.GetGroupedPrettyOutput(useColor, groupBy))
+	} else if outputFormat == "markdown" {
+		outputBytes = []byte(auditData.GetMarkdownOutput(groupBy))
+	} else if outputFormat == "compliance" {
+		outputBytes = []byte(complianceReport.GetPrettyOutput(useColor))
+	} else if outputFormat == "efficiency" {
+		outputBytes = []byte(auditData.GetEfficiencyPrettyOutput(useColor))
 	} else {
 		outputBytes, err = json.MarshalIndent(auditData, "", "  ")
 	}
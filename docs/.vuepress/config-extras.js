@@ -48,6 +48,7 @@ module.exports = {
           "/customization/checks",
           "/customization/custom-checks",
           "/customization/exemptions",
+          "/customization/compliance",
         ]
       },
       {
@@ -58,6 +59,9 @@ module.exports = {
           "/checks/security",
           "/checks/efficiency",
           "/checks/reliability",
+          "/checks/schema",
+          "/checks/pod-security",
+          "/checks/nodes",
         ],
       },
     ]
@@ -31,6 +31,11 @@ var (
 		"hostIPCSet",
 		"hostPIDSet",
 		"hostNetworkSet",
+		"shareProcessNamespaceSet",
+		"hostUsersEnabled",
+		"unsafeSysctlsSet",
+		"seccompUnconfined",
+		"appArmorUnconfined",
 		"automountServiceAccountToken",
 		"topologySpreadConstraint",
 		// Container checks
@@ -43,6 +48,10 @@ var (
 		"pullPolicyNotAlways",
 		"tagNotSpecified",
 		"hostPortSet",
+		"hostPathVolumeSet",
+		"hostPathVolumeWritable",
+		"sensitiveHostPathMounted",
+		"procMountUnmasked",
 		"runAsRootAllowed",
 		"runAsPrivileged",
 		"notReadOnlyRootFilesystem",
@@ -54,11 +63,23 @@ var (
 		"sensitiveContainerEnvVar",
 		// Other checks
 		"tlsSettingsMissing",
+		"loadBalancerSourceRangesMissing",
+		"nodePortServiceSet",
+		"serviceExternalIPsSet",
+		"ingressWildcardHost",
+		"ingressDuplicateHostPath",
+		"gatewayListenerTLSMissing",
+		"httpRouteTLSMissing",
 		"pdbDisruptionsIsZero",
 		"metadataAndInstanceMismatched",
 		"missingPodDisruptionBudget",
 		"missingNetworkPolicy",
 		"sensitiveConfigmapContent",
+		"sensitiveContentDetected",
+		"imageRegistryNotAllowed",
+		"imageDigestMissing",
+		"imageTagNotAllowed",
+		"imageTagMutable",
 		"clusterrolePodExecAttach",
 		"rolePodExecAttach",
 		"clusterrolebindingPodExecAttach",
@@ -67,9 +88,74 @@ var (
 		"clusterrolebindingClusterAdmin",
 		"rolebindingClusterAdminClusterRole",
 		"rolebindingClusterAdminRole",
+		"clusterrolebindingEscalation",
+		"rolebindingEscalation",
+		"serviceAccountTokenReadsSecrets",
+		"serviceAccountTokenEscalation",
 		"hpaMaxAvailability",
 		"hpaMinAvailability",
 		"pdbMinAvailableGreaterThanHPAMinReplicas",
+		"hpaTargetRequestsMissing",
+		"hpaTargetReplicasSet",
+		"hpaMetricsRequireV2",
+		"deprecatedAPIVersion",
+		"kubernetesSchema",
+		"podOverheadMismatch",
+		"danglingConfigMapReference",
+		"danglingSecretReference",
+		"danglingServiceAccountReference",
+		"danglingPersistentVolumeClaimReference",
+		"danglingIngressBackend",
+		"danglingHPAScaleTargetRef",
+		"serviceSelectorMatchesNothing",
+		"jobBackoffLimitMissing",
+		"jobActiveDeadlineMissing",
+		"jobTTLSecondsAfterFinishedMissing",
+		"cronJobConcurrencyAllowed",
+		"cronJobStartingDeadlineMissing",
+		"cronJobHistoryLimitsMissing",
+		"cronJobScheduleTooFrequent",
+		"statefulSetStorageClassMissing",
+		"statefulSetRetentionPolicyMissing",
+		"statefulSetPodManagementParallel",
+		"statefulSetUpdateStrategyRisky",
+		"emptyDirSizeLimitMissing",
+		"probesIdentical",
+		"livenessProbeStartupMissing",
+		"terminationGracePeriodTooShort",
+		"rollingUpdateMaxUnavailableAll",
+		"singleReplicaPDBBlocksEviction",
+		"replicasNotSpread",
+		"namespaceResourceQuotaMissing",
+		"namespaceLimitRangeMissing",
+		"namespaceRequiredLabelsMissing",
+		"namespacePodSecurityEnforceMissing",
+		"namespaceDefaultInUse",
+		"nodeKubeletVersionSkew",
+		"nodeUnschedulableTooLong",
+		"nodeDedicatedPoolTaintMissing",
+		"nodeContainerRuntimeOutdated",
+		"nodeDeprecatedLabels",
+		"limitRequestRatioHigh",
+		"requestsExceedNodeShare",
+		// Pod Security Standards checks
+		"pssHostProcess",
+		"pssHostNamespaces",
+		"pssPrivilegedContainers",
+		"pssCapabilities",
+		"pssHostPathVolumes",
+		"pssHostPorts",
+		"pssAppArmor",
+		"pssSELinux",
+		"pssProcMountType",
+		"pssSeccomp",
+		"pssSysctls",
+		"pssVolumeTypes",
+		"pssPrivilegeEscalation",
+		"pssRunAsNonRoot",
+		"pssRunAsUser",
+		"pssSeccompRestricted",
+		"pssCapabilitiesRestricted",
 	}
 
 	// BuiltInChecks contains the checks that come pre-installed w/ Polaris
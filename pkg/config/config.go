@@ -22,23 +22,127 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"path"
+	"regexp"
 	"strings"
+	"time"
 
+	"k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/apimachinery/pkg/util/yaml"
 )
 
 // Configuration contains all of the config for the validation checks.
 type Configuration struct {
-	DisplayName                  string                 `json:"displayName"`
-	Checks                       map[string]Severity    `json:"checks"`
-	CustomChecks                 map[string]SchemaCheck `json:"customChecks"`
-	Exemptions                   []Exemption            `json:"exemptions"`
-	DisallowExemptions           bool                   `json:"disallowExemptions"`
-	DisallowConfigExemptions     bool                   `json:"disallowConfigExemptions"`
-	DisallowAnnotationExemptions bool                   `json:"disallowAnnotationExemptions"`
-	Mutations                    []string               `json:"mutations"`
-	KubeContext                  string                 `json:"kubeContext"`
-	Namespace                    string                 `json:"namespace"`
+	DisplayName                  string                  `json:"displayName"`
+	Checks                       map[string]Severity     `json:"checks"`
+	CustomChecks                 map[string]SchemaCheck  `json:"customChecks"`
+	Exemptions                   []Exemption             `json:"exemptions"`
+	DisallowExemptions           bool                    `json:"disallowExemptions"`
+	DisallowConfigExemptions     bool                    `json:"disallowConfigExemptions"`
+	DisallowAnnotationExemptions bool                    `json:"disallowAnnotationExemptions"`
+	Mutations                    []string                `json:"mutations"`
+	KubeContext                  string                  `json:"kubeContext"`
+	Namespace                    string                  `json:"namespace"`
+	KubernetesVersion            string                  `json:"kubernetesVersion"`
+	CRDPaths                     []string                `json:"crdPaths"`
+	PodSpecPaths                 map[string]PodSpecPaths `json:"podSpecPaths"`
+	PodSecurityStandards         bool                    `json:"podSecurityStandards"`
+	CronJobMinimumInterval       string                  `json:"cronJobMinimumInterval"`
+	AllowedStorageClasses        []string                `json:"allowedStorageClasses"`
+	ImagePolicy                  ImagePolicy             `json:"imagePolicy"`
+	NodePolicy                   NodePolicy              `json:"nodePolicy"`
+	EfficiencyPolicy             EfficiencyPolicy        `json:"efficiencyPolicy"`
+	EfficiencyReport             bool                    `json:"efficiencyReport"`
+	ComplianceReport             bool                    `json:"complianceReport"`
+	Ownership                    Ownership               `json:"ownership"`
+	// RequiredNamespaceLabels replaces DefaultRequiredNamespaceLabels when set
+	RequiredNamespaceLabels []string `json:"requiredNamespaceLabels"`
+	// CriticalNamespaces are glob patterns of namespaces whose findings are riskier, see Result.Risk
+	CriticalNamespaces []string `json:"criticalNamespaces"`
+}
+
+// ImagePolicy configures the image provenance checks. Registries and namespaces are glob patterns.
+type ImagePolicy struct {
+	AllowedRegistries []string `json:"allowedRegistries"`
+	DeniedRegistries  []string `json:"deniedRegistries"`
+	// DigestNamespaces limits imageDigestMissing to some namespaces, or applies it everywhere when empty
+	DigestNamespaces []string `json:"digestNamespaces"`
+	TagPattern       string   `json:"tagPattern"`
+	// MutableTags replaces DefaultMutableTags when set
+	MutableTags []string `json:"mutableTags"`
+	// DigestLockFile maps image references to the digests used by the imageDigestMissing mutation
+	DigestLockFile string `json:"digestLockFile"`
+}
+
+// NodePolicy configures the Node checks
+type NodePolicy struct {
+	// UnschedulableMaximum is how long a Node can be cordoned, e.g. 24h
+	UnschedulableMaximum string `json:"unschedulableMaximum"`
+	// DedicatedLabels are the labels, as `key` or `key=value`, of Nodes in dedicated pools, which should be tainted
+	DedicatedLabels []string `json:"dedicatedLabels"`
+	// MinimumRuntimeVersions overrides DefaultMinimumRuntimeVersions for some container runtimes, e.g. containerd
+	MinimumRuntimeVersions map[string]string `json:"minimumRuntimeVersions"`
+}
+
+// EfficiencyPolicy configures the resource efficiency checks
+type EfficiencyPolicy struct {
+	// MaximumLimitRequestRatio is the largest ratio of a container's CPU or memory limit to its request, e.g. 4
+	MaximumLimitRequestRatio float64 `json:"maximumLimitRequestRatio"`
+	// MaximumNodeSharePercent is the largest share of the biggest Node's allocatable CPU or memory a pod can request
+	MaximumNodeSharePercent int `json:"maximumNodeSharePercent"`
+}
+
+// Ownership configures how results are attributed to the teams that can fix them
+type Ownership struct {
+	// Labels are the labels or annotations of a resource naming its owner, in order of precedence
+	Labels []string `json:"labels"`
+	// NamespaceLabels name the owner of the resources in a Namespace, when a resource has none of Labels
+	NamespaceLabels []string `json:"namespaceLabels"`
+	// CodeOwnersFile is a CODEOWNERS-style file mapping the files of --audit-path audits to their owners
+	CodeOwnersFile string `json:"codeOwnersFile"`
+}
+
+// DefaultOwnerLabels are used when ownership.labels isn't set
+var DefaultOwnerLabels = []string{"team", "owner", "app.kubernetes.io/part-of"}
+
+// DefaultNamespaceOwnerLabels are used when ownership.namespaceLabels isn't set
+var DefaultNamespaceOwnerLabels = []string{"team", "owner"}
+
+// DefaultMaximumLimitRequestRatio is used when efficiencyPolicy.maximumLimitRequestRatio isn't set
+const DefaultMaximumLimitRequestRatio = 4
+
+// DefaultMaximumNodeSharePercent is used when efficiencyPolicy.maximumNodeSharePercent isn't set
+const DefaultMaximumNodeSharePercent = 50
+
+// DefaultNodeUnschedulableMaximum is how long a Node can be cordoned when nodePolicy.unschedulableMaximum isn't set
+const DefaultNodeUnschedulableMaximum = 24 * time.Hour
+
+// DefaultDedicatedNodeLabels mark Nodes in dedicated pools when nodePolicy.dedicatedLabels isn't set
+var DefaultDedicatedNodeLabels = []string{"dedicated"}
+
+// DefaultMinimumRuntimeVersions are the oldest supported versions of each container runtime
+var DefaultMinimumRuntimeVersions = map[string]string{
+	"containerd": "1.7.0",
+	"cri-o":      "1.28.0",
+	"docker":     "24.0.0",
+}
+
+// DefaultMutableTags are tags that are commonly moved to newer images
+var DefaultMutableTags = []string{"main", "master", "stable", "dev", "develop", "edge", "nightly", "canary", "snapshot"}
+
+// DefaultRequiredNamespaceLabels are the labels namespaceRequiredLabelsMissing expects on every Namespace
+var DefaultRequiredNamespaceLabels = []string{"owner", "cost-center"}
+
+// DefaultCronJobMinimumInterval is the shortest interval allowed between CronJob runs when
+// cronJobMinimumInterval isn't set
+const DefaultCronJobMinimumInterval = 5 * time.Minute
+
+// PodSpecPaths tells Polaris where a workload kind keeps its pods, using field paths like `spec.template`.
+// Exactly one of PodTemplate, PodSpec or Containers must be set.
+type PodSpecPaths struct {
+	PodTemplate string   `json:"podTemplate"`
+	PodSpec     string   `json:"podSpec"`
+	Containers  []string `json:"containers"`
 }
 
 // Exemption represents an exemption to normal rules
@@ -88,6 +192,9 @@ func Parse(rawBytes []byte) (Configuration, error) {
 			return conf, fmt.Errorf("Decoding config failed: %v", err)
 		}
 	}
+	if conf.PodSecurityStandards {
+		conf.EnablePodSecurityStandards()
+	}
 	for key, check := range conf.CustomChecks {
 		err := check.Initialize(key)
 		if err != nil {
@@ -106,5 +213,163 @@ func (conf Configuration) Validate() error {
 	if len(conf.Checks) == 0 {
 		return errors.New("No checks were enabled")
 	}
+	if _, err := ParseKubernetesVersion(conf.KubernetesVersion); err != nil {
+		return err
+	}
+	for groupKind, paths := range conf.PodSpecPaths {
+		set := 0
+		if paths.PodTemplate != "" {
+			set++
+		}
+		if paths.PodSpec != "" {
+			set++
+		}
+		if len(paths.Containers) > 0 {
+			set++
+		}
+		if set != 1 {
+			return fmt.Errorf("podSpecPaths for %s must set exactly one of podTemplate, podSpec or containers", groupKind)
+		}
+	}
+	if err := conf.ImagePolicy.validate(); err != nil {
+		return err
+	}
+	if err := conf.NodePolicy.validate(); err != nil {
+		return err
+	}
+	if err := conf.EfficiencyPolicy.validate(); err != nil {
+		return err
+	}
+	for _, pattern := range conf.CriticalNamespaces {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid criticalNamespaces pattern %s: %v", pattern, err)
+		}
+	}
+	if conf.CronJobMinimumInterval != "" {
+		if _, err := time.ParseDuration(conf.CronJobMinimumInterval); err != nil {
+			return fmt.Errorf("invalid cronJobMinimumInterval %s: %v", conf.CronJobMinimumInterval, err)
+		}
+	}
+	return nil
+}
+
+func (policy ImagePolicy) validate() error {
+	for _, patterns := range [][]string{policy.AllowedRegistries, policy.DeniedRegistries, policy.DigestNamespaces} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid imagePolicy pattern %s: %v", pattern, err)
+			}
+		}
+	}
+	if _, err := regexp.Compile(policy.TagPattern); err != nil {
+		return fmt.Errorf("invalid imagePolicy tagPattern %s: %v", policy.TagPattern, err)
+	}
+	return nil
+}
+
+func (policy NodePolicy) validate() error {
+	if policy.UnschedulableMaximum != "" {
+		if _, err := time.ParseDuration(policy.UnschedulableMaximum); err != nil {
+			return fmt.Errorf("invalid nodePolicy unschedulableMaximum %s: %v", policy.UnschedulableMaximum, err)
+		}
+	}
+	for runtime, minimum := range policy.MinimumRuntimeVersions {
+		if _, err := version.ParseGeneric(minimum); err != nil {
+			return fmt.Errorf("invalid nodePolicy minimumRuntimeVersions for %s: %v", runtime, err)
+		}
+	}
+	return nil
+}
+
+func (policy EfficiencyPolicy) validate() error {
+	if policy.MaximumLimitRequestRatio != 0 && policy.MaximumLimitRequestRatio < 1 {
+		return fmt.Errorf("invalid efficiencyPolicy maximumLimitRequestRatio %v: must be at least 1", policy.MaximumLimitRequestRatio)
+	}
+	if policy.MaximumNodeSharePercent < 0 || policy.MaximumNodeSharePercent > 100 {
+		return fmt.Errorf("invalid efficiencyPolicy maximumNodeSharePercent %d: must be between 0 and 100", policy.MaximumNodeSharePercent)
+	}
 	return nil
 }
+
+// GetMaximumLimitRequestRatio returns the largest ratio of a container's limit to its request
+func (policy EfficiencyPolicy) GetMaximumLimitRequestRatio() float64 {
+	if policy.MaximumLimitRequestRatio > 0 {
+		return policy.MaximumLimitRequestRatio
+	}
+	return DefaultMaximumLimitRequestRatio
+}
+
+// GetMaximumNodeSharePercent returns the largest share of a Node's allocatable resources a pod can request
+func (policy EfficiencyPolicy) GetMaximumNodeSharePercent() int {
+	if policy.MaximumNodeSharePercent > 0 {
+		return policy.MaximumNodeSharePercent
+	}
+	return DefaultMaximumNodeSharePercent
+}
+
+// GetLabels returns the labels or annotations of a resource naming its owner
+func (ownership Ownership) GetLabels() []string {
+	if len(ownership.Labels) > 0 {
+		return ownership.Labels
+	}
+	return DefaultOwnerLabels
+}
+
+// GetNamespaceLabels returns the labels of a Namespace naming the owner of its resources
+func (ownership Ownership) GetNamespaceLabels() []string {
+	if len(ownership.NamespaceLabels) > 0 {
+		return ownership.NamespaceLabels
+	}
+	return DefaultNamespaceOwnerLabels
+}
+
+// GetUnschedulableMaximum returns how long a Node can be cordoned
+func (policy NodePolicy) GetUnschedulableMaximum() time.Duration {
+	maximum, err := time.ParseDuration(policy.UnschedulableMaximum)
+	if err != nil {
+		return DefaultNodeUnschedulableMaximum
+	}
+	return maximum
+}
+
+// GetDedicatedLabels returns the labels of Nodes in dedicated pools
+func (policy NodePolicy) GetDedicatedLabels() []string {
+	if len(policy.DedicatedLabels) > 0 {
+		return policy.DedicatedLabels
+	}
+	return DefaultDedicatedNodeLabels
+}
+
+// GetMinimumRuntimeVersion returns the oldest supported version of a container runtime, if it's known
+func (policy NodePolicy) GetMinimumRuntimeVersion(runtime string) (string, bool) {
+	if minimum, ok := policy.MinimumRuntimeVersions[runtime]; ok {
+		return minimum, true
+	}
+	minimum, ok := DefaultMinimumRuntimeVersions[runtime]
+	return minimum, ok
+}
+
+// GetMutableTags returns the tags imageTagMutable rejects
+func (policy ImagePolicy) GetMutableTags() []string {
+	if len(policy.MutableTags) > 0 {
+		return policy.MutableTags
+	}
+	return DefaultMutableTags
+}
+
+// GetRequiredNamespaceLabels returns the labels namespaceRequiredLabelsMissing expects on every Namespace
+func (conf Configuration) GetRequiredNamespaceLabels() []string {
+	if len(conf.RequiredNamespaceLabels) > 0 {
+		return conf.RequiredNamespaceLabels
+	}
+	return DefaultRequiredNamespaceLabels
+}
+
+// GetCronJobMinimumInterval returns the shortest interval allowed between CronJob runs
+func (conf Configuration) GetCronJobMinimumInterval() time.Duration {
+	interval, err := time.ParseDuration(conf.CronJobMinimumInterval)
+	if err != nil {
+		return DefaultCronJobMinimumInterval
+	}
+	return interval
+}
@@ -180,6 +180,97 @@ func TestCustomChecksMissingSeverity(t *testing.T) {
 	assert.Error(t, err, "Expected error when check has no severity set")
 }
 
+func TestPodSpecPathsValidation(t *testing.T) {
+	parsedConf, err := Parse([]byte(`
+checks:
+  pullPolicyNotAlways: warning
+podSpecPaths:
+  argoproj.io/Rollout:
+    podTemplate: spec.template
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, "spec.template", parsedConf.PodSpecPaths["argoproj.io/Rollout"].PodTemplate)
+
+	_, err = Parse([]byte(`
+checks:
+  pullPolicyNotAlways: warning
+podSpecPaths:
+  argoproj.io/Rollout:
+    podTemplate: spec.template
+    podSpec: spec.template.spec
+`))
+	assert.EqualError(t, err, "podSpecPaths for argoproj.io/Rollout must set exactly one of podTemplate, podSpec or containers")
+}
+
+func TestCronJobMinimumInterval(t *testing.T) {
+	parsedConf, err := Parse([]byte(`
+checks:
+  cronJobScheduleTooFrequent: warning
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultCronJobMinimumInterval, parsedConf.GetCronJobMinimumInterval())
+
+	parsedConf, err = Parse([]byte(`
+checks:
+  cronJobScheduleTooFrequent: warning
+cronJobMinimumInterval: 1h
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, parsedConf.GetCronJobMinimumInterval())
+
+	_, err = Parse([]byte(`
+checks:
+  cronJobScheduleTooFrequent: warning
+cronJobMinimumInterval: hourly
+`))
+	assert.EqualError(t, err, `invalid cronJobMinimumInterval hourly: time: invalid duration "hourly"`)
+}
+
+func TestImagePolicy(t *testing.T) {
+	parsedConf, err := Parse([]byte(`
+checks:
+  imageTagMutable: warning
+imagePolicy:
+  allowedRegistries: ["*.gcr.io"]
+  tagPattern: ^v[0-9]+$
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"*.gcr.io"}, parsedConf.ImagePolicy.AllowedRegistries)
+	assert.Equal(t, DefaultMutableTags, parsedConf.ImagePolicy.GetMutableTags())
+
+	_, err = Parse([]byte(`
+checks:
+  imageRegistryNotAllowed: danger
+imagePolicy:
+  deniedRegistries: ["[docker.io"]
+`))
+	assert.EqualError(t, err, `invalid imagePolicy pattern [docker.io: syntax error in pattern`)
+
+	_, err = Parse([]byte(`
+checks:
+  imageTagNotAllowed: warning
+imagePolicy:
+  tagPattern: "v[0-9"
+`))
+	assert.EqualError(t, err, "invalid imagePolicy tagPattern v[0-9: error parsing regexp: missing closing ]: `[0-9`")
+}
+
+func TestPodSecurityStandardsConfig(t *testing.T) {
+	parsedConf, err := Parse([]byte(`
+podSecurityStandards: true
+checks:
+  pssHostNamespaces: warning
+  pssRunAsNonRoot: ignore
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, SeverityWarning, parsedConf.Checks["pssHostNamespaces"])
+	assert.Equal(t, SeverityIgnore, parsedConf.Checks["pssRunAsNonRoot"])
+	assert.Equal(t, SeverityDanger, parsedConf.Checks["pssCapabilitiesRestricted"])
+	assert.Equal(t, PodSecurityLevelBaseline, BuiltInChecks["pssHostNamespaces"].PodSecurityLevel)
+	assert.True(t, PodSecurityLevelRestricted.Includes(PodSecurityLevelBaseline))
+	assert.False(t, PodSecurityLevelBaseline.Includes(PodSecurityLevelRestricted))
+}
+
 func testParsedConfig(t *testing.T, config *Configuration) {
 	assert.Equal(t, SeverityWarning, config.Checks["cpuRequestsMissing"])
 	assert.Equal(t, Severity(""), config.Checks["cpuLimitsMissing"])
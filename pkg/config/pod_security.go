@@ -0,0 +1,65 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+// PodSecurityLevel is a profile of the Kubernetes Pod Security Standards
+type PodSecurityLevel string
+
+const (
+	// PodSecurityLevelPrivileged is the unrestricted profile
+	PodSecurityLevelPrivileged PodSecurityLevel = "privileged"
+	// PodSecurityLevelBaseline prevents known privilege escalations
+	PodSecurityLevelBaseline PodSecurityLevel = "baseline"
+	// PodSecurityLevelRestricted follows pod hardening best practices
+	PodSecurityLevelRestricted PodSecurityLevel = "restricted"
+)
+
+// PodSecurityLevels lists the Pod Security Standards profiles, from the least to the most restrictive
+var PodSecurityLevels = []PodSecurityLevel{
+	PodSecurityLevelPrivileged,
+	PodSecurityLevelBaseline,
+	PodSecurityLevelRestricted,
+}
+
+// Includes returns true if the controls of the other level are part of this level.
+// Every control of the baseline profile is also part of the restricted profile.
+func (level PodSecurityLevel) Includes(other PodSecurityLevel) bool {
+	return level.rank() >= other.rank()
+}
+
+func (level PodSecurityLevel) rank() int {
+	for idx, l := range PodSecurityLevels {
+		if l == level {
+			return idx
+		}
+	}
+	return -1
+}
+
+// EnablePodSecurityStandards turns on every check that evaluates a Pod Security Standards control
+// with a danger severity, unless the configuration already sets a severity for it
+func (conf *Configuration) EnablePodSecurityStandards() {
+	if conf.Checks == nil {
+		conf.Checks = map[string]Severity{}
+	}
+	for checkID, check := range BuiltInChecks {
+		if check.PodSecurityLevel == "" {
+			continue
+		}
+		if _, ok := conf.Checks[checkID]; !ok {
+			conf.Checks[checkID] = SeverityDanger
+		}
+	}
+}
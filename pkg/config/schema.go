@@ -42,6 +42,10 @@ const (
 	TargetPodSpec TargetKind = "PodSpec"
 	// TargetPodTemplate points to the pod template
 	TargetPodTemplate TargetKind = "PodTemplate"
+	// TargetAny points to every resource, regardless of its kind
+	TargetAny TargetKind = "Any"
+	// TargetNode points to the cluster's Nodes
+	TargetNode TargetKind = "Node"
 )
 
 // HandledTargets is a list of target names that are explicitly handled
@@ -50,8 +54,21 @@ var HandledTargets = []TargetKind{
 	TargetContainer,
 	TargetPodSpec,
 	TargetPodTemplate,
+	TargetAny,
 }
 
+// ContainerClass is the list of a pod spec a container belongs to
+type ContainerClass string
+
+const (
+	// ContainerClassContainer is for containers in `containers`
+	ContainerClassContainer ContainerClass = "container"
+	// ContainerClassInit is for containers in `initContainers`
+	ContainerClassInit ContainerClass = "initContainer"
+	// ContainerClassEphemeral is for containers in `ephemeralContainers`, e.g. added by `kubectl debug`
+	ContainerClassEphemeral ContainerClass = "ephemeralContainer"
+)
+
 // Mutation defines how to change a YAML file, in the style of JSON Patch
 type Mutation struct {
 	Path    string
@@ -76,7 +93,22 @@ type SchemaCheck struct {
 	AdditionalSchemas       map[string]map[string]interface{} `yaml:"additionalSchemas" json:"additionalSchemas"`
 	AdditionalSchemaStrings map[string]string                 `yaml:"additionalSchemaStrings" json:"additionalSchemaStrings"`
 	AdditionalValidators    map[string]jsonschema.RootSchema  `yaml:"-" json:"-"`
+	AdditionalKinds         []TargetKind                      `yaml:"additionalKinds" json:"additionalKinds"`
 	Mutations               []Mutation                        `yaml:"mutations" json:"mutations"`
+	MinKubernetesVersion    string                            `yaml:"minKubernetesVersion" json:"minKubernetesVersion"`
+	MaxKubernetesVersion    string                            `yaml:"maxKubernetesVersion" json:"maxKubernetesVersion"`
+	SchemaVariants          []SchemaVariant                   `yaml:"schemaVariants" json:"schemaVariants"`
+	PodSecurityLevel        PodSecurityLevel                  `yaml:"podSecurityLevel" json:"podSecurityLevel"`
+	Compliance              map[string][]string               `yaml:"compliance" json:"compliance"`
+}
+
+// SchemaVariant replaces the schema, and optionally the containers, of a check for a range of Kubernetes versions
+type SchemaVariant struct {
+	MinKubernetesVersion string                 `yaml:"minKubernetesVersion" json:"minKubernetesVersion"`
+	MaxKubernetesVersion string                 `yaml:"maxKubernetesVersion" json:"maxKubernetesVersion"`
+	Containers           *includeExcludeList    `yaml:"containers" json:"containers"`
+	Schema               map[string]interface{} `yaml:"schema" json:"schema"`
+	SchemaString         string                 `yaml:"schemaString" json:"schemaString"`
 }
 
 type resourceMinimum string
@@ -111,6 +143,12 @@ func ParseCheck(id string, rawBytes []byte) (SchemaCheck, error) {
 func init() {
 	jsonschema.RegisterValidator("resourceMinimum", newResourceMinimum)
 	jsonschema.RegisterValidator("resourceMaximum", newResourceMaximum)
+	jsonschema.RegisterValidator("resourceRatioMaximum", newResourceRatioMaximum)
+	jsonschema.RegisterValidator("durationMinimum", newDurationMinimum)
+	jsonschema.RegisterValidator("durationMaximum", newDurationMaximum)
+	jsonschema.RegisterValidator("semverMinimum", newSemverMinimum)
+	jsonschema.RegisterValidator("cidrWithin", newCIDRWithin)
+	jsonschema.RegisterValidator("labelSelectorMatches", newLabelSelectorMatches)
 }
 
 type includeExcludeList struct {
@@ -209,9 +247,51 @@ func (check *SchemaCheck) Initialize(id string) error {
 	}
 	check.Schema = map[string]interface{}{}
 	check.AdditionalSchemas = map[string]map[string]interface{}{}
+	if err := validateVersionRange(check.MinKubernetesVersion, check.MaxKubernetesVersion); err != nil {
+		return fmt.Errorf("check %s: %w", id, err)
+	}
+	for idx, variant := range check.SchemaVariants {
+		if err := validateVersionRange(variant.MinKubernetesVersion, variant.MaxKubernetesVersion); err != nil {
+			return fmt.Errorf("check %s: schema variant %d: %w", id, idx, err)
+		}
+		if variant.SchemaString == "" {
+			jsonBytes, err := json.Marshal(variant.Schema)
+			if err != nil {
+				return err
+			}
+			variant.SchemaString = string(jsonBytes)
+		}
+		variant.Schema = map[string]interface{}{}
+		check.SchemaVariants[idx] = variant
+	}
 	return nil
 }
 
+// IsApplicableToVersion decides if this check applies to a particular Kubernetes version.
+// Checks always apply when the version is unknown.
+func (check SchemaCheck) IsApplicableToVersion(version KubernetesVersion) bool {
+	return versionInRange(check.MinKubernetesVersion, check.MaxKubernetesVersion, version)
+}
+
+// ForKubernetesVersion returns a copy of the check using the first schema variant
+// that matches the Kubernetes version, or the check itself if none match
+func (check SchemaCheck) ForKubernetesVersion(version KubernetesVersion) SchemaCheck {
+	if version.IsZero() {
+		return check
+	}
+	for _, variant := range check.SchemaVariants {
+		if versionInRange(variant.MinKubernetesVersion, variant.MaxKubernetesVersion, version) {
+			newCheck := check
+			newCheck.SchemaString = variant.SchemaString
+			if variant.Containers != nil {
+				newCheck.Containers = *variant.Containers
+			}
+			return newCheck
+		}
+	}
+	return check
+}
+
 // TemplateForResource fills out a check's templated fields given a particular resource
 func (check SchemaCheck) TemplateForResource(res interface{}) (*SchemaCheck, error) {
 	newCheck := check // Make a copy of the check, since we're going to modify the schema
@@ -321,7 +401,7 @@ func (check SchemaCheck) CheckAdditionalObjects(groupkind string, objects []inte
 }
 
 // IsActionable decides if this check applies to a particular target
-func (check SchemaCheck) IsActionable(target TargetKind, kind string, isInit bool) bool {
+func (check SchemaCheck) IsActionable(target TargetKind, kind string, containerClass ContainerClass) bool {
 	if funk.Contains(HandledTargets, target) {
 		if check.Target == TargetPodTemplate && target == TargetPodSpec {
 			// A target=PodSpec and check.Target=PodTemplate is expected
@@ -331,7 +411,7 @@ func (check SchemaCheck) IsActionable(target TargetKind, kind string, isInit boo
 		if check.Target != target {
 			return false
 		}
-	} else if string(check.Target) != kind && !strings.HasSuffix(string(check.Target), "/"+kind) {
+	} else if check.Target != TargetAny && string(check.Target) != kind && !strings.HasSuffix(string(check.Target), "/"+kind) {
 		return false
 	}
 	isIncluded := len(check.Controllers.Include) == 0
@@ -352,7 +432,7 @@ func (check SchemaCheck) IsActionable(target TargetKind, kind string, isInit boo
 	if check.Target == TargetContainer {
 		isIncluded := len(check.Containers.Include) == 0
 		for _, inclusion := range check.Containers.Include {
-			if (inclusion == "initContainer" && isInit) || (inclusion == "container" && !isInit) {
+			if inclusion == string(containerClass) {
 				isIncluded = true
 				break
 			}
@@ -361,7 +441,7 @@ func (check SchemaCheck) IsActionable(target TargetKind, kind string, isInit boo
 			return false
 		}
 		for _, exclusion := range check.Containers.Exclude {
-			if (exclusion == "initContainer" && isInit) || (exclusion == "container" && !isInit) {
+			if exclusion == string(containerClass) {
 				return false
 			}
 		}
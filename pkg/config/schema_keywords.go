@@ -0,0 +1,290 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qri-io/jsonschema"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// resourceRatioMaximum is the largest ratio of limits to requests in a `resources` object. It's either a number,
+// for every resource, or a map of resource names to numbers.
+type resourceRatioMaximum map[string]float64
+
+type durationMinimum string
+type durationMaximum string
+type semverMinimum string
+
+// cidrWithin is a CIDR, or a list of CIDRs, that an IP address or CIDR must be contained in
+type cidrWithin []string
+
+// labelSelectorMatches is a label selector, like `app=web,tier in (frontend, backend)`, that labels must match
+type labelSelectorMatches string
+
+func newResourceRatioMaximum() jsonschema.Validator {
+	return new(resourceRatioMaximum)
+}
+
+func newDurationMinimum() jsonschema.Validator {
+	return new(durationMinimum)
+}
+
+func newDurationMaximum() jsonschema.Validator {
+	return new(durationMaximum)
+}
+
+func newSemverMinimum() jsonschema.Validator {
+	return new(semverMinimum)
+}
+
+func newCIDRWithin() jsonschema.Validator {
+	return new(cidrWithin)
+}
+
+func newLabelSelectorMatches() jsonschema.Validator {
+	return new(labelSelectorMatches)
+}
+
+// UnmarshalJSON accepts a number or a map of resource names to numbers
+func (max *resourceRatioMaximum) UnmarshalJSON(data []byte) error {
+	var ratio float64
+	if err := json.Unmarshal(data, &ratio); err == nil {
+		*max = resourceRatioMaximum{"": ratio}
+		return nil
+	}
+	ratios := map[string]float64{}
+	if err := json.Unmarshal(data, &ratios); err != nil {
+		return errors.New("resourceRatioMaximum must be a number, or a map of resource names to numbers")
+	}
+	*max = ratios
+	return nil
+}
+
+// MarshalJSON returns a number if the ratio applies to every resource
+func (max resourceRatioMaximum) MarshalJSON() ([]byte, error) {
+	if ratio, ok := max[""]; ok && len(max) == 1 {
+		return json.Marshal(ratio)
+	}
+	return json.Marshal(map[string]float64(max))
+}
+
+// Validate checks that no limit is more than the maximum ratio times its request. Resources without a request are
+// skipped, since the request defaults to the limit.
+func (max resourceRatioMaximum) Validate(path string, data interface{}, errs *[]jsonschema.ValError) {
+	resources, ok := data.(map[string]interface{})
+	if !ok {
+		return
+	}
+	limits, _ := resources["limits"].(map[string]interface{})
+	requests, _ := resources["requests"].(map[string]interface{})
+	names := make([]string, 0, len(limits))
+	for name := range limits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		maxRatio, ok := max[name]
+		if !ok {
+			maxRatio, ok = max[""]
+		}
+		request, hasRequest := requests[name]
+		if !ok || !hasRequest {
+			continue
+		}
+		limitQuantity, err := parseQuantity(limits[name])
+		if err != nil {
+			*errs = append(*errs, *err...)
+			continue
+		}
+		requestQuantity, err := parseQuantity(request)
+		if err != nil {
+			*errs = append(*errs, *err...)
+			continue
+		}
+		if requestQuantity.IsZero() {
+			continue
+		}
+		ratio := limitQuantity.AsApproximateFloat64() / requestQuantity.AsApproximateFloat64()
+		if ratio > maxRatio {
+			*errs = append(*errs, jsonschema.ValError{
+				Message: fmt.Sprintf("%s %s limit %s is %.1fx the request %s, more than %vx", path, name, limitQuantity.String(), ratio, requestQuantity.String(), maxRatio),
+			})
+		}
+	}
+}
+
+func parseDuration(i interface{}) (time.Duration, *[]jsonschema.ValError) {
+	durationStr, ok := i.(string)
+	if !ok {
+		return 0, &[]jsonschema.ValError{
+			{Message: fmt.Sprintf("Duration %v is not a string", i)},
+		}
+	}
+	duration, err := time.ParseDuration(durationStr)
+	if err != nil {
+		return 0, &[]jsonschema.ValError{
+			{Message: fmt.Sprintf("Could not parse duration: %s", durationStr)},
+		}
+	}
+	return duration, nil
+}
+
+func validateDurationRange(path string, limit string, data interface{}, isMinimum bool) *[]jsonschema.ValError {
+	limitDuration, err := parseDuration(limit)
+	if err != nil {
+		return err
+	}
+	actualDuration, err := parseDuration(data)
+	if err != nil {
+		return err
+	}
+	if isMinimum && actualDuration < limitDuration {
+		return &[]jsonschema.ValError{
+			{Message: fmt.Sprintf("%s duration %v is shorter than %v", path, actualDuration, limitDuration)},
+		}
+	}
+	if !isMinimum && actualDuration > limitDuration {
+		return &[]jsonschema.ValError{
+			{Message: fmt.Sprintf("%s duration %v is longer than %v", path, actualDuration, limitDuration)},
+		}
+	}
+	return nil
+}
+
+// Validate checks that a Go duration, like `90s` or `1h30m`, is not shorter than the minimum
+func (min durationMinimum) Validate(path string, data interface{}, errs *[]jsonschema.ValError) {
+	if err := validateDurationRange(path, string(min), data, true); err != nil {
+		*errs = append(*errs, *err...)
+	}
+}
+
+// Validate checks that a Go duration, like `90s` or `1h30m`, is not longer than the maximum
+func (max durationMaximum) Validate(path string, data interface{}, errs *[]jsonschema.ValError) {
+	if err := validateDurationRange(path, string(max), data, false); err != nil {
+		*errs = append(*errs, *err...)
+	}
+}
+
+// Validate checks that a version, like `v1.2.3` or `1.2`, is not older than the minimum. Suffixes like
+// pre-release versions are ignored.
+func (min semverMinimum) Validate(path string, data interface{}, errs *[]jsonschema.ValError) {
+	minimum, err := version.ParseGeneric(string(min))
+	if err != nil {
+		*errs = append(*errs, jsonschema.ValError{Message: fmt.Sprintf("Could not parse version: %s", string(min))})
+		return
+	}
+	versionStr, ok := data.(string)
+	if !ok {
+		*errs = append(*errs, jsonschema.ValError{Message: fmt.Sprintf("Version %v is not a string", data)})
+		return
+	}
+	actual, err := version.ParseGeneric(versionStr)
+	if err != nil {
+		*errs = append(*errs, jsonschema.ValError{Message: fmt.Sprintf("Could not parse version: %s", versionStr)})
+		return
+	}
+	if actual.LessThan(minimum) {
+		*errs = append(*errs, jsonschema.ValError{
+			Message: fmt.Sprintf("%s version %s is older than %s", path, versionStr, string(min)),
+		})
+	}
+}
+
+// UnmarshalJSON accepts a single CIDR or a list of CIDRs
+func (within *cidrWithin) UnmarshalJSON(data []byte) error {
+	var cidr string
+	if err := json.Unmarshal(data, &cidr); err == nil {
+		*within = cidrWithin{cidr}
+		return nil
+	}
+	cidrs := []string{}
+	if err := json.Unmarshal(data, &cidrs); err != nil {
+		return errors.New("cidrWithin must be a CIDR, or a list of CIDRs")
+	}
+	*within = cidrs
+	return nil
+}
+
+// parsePrefix parses a CIDR, or an IP address as a CIDR containing only that address
+func parsePrefix(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Validate checks that an IP address or CIDR is contained in one of the CIDRs
+func (within cidrWithin) Validate(path string, data interface{}, errs *[]jsonschema.ValError) {
+	cidrStr, ok := data.(string)
+	if !ok {
+		*errs = append(*errs, jsonschema.ValError{Message: fmt.Sprintf("CIDR %v is not a string", data)})
+		return
+	}
+	actual, err := parsePrefix(cidrStr)
+	if err != nil {
+		*errs = append(*errs, jsonschema.ValError{Message: fmt.Sprintf("Could not parse CIDR: %s", cidrStr)})
+		return
+	}
+	for _, cidr := range within {
+		allowed, err := parsePrefix(cidr)
+		if err != nil {
+			*errs = append(*errs, jsonschema.ValError{Message: fmt.Sprintf("Could not parse CIDR: %s", cidr)})
+			return
+		}
+		if allowed.Bits() <= actual.Bits() && allowed.Contains(actual.Addr()) {
+			return
+		}
+	}
+	*errs = append(*errs, jsonschema.ValError{
+		Message: fmt.Sprintf("%s %s is not within %s", path, cidrStr, strings.Join(within, ", ")),
+	})
+}
+
+// Validate checks that a map of labels matches the label selector
+func (selector labelSelectorMatches) Validate(path string, data interface{}, errs *[]jsonschema.ValError) {
+	parsed, err := labels.Parse(string(selector))
+	if err != nil {
+		*errs = append(*errs, jsonschema.ValError{Message: fmt.Sprintf("Could not parse label selector %s: %v", string(selector), err)})
+		return
+	}
+	labelMap, ok := data.(map[string]interface{})
+	if !ok {
+		*errs = append(*errs, jsonschema.ValError{Message: fmt.Sprintf("Labels %v are not a map", data)})
+		return
+	}
+	labelSet := labels.Set{}
+	for key, value := range labelMap {
+		labelSet[key] = fmt.Sprint(value)
+	}
+	if !parsed.Matches(labelSet) {
+		*errs = append(*errs, jsonschema.ValError{
+			Message: fmt.Sprintf("%s labels %s don't match selector %s", path, labelSet.String(), string(selector)),
+		})
+	}
+}
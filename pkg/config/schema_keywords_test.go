@@ -0,0 +1,87 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaKeywords(t *testing.T) {
+	testCases := []struct {
+		schema  string
+		data    string
+		message string
+	}{{
+		schema:  `{"properties": {"resources": {"resourceRatioMaximum": 2}}}`,
+		data:    `{"resources": {"requests": {"cpu": "100m", "memory": "1Gi"}, "limits": {"cpu": "500m", "memory": "2Gi"}}}`,
+		message: "/resources cpu limit 500m is 5.0x the request 100m, more than 2x",
+	}, {
+		schema: `{"properties": {"resources": {"resourceRatioMaximum": {"memory": 1}}}}`,
+		data:   `{"resources": {"requests": {"cpu": "100m"}, "limits": {"cpu": "500m", "memory": "2Gi"}}}`,
+	}, {
+		schema:  `{"properties": {"interval": {"durationMinimum": "1m", "durationMaximum": "1h"}}}`,
+		data:    `{"interval": "90m"}`,
+		message: "/interval duration 1h30m0s is longer than 1h0m0s",
+	}, {
+		schema:  `{"properties": {"interval": {"durationMinimum": "1m", "durationMaximum": "1h"}}}`,
+		data:    `{"interval": "30s"}`,
+		message: "/interval duration 30s is shorter than 1m0s",
+	}, {
+		schema:  `{"properties": {"version": {"semverMinimum": "1.20"}}}`,
+		data:    `{"version": "v1.9.3"}`,
+		message: "/version version v1.9.3 is older than 1.20",
+	}, {
+		schema: `{"properties": {"version": {"semverMinimum": "1.20"}}}`,
+		data:   `{"version": "1.20.0-rc.1"}`,
+	}, {
+		schema:  `{"properties": {"cidr": {"cidrWithin": "10.0.0.0/8"}}}`,
+		data:    `{"cidr": "10.0.0.0/7"}`,
+		message: "/cidr 10.0.0.0/7 is not within 10.0.0.0/8",
+	}, {
+		schema: `{"properties": {"cidr": {"cidrWithin": ["172.16.0.0/12", "10.0.0.0/8"]}}}`,
+		data:   `{"cidr": "10.1.2.3"}`,
+	}, {
+		schema:  `{"properties": {"labels": {"labelSelectorMatches": "app, tier in (web)"}}}`,
+		data:    `{"labels": {"app": "shop", "tier": "db"}}`,
+		message: "/labels labels app=shop,tier=db don't match selector app, tier in (web)",
+	}, {
+		schema:  `{"properties": {"labels": {"labelSelectorMatches": "app in (shop"}}}`,
+		data:    `{"labels": {"app": "shop"}}`,
+		message: "Could not parse label selector app in (shop: unable to parse requirement: found '', expected: ',' or ')'",
+	}}
+	for _, tc := range testCases {
+		check, err := SchemaCheck{ID: "test", SchemaString: tc.schema}.TemplateForResource(nil)
+		assert.NoError(t, err, tc.schema)
+		data := map[string]interface{}{}
+		assert.NoError(t, UnmarshalYAMLOrJSON([]byte(tc.data), &data))
+		passes, issues, err := check.CheckObject(data)
+		assert.NoError(t, err)
+		if tc.message == "" {
+			assert.True(t, passes, tc.schema)
+			assert.Empty(t, issues, tc.schema)
+		} else if assert.Len(t, issues, 1, tc.schema) {
+			assert.False(t, passes, tc.schema)
+			assert.Equal(t, tc.message, issues[0].Message)
+		}
+	}
+}
+
+func TestSchemaKeywordsInvalid(t *testing.T) {
+	check := SchemaCheck{ID: "test", SchemaString: `{"properties": {"cidr": {"cidrWithin": 10}}}`}
+	_, err := check.TemplateForResource(nil)
+	assert.ErrorContains(t, err, "cidrWithin must be a CIDR, or a list of CIDRs")
+}
@@ -0,0 +1,120 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// KubernetesVersion is a Kubernetes major.minor version, e.g. 1.27
+type KubernetesVersion struct {
+	Major int
+	Minor int
+}
+
+// ParseKubernetesVersion parses versions like `1.27`, `v1.27.3`, `1.27+` or `1.27.3-gke.100`.
+// An empty or `unknown` version parses to the zero KubernetesVersion.
+func ParseKubernetesVersion(version string) (KubernetesVersion, error) {
+	v := strings.TrimSpace(version)
+	if v == "" || v == "unknown" {
+		return KubernetesVersion{}, nil
+	}
+	v = strings.TrimPrefix(v, "v")
+	parts := strings.Split(v, ".")
+	if len(parts) < 2 {
+		return KubernetesVersion{}, fmt.Errorf("invalid Kubernetes version %q, expected major.minor", version)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return KubernetesVersion{}, fmt.Errorf("invalid Kubernetes major version in %q", version)
+	}
+	// Managed providers report minor versions like `27+` or `27-gke.100`
+	minorStr := parts[1]
+	if idx := strings.IndexFunc(minorStr, func(r rune) bool { return r < '0' || r > '9' }); idx >= 0 {
+		minorStr = minorStr[:idx]
+	}
+	minor, err := strconv.Atoi(minorStr)
+	if err != nil {
+		return KubernetesVersion{}, fmt.Errorf("invalid Kubernetes minor version in %q", version)
+	}
+	return KubernetesVersion{Major: major, Minor: minor}, nil
+}
+
+// IsZero returns true if the version is unknown
+func (v KubernetesVersion) IsZero() bool {
+	return v.Major == 0 && v.Minor == 0
+}
+
+// Compare returns -1, 0 or 1 if v is lower than, equal to or greater than other
+func (v KubernetesVersion) Compare(other KubernetesVersion) int {
+	if v.Major != other.Major {
+		if v.Major < other.Major {
+			return -1
+		}
+		return 1
+	}
+	if v.Minor != other.Minor {
+		if v.Minor < other.Minor {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+func (v KubernetesVersion) String() string {
+	if v.IsZero() {
+		return "unknown"
+	}
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// versionInRange checks that version is within the inclusive [min, max] bounds.
+// Empty bounds and unknown versions always match.
+func versionInRange(minVersion, maxVersion string, version KubernetesVersion) bool {
+	if version.IsZero() {
+		return true
+	}
+	if minVersion != "" {
+		min, err := ParseKubernetesVersion(minVersion)
+		if err == nil && version.Compare(min) < 0 {
+			return false
+		}
+	}
+	if maxVersion != "" {
+		max, err := ParseKubernetesVersion(maxVersion)
+		if err == nil && version.Compare(max) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func validateVersionRange(minVersion, maxVersion string) error {
+	min, err := ParseKubernetesVersion(minVersion)
+	if err != nil {
+		return err
+	}
+	max, err := ParseKubernetesVersion(maxVersion)
+	if err != nil {
+		return err
+	}
+	if !min.IsZero() && !max.IsZero() && min.Compare(max) > 0 {
+		return fmt.Errorf("minKubernetesVersion %s is greater than maxKubernetesVersion %s", min, max)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKubernetesVersion(t *testing.T) {
+	cases := map[string]KubernetesVersion{
+		"1.27":           {Major: 1, Minor: 27},
+		"v1.24.3":        {Major: 1, Minor: 24},
+		"1.27+":          {Major: 1, Minor: 27},
+		"1.29.1-gke.100": {Major: 1, Minor: 29},
+		"1.30-eks":       {Major: 1, Minor: 30},
+		"":               {},
+		"unknown":        {},
+	}
+	for input, expected := range cases {
+		actual, err := ParseKubernetesVersion(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, actual, input)
+	}
+	for _, input := range []string{"1", "latest", "one.two"} {
+		_, err := ParseKubernetesVersion(input)
+		assert.Error(t, err, input)
+	}
+}
+
+var confVersionedCheck = `
+checks:
+  foo: warning
+customChecks:
+  foo:
+    successMessage: ok
+    failureMessage: not ok
+    category: Security
+    target: PodSpec
+    minKubernetesVersion: "1.19"
+    maxKubernetesVersion: "1.30"
+    schema:
+      type: object
+      required: ["securityContext"]
+    schemaVariants:
+    - maxKubernetesVersion: "1.24"
+      containers:
+        exclude:
+        - initContainer
+      schema:
+        type: object
+`
+
+func TestCheckKubernetesVersions(t *testing.T) {
+	c, err := Parse([]byte(confVersionedCheck))
+	assert.NoError(t, err)
+	check := c.CustomChecks["foo"]
+
+	assert.True(t, check.IsApplicableToVersion(KubernetesVersion{}))
+	assert.False(t, check.IsApplicableToVersion(KubernetesVersion{Major: 1, Minor: 18}))
+	assert.True(t, check.IsApplicableToVersion(KubernetesVersion{Major: 1, Minor: 19}))
+	assert.True(t, check.IsApplicableToVersion(KubernetesVersion{Major: 1, Minor: 30}))
+	assert.False(t, check.IsApplicableToVersion(KubernetesVersion{Major: 1, Minor: 31}))
+
+	assert.Equal(t, `{"type":"object"}`, check.ForKubernetesVersion(KubernetesVersion{Major: 1, Minor: 24}).SchemaString)
+	assert.Equal(t, check.SchemaString, check.ForKubernetesVersion(KubernetesVersion{Major: 1, Minor: 25}).SchemaString)
+	assert.Equal(t, check.SchemaString, check.ForKubernetesVersion(KubernetesVersion{}).SchemaString)
+	assert.Equal(t, []string{"initContainer"}, check.ForKubernetesVersion(KubernetesVersion{Major: 1, Minor: 24}).Containers.Exclude)
+	assert.Empty(t, check.ForKubernetesVersion(KubernetesVersion{Major: 1, Minor: 25}).Containers.Exclude)
+}
+
+func TestInvalidKubernetesVersions(t *testing.T) {
+	_, err := Parse([]byte("checks:\n  foo: warning\nkubernetesVersion: latest\n"))
+	assert.Error(t, err)
+
+	_, err = Parse([]byte(`
+checks:
+  foo: warning
+customChecks:
+  foo:
+    target: PodSpec
+    minKubernetesVersion: "1.30"
+    maxKubernetesVersion: "1.24"
+    schema: {}
+`))
+	assert.Error(t, err)
+}
@@ -66,15 +66,16 @@ type templateData struct {
 // GetBaseTemplate puts together the dashboard template. Individual pieces can be overridden before rendering.
 func GetBaseTemplate(name string) (*template.Template, error) {
 	tmpl := template.New(name).Funcs(template.FuncMap{
-		"getWarningWidth": getWarningWidth,
-		"getSuccessWidth": getSuccessWidth,
-		"getWeatherIcon":  getWeatherIcon,
-		"getWeatherText":  getWeatherText,
-		"getGrade":        getGrade,
-		"getIcon":         getIcon,
-		"getResultClass":  getResultClass,
-		"getCategoryLink": getCategoryLink,
-		"getCategoryInfo": getCategoryInfo,
+		"getWarningWidth":      getWarningWidth,
+		"getSuccessWidth":      getSuccessWidth,
+		"getWeatherIcon":       getWeatherIcon,
+		"getWeatherText":       getWeatherText,
+		"getGrade":             getGrade,
+		"getIcon":              getIcon,
+		"getResultClass":       getResultClass,
+		"getCategoryLink":      getCategoryLink,
+		"getCategoryInfo":      getCategoryInfo,
+		"getRiskiestWorkloads": getRiskiestWorkloads,
 	})
 
 	templateFileNames := []string{
@@ -121,8 +121,12 @@ func getIcon(rm validator.ResultMessage) string {
 	}
 }
 
+func getRiskiestWorkloads(auditData validator.AuditData) []validator.Result {
+	return auditData.GetRiskiestWorkloads(validator.RiskiestWorkloadsCount)
+}
+
 func getCategoryLink(category string) string {
-	return "https://polaris.docs.fairwinds.com/checks/" + strings.ToLower(category)
+	return "https://polaris.docs.fairwinds.com/checks/" + strings.ReplaceAll(strings.ToLower(category), " ", "-")
 }
 
 func getCategoryInfo(category string) string {
@@ -151,6 +155,18 @@ func getCategoryInfo(category string) string {
 			best practices, mostly focused on ensuring that unnecessary access has not
 			been granted to an application workload.
 		`)
+	case "Schema":
+		return fmt.Sprintf(`
+			Polaris can validate resources against the Kubernetes API schema, catching
+			unknown fields, wrong types and missing required fields that would cause
+			the resource to be rejected when applied to a cluster.
+		`)
+	case "Pod Security":
+		return fmt.Sprintf(`
+			The Kubernetes Pod Security Standards define the baseline and restricted
+			profiles enforced by Pod Security Admission. Polaris evaluates each of their
+			controls, so you can find which profile every namespace can safely enforce.
+		`)
 	default:
 		return ""
 	}
@@ -201,6 +201,9 @@ func TestGetCategoryLink(t *testing.T) {
 
 	assert.Equal(t, expectedOutput, actual)
 	assert.NotEqual(t, "ttps://polaris.docs.fairwinds.com/checks/reliability", actual)
+
+	actual = getCategoryLink("Pod Security")
+	assert.Equal(t, "https://polaris.docs.fairwinds.com/checks/pod-security", actual)
 }
 
 func TestGetCategoryInfo(t *testing.T) {
@@ -69,6 +69,9 @@ func Execute(config config.Configuration, filesPath string, isTemplate bool, che
 		if err != nil {
 			return fmt.Errorf("error creating resource provider from yaml: %v", err)
 		}
+		if err := kubeResources.ApplyPodSpecPaths(config.PodSpecPaths); err != nil {
+			return fmt.Errorf("error finding pod specs in %s: %v", fullFilePath, err)
+		}
 		results, err := validator.ApplyAllSchemaChecksToResourceProvider(&config, kubeResources)
 		if err != nil {
 			return fmt.Errorf("error applying schema check to the resources %s: %v", fullFilePath, err)
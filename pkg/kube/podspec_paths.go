@@ -0,0 +1,167 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	kubeAPICoreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	conf "github.com/fairwindsops/polaris/pkg/config"
+)
+
+// GroupKind returns the group/Kind of the resource, as used for keys of the ResourceProvider and config
+func (resource GenericResource) GroupKind() string {
+	return getGroupKindKey(resource.Resource.GetAPIVersion(), resource.Resource.GetKind())
+}
+
+// HasContainersOnly returns true if the resource's containers were located with podSpecPaths.containers.
+// Its PodSpec only holds those containers, since the resource has no pod spec of its own.
+func (resource GenericResource) HasContainersOnly() bool {
+	return resource.ContainerPaths != nil
+}
+
+// SetPodSpecPaths locates the pod spec of the resource using configured field paths,
+// replacing what was found at the well-known paths
+func (resource *GenericResource) SetPodSpecPaths(paths conf.PodSpecPaths) error {
+	resource.PodSpec = nil
+	resource.PodTemplate = nil
+	resource.PodSpecPath = ""
+	resource.ContainerPaths = nil
+	obj := resource.Resource.Object
+
+	if paths.PodTemplate != "" || paths.PodSpec != "" {
+		var podTemplate map[string]interface{}
+		var podSpecFields []string
+		if paths.PodTemplate != "" {
+			templateFields := splitFieldPath(paths.PodTemplate)
+			template, _, _ := unstructured.NestedFieldNoCopy(obj, templateFields...)
+			podTemplate, _ = template.(map[string]interface{})
+			podSpecFields = append(templateFields, "spec")
+		} else {
+			podSpecFields = splitFieldPath(paths.PodSpec)
+			podSpec, _, _ := unstructured.NestedFieldNoCopy(obj, podSpecFields...)
+			if podSpecMap, ok := podSpec.(map[string]interface{}); ok {
+				podTemplate = map[string]interface{}{"spec": podSpecMap}
+			}
+		}
+		if podTemplate == nil {
+			return nil
+		}
+		podSpec := kubeAPICoreV1.PodSpec{}
+		if err := convertJSON(podTemplate["spec"], &podSpec); err != nil {
+			return fmt.Errorf("could not parse pod spec of %s %s: %w", resource.Kind, resource.Resource.GetName(), err)
+		}
+		templateCopy := map[string]interface{}{}
+		if err := convertJSON(podTemplate, &templateCopy); err != nil {
+			return err
+		}
+		resource.PodSpec = &podSpec
+		resource.PodTemplate = templateCopy
+		resource.PodSpecPath = toJSONPointer(podSpecFields)
+		return nil
+	}
+
+	podSpec := kubeAPICoreV1.PodSpec{}
+	containerPaths := []string{}
+	for _, path := range paths.Containers {
+		fields := splitFieldPath(path)
+		value, _, _ := unstructured.NestedFieldNoCopy(obj, fields...)
+		name := fields[len(fields)-1]
+		switch v := value.(type) {
+		case []interface{}:
+			for idx, item := range v {
+				container, err := toContainer(item, name+"-"+strconv.Itoa(idx))
+				if err != nil {
+					return err
+				}
+				podSpec.Containers = append(podSpec.Containers, container)
+				containerPaths = append(containerPaths, toJSONPointer(fields)+"/"+strconv.Itoa(idx))
+			}
+		case map[string]interface{}:
+			container, err := toContainer(v, name)
+			if err != nil {
+				return err
+			}
+			podSpec.Containers = append(podSpec.Containers, container)
+			containerPaths = append(containerPaths, toJSONPointer(fields))
+		}
+	}
+	if len(podSpec.Containers) == 0 {
+		return nil
+	}
+	podSpecMap, err := SerializePodSpec(&podSpec)
+	if err != nil {
+		return err
+	}
+	resource.PodSpec = &podSpec
+	resource.PodTemplate = map[string]interface{}{"spec": podSpecMap}
+	resource.ContainerPaths = containerPaths
+	return nil
+}
+
+// ApplyPodSpecPaths sets the pod specs of all resources whose kinds have configured paths
+func (resources *ResourceProvider) ApplyPodSpecPaths(paths map[string]conf.PodSpecPaths) error {
+	for groupKind, kindPaths := range paths {
+		kindResources := resources.Resources[groupKind]
+		for idx := range kindResources {
+			if err := kindResources[idx].SetPodSpecPaths(kindPaths); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// toContainer parses a container-like object, naming it after its field if it has no name
+func toContainer(value interface{}, defaultName string) (kubeAPICoreV1.Container, error) {
+	container := kubeAPICoreV1.Container{}
+	if err := convertJSON(value, &container); err != nil {
+		return container, fmt.Errorf("could not parse container %s: %w", defaultName, err)
+	}
+	if container.Name == "" {
+		container.Name = defaultName
+	}
+	return container, nil
+}
+
+// convertJSON converts between types through their JSON representation
+func convertJSON(from interface{}, to interface{}) error {
+	b, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, to)
+}
+
+// splitFieldPath splits paths like `spec.template`, also accepting the JSONPath forms `.spec.template` and `{.spec.template}`
+func splitFieldPath(path string) []string {
+	path = strings.TrimSuffix(strings.TrimPrefix(path, "{"), "}")
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	return strings.Split(path, ".")
+}
+
+func toJSONPointer(fields []string) string {
+	escaper := strings.NewReplacer("~", "~0", "/", "~1")
+	pointer := ""
+	for _, field := range fields {
+		pointer += "/" + escaper.Replace(field)
+	}
+	return pointer
+}
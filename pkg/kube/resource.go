@@ -26,6 +26,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	kubeAPIMetaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 )
@@ -42,6 +43,12 @@ type GenericResource struct {
 	PodTemplate        interface{}
 	OriginalObjectJSON []byte
 	OriginalObjectYAML []byte
+	// PodSpecPath and ContainerPaths are JSON pointers to the pod spec and containers
+	// of kinds configured with podSpecPaths, used to build mutations
+	PodSpecPath    string
+	ContainerPaths []string
+	// SourcePath is the file the resource was read from, for audits of files
+	SourcePath string
 }
 
 // NewGenericResourceFromUnstructured creates a workload from an unstructured.Unstructured
@@ -126,6 +133,19 @@ func NewGenericResourceFromPod(podResource kubeAPICoreV1.Pod, originalObject int
 	return workload, nil
 }
 
+// NewGenericResourceFromNode builds a new resource for a given Node
+func NewGenericResourceFromNode(node kubeAPICoreV1.Node) (GenericResource, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&node)
+	if err != nil {
+		return GenericResource{}, err
+	}
+	unst := unstructured.Unstructured{Object: obj}
+	// Items of typed lists don't have a kind
+	unst.SetAPIVersion("v1")
+	unst.SetKind("Node")
+	return NewGenericResourceFromUnstructured(unst, nil)
+}
+
 // NewGenericResourceFromBytes parses a generic kubernetes resource
 func NewGenericResourceFromBytes(contentBytes []byte) (GenericResource, error) {
 	unst := unstructured.Unstructured{}
@@ -58,13 +58,7 @@ type ResourceProvider struct {
 type resourceKindMap map[string][]GenericResource
 
 func (rkm resourceKindMap) addResource(r GenericResource) {
-	gvk := r.Resource.GroupVersionKind()
-	var key string
-	if gvk.Group != "" {
-		key = gvk.Group + "/" + gvk.Kind
-	} else {
-		key = gvk.Kind
-	}
+	key := r.GroupKind()
 	rkm[key] = append(rkm[key], r)
 }
 
@@ -100,6 +94,14 @@ var kindRewrites = map[string]string{
 	"HorizontalPodAutoscaler": "autoscaling/HorizontalPodAutoscaler",
 }
 
+func getGroupKindKey(apiVersion, kind string) string {
+	gvk := schema.FromAPIVersionAndKind(apiVersion, kind)
+	if gvk.Group != "" {
+		return gvk.Group + "/" + gvk.Kind
+	}
+	return gvk.Kind
+}
+
 // This is here for backward compatibility reasons
 func maybeTransformKindIntoGroupKind(k string) string {
 	if val, ok := kindRewrites[k]; ok {
@@ -139,13 +141,23 @@ var podSpecFields = []string{"jobTemplate", "spec", "template"}
 
 // CreateResourceProvider returns a new ResourceProvider object to interact with k8s resources
 func CreateResourceProvider(ctx context.Context, directory, workload string, c conf.Configuration) (*ResourceProvider, error) {
+	if workload == "" && directory == "" {
+		return CreateResourceProviderFromCluster(ctx, c)
+	}
+	var resources *ResourceProvider
+	var err error
 	if workload != "" {
-		return CreateResourceProviderFromResource(ctx, workload)
+		resources, err = CreateResourceProviderFromResource(ctx, workload)
+	} else {
+		resources, err = CreateResourceProviderFromPath(directory)
 	}
-	if directory != "" {
-		return CreateResourceProviderFromPath(directory)
+	if err != nil {
+		return nil, err
 	}
-	return CreateResourceProviderFromCluster(ctx, c)
+	if err := resources.ApplyPodSpecPaths(c.PodSpecPaths); err != nil {
+		return nil, err
+	}
+	return resources, nil
 }
 
 // CreateResourceProviderFromResource creates a new ResourceProvider that just contains one workload
@@ -204,7 +216,7 @@ func CreateResourceProviderFromPath(directory string) (*ResourceProvider, error)
 			logrus.Errorf("Error reading file: %v", path)
 			return err
 		}
-		err = resources.addResourcesFromYaml(string(contents))
+		err = resources.addResourcesFromYaml(string(contents), path)
 		if err != nil {
 			logrus.Warnf("skipping %s: cannot add resource from YAML: %v", path, err)
 		}
@@ -221,7 +233,7 @@ func CreateResourceProviderFromPath(directory string) (*ResourceProvider, error)
 // CreateResourceProviderFromYaml returns a new ResourceProvider using the yaml
 func CreateResourceProviderFromYaml(yamlContent string) (*ResourceProvider, error) {
 	resources := newResourceProvider("unknown", "Content", "unknown")
-	err := resources.addResourcesFromYaml(string(yamlContent))
+	err := resources.addResourcesFromYaml(string(yamlContent), "")
 	if err != nil {
 		return nil, err
 	}
@@ -328,7 +340,11 @@ func CreateResourceProviderFromAPI(ctx context.Context, kube kubernetes.Interfac
 		for key := range check.AdditionalSchemaStrings {
 			neededKinds = append(neededKinds, conf.TargetKind(key))
 		}
+		neededKinds = append(neededKinds, check.AdditionalKinds...)
 		for _, kind := range neededKinds {
+			if _, ok := c.PodSpecPaths[maybeTransformKindIntoGroupKind(string(kind))]; ok {
+				continue
+			}
 			if !funk.Contains(conf.HandledTargets, kind) && !funk.Contains(additionalKinds, kind) {
 				additionalKinds = append(additionalKinds, kind)
 			}
@@ -339,7 +355,11 @@ func CreateResourceProviderFromAPI(ctx context.Context, kube kubernetes.Interfac
 	for _, kind := range additionalKinds {
 		groupKind := parseGroupKind(maybeTransformKindIntoGroupKind(string(kind)))
 		mapping, err := restMapper.RESTMapping(groupKind)
-		if err != nil {
+		if meta.IsNoMatchError(err) {
+			// e.g. the Gateway API CRDs aren't installed
+			logrus.Infof("Skipping %s because the cluster doesn't serve it", kind)
+			continue
+		} else if err != nil {
 			logrus.Warnf("error retrieving mapping of Kind %s because of error: %v", kind, err)
 			return nil, err
 		}
@@ -348,6 +368,18 @@ func CreateResourceProviderFromAPI(ctx context.Context, kube kubernetes.Interfac
 			continue
 		}
 
+		if kind == conf.TargetNode {
+			// Nodes were already listed above
+			for _, node := range nodes.Items {
+				res, err := NewGenericResourceFromNode(node)
+				if err != nil {
+					return nil, err
+				}
+				kubernetesResources = append(kubernetesResources, res)
+			}
+			continue
+		}
+
 		logrus.Info("Loading " + kind)
 		objects, err := dynamic.Resource(mapping.Resource).Namespace(c.Namespace).List(ctx, metav1.ListOptions{})
 		if err != nil {
@@ -362,6 +394,29 @@ func CreateResourceProviderFromAPI(ctx context.Context, kube kubernetes.Interfac
 			kubernetesResources = append(kubernetesResources, res)
 		}
 	}
+	for groupKind := range c.PodSpecPaths {
+		mapping, err := restMapper.RESTMapping(parseGroupKind(groupKind))
+		if err != nil {
+			logrus.Warnf("skipping podSpecPaths for %s, kind not found: %v", groupKind, err)
+			continue
+		}
+		if c.Namespace != "" && mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+			continue
+		}
+		logrus.Info("Loading " + groupKind)
+		objects, err := dynamic.Resource(mapping.Resource).Namespace(c.Namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			logrus.Warnf("error retrieving %s: %v", groupKind, err)
+			return nil, err
+		}
+		for _, obj := range objects.Items {
+			res, err := NewGenericResourceFromUnstructured(obj, nil)
+			if err != nil {
+				return nil, err
+			}
+			kubernetesResources = append(kubernetesResources, res)
+		}
+	}
 	logrus.Info("Loading controllers")
 	client := controller.Client{
 		Context:    ctx,
@@ -374,6 +429,10 @@ func CreateResourceProviderFromAPI(ctx context.Context, kube kubernetes.Interfac
 	}
 	for _, workload := range topControllers {
 		topController := workload.TopController
+		if _, ok := c.PodSpecPaths[getGroupKindKey(topController.GetAPIVersion(), topController.GetKind())]; ok {
+			// Already loaded above
+			continue
+		}
 		workloadObj, err := NewGenericResourceFromUnstructured(topController, nil)
 		if err != nil {
 			return nil, fmt.Errorf("could not parse workload %v: %w", workload, err)
@@ -384,6 +443,9 @@ func CreateResourceProviderFromAPI(ctx context.Context, kube kubernetes.Interfac
 	provider.Nodes = nodes.Items
 	provider.Namespaces = namespaces.Items
 	provider.Resources.addResources(kubernetesResources)
+	if err := provider.ApplyPodSpecPaths(c.PodSpecPaths); err != nil {
+		return nil, err
+	}
 	logrus.Info("Done loading Kubernetes resources")
 	return &provider, nil
 }
@@ -394,19 +456,20 @@ func (resources *ResourceProvider) addResourcesFromReader(reader io.Reader) erro
 		logrus.Errorf("Error reading from %v: %v", reader, err)
 		return err
 	}
-	if err := resources.addResourcesFromYaml(string(contents)); err != nil {
+	if err := resources.addResourcesFromYaml(string(contents), ""); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (resources *ResourceProvider) addResourcesFromYaml(contents string) error {
+// addResourcesFromYaml adds the resources of a YAML document, read from sourcePath if it's a file
+func (resources *ResourceProvider) addResourcesFromYaml(contents string, sourcePath string) error {
 	specs := regexp.MustCompile("[\r\n]-+[\r\n]").Split(string(contents), -1)
 	for _, spec := range specs {
 		if strings.TrimSpace(spec) == "" {
 			continue
 		}
-		err := resources.addResourceFromString(spec)
+		err := resources.addResourceFromString(spec, sourcePath)
 		if err != nil {
 			logrus.Errorf("Error parsing YAML: (%v)", err)
 			return err
@@ -415,7 +478,7 @@ func (resources *ResourceProvider) addResourcesFromYaml(contents string) error {
 	return nil
 }
 
-func (resources *ResourceProvider) addResourceFromString(contents string) error {
+func (resources *ResourceProvider) addResourceFromString(contents string, sourcePath string) error {
 	contentBytes := []byte(contents)
 	decoder := k8sYaml.NewYAMLOrJSONDecoder(bytes.NewReader(contentBytes), 1000)
 	resource := k8sResource{}
@@ -430,6 +493,13 @@ func (resources *ResourceProvider) addResourceFromString(contents string) error
 		err = decoder.Decode(&ns)
 		resources.Namespaces = append(resources.Namespaces, ns)
 	}
+	if resource.Kind == "Node" {
+		node := corev1.Node{}
+		if err := decoder.Decode(&node); err != nil {
+			return err
+		}
+		resources.Nodes = append(resources.Nodes, node)
+	}
 
 	if resource.Kind == "Pod" {
 		pod := corev1.Pod{}
@@ -442,12 +512,14 @@ func (resources *ResourceProvider) addResourceFromString(contents string) error
 			return err
 		}
 		workload.OriginalObjectYAML = contentBytes
+		workload.SourcePath = sourcePath
 		resources.Resources.addResource(workload)
 	} else {
 		newResource, err := NewGenericResourceFromBytes(contentBytes)
 		if err != nil {
 			return err
 		}
+		newResource.SourcePath = sourcePath
 		resources.Resources.addResource(newResource)
 	}
 	return err
@@ -67,6 +67,7 @@ func TestGetMultipleResourceFromSingleFile(t *testing.T) {
 
 	assert.Equal(t, 1, len(resources.Resources["apps/Deployment"]), "Should have one controller")
 	assert.Equal(t, "dashboard", resources.Resources["apps/Deployment"][0].PodSpec.Containers[0].Name)
+	assert.Equal(t, "./test_files/test_2/multi.yaml", resources.Resources["apps/Deployment"][0].SourcePath)
 
 	assert.Equal(t, 2, len(resources.Namespaces), "Should have a namespace")
 	assert.Equal(t, "polaris", resources.Namespaces[0].ObjectMeta.Name)
@@ -175,3 +176,53 @@ func TestGetResourceFromAPI(t *testing.T) {
 		})
 	}
 }
+
+func TestPodSpecPaths(t *testing.T) {
+	c := conf.Configuration{
+		PodSpecPaths: map[string]conf.PodSpecPaths{
+			"argoproj.io/Rollout": {
+				PodTemplate: "spec.template",
+			},
+			"sparkoperator.k8s.io/SparkApplication": {
+				Containers: []string{"spec.driver", "spec.executor"},
+			},
+			"tekton.dev/Task": {
+				Containers: []string{"{.spec.steps}"},
+			},
+		},
+	}
+	provider, err := CreateResourceProvider(context.Background(), "./test_files/test_4", "", c)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, provider.Resources.GetNumberOfControllers())
+
+	rollout := provider.Resources["argoproj.io/Rollout"][0]
+	assert.Equal(t, "/spec/template/spec", rollout.PodSpecPath)
+	assert.False(t, rollout.HasContainersOnly())
+	assert.Equal(t, "app", rollout.PodSpec.Containers[0].Name)
+	assert.Equal(t, "rollout", rollout.PodTemplate.(map[string]interface{})["metadata"].(map[string]interface{})["labels"].(map[string]interface{})["app"])
+
+	spark := provider.Resources["sparkoperator.k8s.io/SparkApplication"][0]
+	assert.Len(t, spark.PodSpec.Containers, 2)
+	assert.Equal(t, "driver", spark.PodSpec.Containers[0].Name)
+	assert.Equal(t, "executor", spark.PodSpec.Containers[1].Name)
+	assert.True(t, *spark.PodSpec.Containers[1].SecurityContext.RunAsNonRoot)
+	assert.Equal(t, []string{"/spec/driver", "/spec/executor"}, spark.ContainerPaths)
+	assert.True(t, spark.HasContainersOnly())
+
+	task := provider.Resources["tekton.dev/Task"][0]
+	assert.Len(t, task.PodSpec.Containers, 2)
+	assert.Equal(t, "compile", task.PodSpec.Containers[0].Name)
+	assert.Equal(t, "steps-1", task.PodSpec.Containers[1].Name)
+	assert.Equal(t, "alpine:3.19", task.PodSpec.Containers[1].Image)
+	assert.Equal(t, []string{"/spec/steps/0", "/spec/steps/1"}, task.ContainerPaths)
+}
+
+func TestCronJobPodTemplate(t *testing.T) {
+	provider, err := CreateResourceProviderFromPath("./test_files/test_1/cron_job.yaml")
+	assert.NoError(t, err)
+	cronJob := provider.Resources["batch/CronJob"][0]
+	assert.Equal(t, "CronJob", cronJob.Kind)
+	assert.Equal(t, "test", cronJob.PodSpec.Containers[0].Name)
+	podTemplate := cronJob.PodTemplate.(map[string]interface{})
+	assert.Equal(t, "OnFailure", podTemplate["spec"].(map[string]interface{})["restartPolicy"])
+}
@@ -327,6 +327,8 @@ func getSplitFromPath(path string) []string {
 		if key == "" {
 			continue
 		}
+		// Keys containing a slash, like annotations, are escaped as in JSON Pointer
+		key = strings.ReplaceAll(strings.ReplaceAll(key, "~1", "/"), "~0", "~")
 		if digitStarCheck.MatchString(key) {
 			lastElementIdx := len(formatedSplit) - 1
 			lastElement := formatedSplit[lastElementIdx]
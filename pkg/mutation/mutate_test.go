@@ -181,6 +181,23 @@ extra:
 		},
 		mutated: `foo: baz # override`,
 		message: "Expected a comment to overridden",
+	}, {
+		original: `
+metadata:
+  annotations:
+    example.com/mode: unsafe
+`,
+		patch: config.Mutation{
+			Op:    "replace",
+			Value: "safe",
+			Path:  "/metadata/annotations/example.com~1mode",
+		},
+		mutated: `
+metadata:
+  annotations:
+    example.com/mode: safe
+`,
+		message: "Expected an escaped slash to match a key",
 	},
 }
 
@@ -0,0 +1,214 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/thoas/go-funk"
+
+	"github.com/fairwindsops/polaris/pkg/config"
+)
+
+// ComplianceReport groups the results of an audit by the controls of compliance frameworks
+type ComplianceReport struct {
+	DisplayName string
+	AuditTime   string
+	Frameworks  []ComplianceFramework
+}
+
+// ComplianceFramework is a compliance framework, like the CIS Kubernetes Benchmark
+type ComplianceFramework struct {
+	Name     string
+	Controls []ComplianceControl
+}
+
+// ComplianceControl counts the resources that passed, failed, or were exempted from the checks mapped to a control
+type ComplianceControl struct {
+	ID       string
+	Checks   []string
+	Passed   uint
+	Failed   uint
+	Exempt   uint
+	Failures []ComplianceFailure
+}
+
+// ComplianceFailure is a resource that failed a check mapped to a control
+type ComplianceFailure struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Container string
+	Check     string
+	Message   string
+}
+
+// GetComplianceReport groups the results by the compliance references of their checks.
+// A resource passes a control if it passes every check mapped to the control, and is exempt
+// if it was exempted from all of them. Exemptions are only counted if the audit ran with config.ComplianceReport.
+func (res AuditData) GetComplianceReport(conf config.Configuration) ComplianceReport {
+	controlChecks := map[string]map[string][]string{}
+	for checkID := range conf.Checks {
+		check, ok := conf.CustomChecks[checkID]
+		if !ok {
+			check, ok = config.BuiltInChecks[checkID]
+		}
+		if !ok {
+			continue
+		}
+		for framework, controls := range check.Compliance {
+			if _, ok := controlChecks[framework]; !ok {
+				controlChecks[framework] = map[string][]string{}
+			}
+			for _, control := range controls {
+				controlChecks[framework][control] = append(controlChecks[framework][control], checkID)
+			}
+		}
+	}
+
+	report := ComplianceReport{
+		DisplayName: res.DisplayName,
+		AuditTime:   res.AuditTime,
+		Frameworks:  []ComplianceFramework{},
+	}
+	for _, framework := range getSortedStrings(funk.Keys(controlChecks).([]string)) {
+		complianceFramework := ComplianceFramework{Name: framework}
+		controlIDs := funk.Keys(controlChecks[framework]).([]string)
+		sort.Slice(controlIDs, func(i, j int) bool {
+			return compareControlIDs(controlIDs[i], controlIDs[j])
+		})
+		for _, controlID := range controlIDs {
+			control := ComplianceControl{
+				ID:       controlID,
+				Checks:   getSortedStrings(controlChecks[framework][controlID]),
+				Failures: []ComplianceFailure{},
+			}
+			for _, result := range res.Results {
+				control.addResult(result)
+			}
+			complianceFramework.Controls = append(complianceFramework.Controls, control)
+		}
+		report.Frameworks = append(report.Frameworks, complianceFramework)
+	}
+	return report
+}
+
+func (control *ComplianceControl) addResult(result Result) {
+	failures := []ComplianceFailure{}
+	ran := false
+	addResultSet := func(resultSet ResultSet, container string) {
+		for _, checkID := range control.Checks {
+			msg, ok := resultSet[checkID]
+			if !ok {
+				continue
+			}
+			ran = true
+			if !msg.Success {
+				failures = append(failures, ComplianceFailure{
+					Kind:      result.Kind,
+					Namespace: result.Namespace,
+					Name:      result.Name,
+					Container: container,
+					Check:     checkID,
+					Message:   msg.Message,
+				})
+			}
+		}
+	}
+	addResultSet(result.Results, "")
+	if result.PodResult != nil {
+		addResultSet(result.PodResult.Results, "")
+		for _, containerResult := range result.PodResult.ContainerResults {
+			addResultSet(containerResult.Results, containerResult.Name)
+		}
+	}
+	if len(failures) > 0 {
+		control.Failed++
+		control.Failures = append(control.Failures, failures...)
+	} else if ran {
+		control.Passed++
+	} else {
+		for _, checkID := range control.Checks {
+			if funk.ContainsString(result.Exemptions, checkID) {
+				control.Exempt++
+				break
+			}
+		}
+	}
+}
+
+// compareControlIDs sorts numbered controls like 5.2.10 after 5.2.9
+func compareControlIDs(a, b string) bool {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	for idx := 0; idx < len(aParts) && idx < len(bParts); idx++ {
+		if aParts[idx] == bParts[idx] {
+			continue
+		}
+		aNum, aErr := strconv.Atoi(aParts[idx])
+		bNum, bErr := strconv.Atoi(bParts[idx])
+		if aErr == nil && bErr == nil {
+			return aNum < bNum
+		}
+		return aParts[idx] < bParts[idx]
+	}
+	return len(aParts) < len(bParts)
+}
+
+func getSortedStrings(values []string) []string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// GetPrettyOutput returns a human-readable string
+func (report ComplianceReport) GetPrettyOutput(useColor bool) string {
+	color.NoColor = !useColor
+	str := titleColor.Sprint(fmt.Sprintf("Polaris compliance report for %s at %s\n", report.DisplayName, report.AuditTime))
+	for _, framework := range report.Frameworks {
+		str += "\n" + titleColor.Sprint(framework.Name) + "\n"
+		for _, control := range framework.Controls {
+			status := color.GreenString("Pass")
+			if control.Failed > 0 {
+				status = color.RedString("Fail")
+			} else if control.Passed == 0 {
+				status = color.YellowString("N/A")
+			}
+			str += fmt.Sprintf("  %s %s | passed: %d | failed: %d | exempt: %d\n", checkColor.Sprint(fillString(control.ID, minIDLength-2)), status, control.Passed, control.Failed, control.Exempt)
+			str += fmt.Sprintf("      checks: %s\n", strings.Join(control.Checks, ", "))
+			for _, failure := range control.Failures {
+				str += fmt.Sprintf("      %s\n", failure.String())
+			}
+		}
+	}
+	color.NoColor = false
+	return str
+}
+
+func (failure ComplianceFailure) String() string {
+	str := failure.Kind + " "
+	if failure.Namespace != "" {
+		str += failure.Namespace + "/"
+	}
+	str += failure.Name
+	if failure.Container != "" {
+		str += " container " + failure.Container
+	}
+	return fmt.Sprintf("%s: %s - %s", str, failure.Check, failure.Message)
+}
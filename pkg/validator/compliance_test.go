@@ -0,0 +1,133 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	conf "github.com/fairwindsops/polaris/pkg/config"
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+func TestComplianceReport(t *testing.T) {
+	c, err := conf.Parse([]byte(`
+checks:
+  hostNetworkSet: danger
+  hostPIDSet: danger
+  runAsPrivileged: danger
+  imageRegistry: warning
+exemptions:
+  - controllerNames:
+      - exempt
+    rules:
+      - hostNetworkSet
+customChecks:
+  imageRegistry:
+    successMessage: Image comes from allowed registries
+    failureMessage: Image should not be from disallowed registry
+    category: Images
+    target: Container
+    compliance:
+      Internal:
+        - IMG-1
+    schema:
+      '$schema': http://json-schema.org/draft-07/schema
+      type: object
+      properties:
+        image:
+          type: string
+          not:
+            pattern: ^quay.io
+`))
+	assert.NoError(t, err)
+	provider, err := kube.CreateResourceProviderFromYaml(`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: host
+  namespace: apps
+spec:
+  hostNetwork: true
+  containers:
+    - name: app
+      image: quay.io/app:1.0
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: exempt
+  namespace: apps
+spec:
+  hostNetwork: true
+  containers:
+    - name: app
+      image: nginx:1.25
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: safe
+  namespace: apps
+spec:
+  containers:
+    - name: app
+      image: nginx:1.25
+`)
+	assert.NoError(t, err)
+	auditData, err := RunAudit(c, provider)
+	assert.NoError(t, err)
+	for _, result := range auditData.Results {
+		assert.Nil(t, result.Exemptions, "exemptions are only listed for compliance reports")
+	}
+
+	c.ComplianceReport = true
+	auditData, err = RunAudit(c, provider)
+	assert.NoError(t, err)
+	report := auditData.GetComplianceReport(c)
+	frameworks := map[string][]ComplianceControl{}
+	for _, framework := range report.Frameworks {
+		frameworks[framework.Name] = framework.Controls
+	}
+	assert.Len(t, frameworks, 3)
+
+	cis := frameworks["CIS"]
+	assert.Equal(t, []string{"5.2.2", "5.2.3", "5.2.5"}, []string{cis[0].ID, cis[1].ID, cis[2].ID})
+	assert.Equal(t, uint(3), cis[0].Passed)
+	hostNetwork := cis[2]
+	assert.Equal(t, []string{"hostNetworkSet"}, hostNetwork.Checks)
+	assert.Equal(t, uint(1), hostNetwork.Passed)
+	assert.Equal(t, uint(1), hostNetwork.Failed)
+	assert.Equal(t, uint(1), hostNetwork.Exempt)
+	assert.Equal(t, []ComplianceFailure{
+		{Kind: "Pod", Namespace: "apps", Name: "host", Check: "hostNetworkSet", Message: "Host network should not be configured"},
+	}, hostNetwork.Failures)
+
+	internal := frameworks["Internal"]
+	assert.Len(t, internal, 1)
+	assert.Equal(t, "IMG-1", internal[0].ID)
+	assert.Equal(t, uint(2), internal[0].Passed)
+	assert.Equal(t, uint(1), internal[0].Failed)
+	assert.Equal(t, "app", internal[0].Failures[0].Container)
+}
+
+func TestCompareControlIDs(t *testing.T) {
+	assert.True(t, compareControlIDs("5.2.9", "5.2.10"))
+	assert.False(t, compareControlIDs("5.2.10", "5.2.9"))
+	assert.True(t, compareControlIDs("5.1.8", "5.2.1"))
+	assert.True(t, compareControlIDs("5.2", "5.2.1"))
+	assert.True(t, compareControlIDs("Network policies", "Secrets"))
+}
@@ -69,7 +69,7 @@ func testValidateWithWorkload(t *testing.T, container *corev1.Container, resourc
 	assert.NoError(t, err, "Expected no error when parsing config")
 
 	var results ResultSet
-	results, err = applyContainerSchemaChecks(&parsedConf, nil, workload, container, false)
+	results, err = applyContainerSchemaChecks(&parsedConf, nil, workload, container, conf.ContainerClassContainer)
 	if err != nil {
 		panic(err)
 	}
@@ -93,7 +93,7 @@ func TestValidateResourcesEmptyConfig(t *testing.T) {
 		Name: "Empty",
 	}
 
-	results, err := applyContainerSchemaChecks(&conf.Configuration{}, nil, getEmptyWorkload(t, ""), container, false)
+	results, err := applyContainerSchemaChecks(&conf.Configuration{}, nil, getEmptyWorkload(t, ""), container, conf.ContainerClassContainer)
 	if err != nil {
 		panic(err)
 	}
@@ -172,17 +172,18 @@ func TestValidateHealthChecks(t *testing.T) {
 	w1 := []ResultMessage{l}
 
 	var testCases = []struct {
-		name      string
-		probes    map[string]conf.Severity
-		container *corev1.Container
-		isInit    bool
-		dangers   *[]ResultMessage
-		warnings  *[]ResultMessage
+		name           string
+		probes         map[string]conf.Severity
+		container      *corev1.Container
+		containerClass conf.ContainerClass
+		dangers        *[]ResultMessage
+		warnings       *[]ResultMessage
 	}{
 		{name: "probes not configured", probes: p1, container: emptyContainer, dangers: &f1},
 		{name: "probes not required", probes: p2, container: emptyContainer, dangers: &f1},
 		{name: "probes required & configured", probes: p3, container: goodContainer, dangers: &f1},
-		{name: "probes required, not configured, but init", probes: p3, container: emptyContainer, isInit: true, dangers: &f1},
+		{name: "probes required, not configured, but init", probes: p3, container: emptyContainer, containerClass: conf.ContainerClassInit, dangers: &f1},
+		{name: "probes required, not configured, but ephemeral", probes: p3, container: emptyContainer, containerClass: conf.ContainerClassEphemeral, dangers: &f1},
 		{name: "probes required & not configured", probes: p3, container: emptyContainer, dangers: &f2, warnings: &w1},
 		{name: "probes configured, but not required", probes: p2, container: goodContainer, dangers: &f1},
 	}
@@ -190,7 +191,7 @@ func TestValidateHealthChecks(t *testing.T) {
 	for idx, tt := range testCases {
 		t.Run(tt.name, func(t *testing.T) {
 			controller := getEmptyWorkload(t, "")
-			results, err := applyContainerSchemaChecks(&conf.Configuration{Checks: tt.probes}, nil, controller, tt.container, tt.isInit)
+			results, err := applyContainerSchemaChecks(&conf.Configuration{Checks: tt.probes}, nil, controller, tt.container, tt.containerClass)
 			if err != nil {
 				panic(err)
 			}
@@ -304,7 +305,7 @@ func TestValidateImage(t *testing.T) {
 	for _, tt := range testCases {
 		t.Run(tt.name, func(t *testing.T) {
 			controller := getEmptyWorkload(t, "")
-			results, err := applyContainerSchemaChecks(&conf.Configuration{Checks: tt.image}, nil, controller, tt.container, false)
+			results, err := applyContainerSchemaChecks(&conf.Configuration{Checks: tt.image}, nil, controller, tt.container, conf.ContainerClassContainer)
 			if err != nil {
 				panic(err)
 			}
@@ -421,7 +422,7 @@ func TestValidateNetworking(t *testing.T) {
 	for _, tt := range testCases {
 		t.Run(tt.name, func(t *testing.T) {
 			controller := getEmptyWorkload(t, "")
-			results, err := applyContainerSchemaChecks(&conf.Configuration{Checks: tt.networkConf}, nil, controller, tt.container, false)
+			results, err := applyContainerSchemaChecks(&conf.Configuration{Checks: tt.networkConf}, nil, controller, tt.container, conf.ContainerClassContainer)
 			if err != nil {
 				panic(err)
 			}
@@ -926,7 +927,7 @@ func TestValidateSecurity(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			workload, err := kube.NewGenericResourceFromPod(corev1.Pod{Spec: *tt.pod}, nil)
 			assert.NoError(t, err)
-			results, err := applyContainerSchemaChecks(&conf.Configuration{Checks: tt.securityConf}, nil, workload, tt.container, false)
+			results, err := applyContainerSchemaChecks(&conf.Configuration{Checks: tt.securityConf}, nil, workload, tt.container, conf.ContainerClassContainer)
 			if err != nil {
 				panic(err)
 			}
@@ -1071,7 +1072,7 @@ func TestValidateRunAsRoot(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			workload, err := kube.NewGenericResourceFromPod(corev1.Pod{Spec: *tt.pod}, nil)
 			assert.NoError(t, err)
-			results, err := applyContainerSchemaChecks(&config, nil, workload, tt.container, false)
+			results, err := applyContainerSchemaChecks(&config, nil, workload, tt.container, conf.ContainerClassContainer)
 			if err != nil {
 				panic(err)
 			}
@@ -0,0 +1,233 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronDescriptors are the shorthands accepted by the CronJob controller
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var cronMonths = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+var cronWeekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+type cronField struct {
+	min   int
+	max   int
+	names []string
+}
+
+var (
+	cronMinuteField  = cronField{min: 0, max: 59}
+	cronHourField    = cronField{min: 0, max: 23}
+	cronDayField     = cronField{min: 1, max: 31}
+	cronMonthField   = cronField{min: 1, max: 12, names: cronMonths}
+	cronWeekdayField = cronField{min: 0, max: 6, names: cronWeekdays}
+)
+
+// cronSchedule is a parsed CronJob schedule, in the format understood by the CronJob controller
+type cronSchedule struct {
+	minutes  []bool
+	hours    []bool
+	days     []bool
+	months   []bool
+	weekdays []bool
+	// anyDay and anyWeekday are set when the field is a wildcard. A schedule restricting both the day
+	// of month and the day of week runs on days matching either.
+	anyDay     bool
+	anyWeekday bool
+	// every is set for @every schedules, which run at a fixed interval
+	every time.Duration
+}
+
+func parseCronSchedule(schedule string) (*cronSchedule, error) {
+	fields := strings.Fields(schedule)
+	if len(fields) > 0 && (strings.HasPrefix(fields[0], "TZ=") || strings.HasPrefix(fields[0], "CRON_TZ=")) {
+		fields = fields[1:]
+	}
+	if len(fields) == 2 && fields[0] == "@every" {
+		every, err := time.ParseDuration(fields[1])
+		if err != nil || every <= 0 {
+			return nil, fmt.Errorf("invalid interval %s", fields[1])
+		}
+		return &cronSchedule{every: every}, nil
+	}
+	if len(fields) == 1 {
+		if descriptor, ok := cronDescriptors[strings.ToLower(fields[0])]; ok {
+			fields = strings.Fields(descriptor)
+		}
+	}
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, found %d", len(fields))
+	}
+	cron := cronSchedule{
+		anyDay:     isCronWildcard(fields[2]),
+		anyWeekday: isCronWildcard(fields[4]),
+	}
+	var err error
+	if cron.minutes, err = cronMinuteField.parse(fields[0]); err != nil {
+		return nil, err
+	}
+	if cron.hours, err = cronHourField.parse(fields[1]); err != nil {
+		return nil, err
+	}
+	if cron.days, err = cronDayField.parse(fields[2]); err != nil {
+		return nil, err
+	}
+	if cron.months, err = cronMonthField.parse(fields[3]); err != nil {
+		return nil, err
+	}
+	if cron.weekdays, err = cronWeekdayField.parse(fields[4]); err != nil {
+		return nil, err
+	}
+	return &cron, nil
+}
+
+func isCronWildcard(field string) bool {
+	return strings.HasPrefix(field, "*") || strings.HasPrefix(field, "?")
+}
+
+// parse returns the values matched by a field, indexed by value
+func (field cronField) parse(expression string) ([]bool, error) {
+	values := make([]bool, field.max+1)
+	for _, item := range strings.Split(expression, ",") {
+		rangeExpr, stepExpr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepExpr)
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in %s", item)
+			}
+		}
+		start, end := field.min, field.max
+		if rangeExpr != "*" && rangeExpr != "?" {
+			startExpr, endExpr, isRange := strings.Cut(rangeExpr, "-")
+			var err error
+			if start, err = field.parseValue(startExpr); err != nil {
+				return nil, err
+			}
+			if isRange {
+				if end, err = field.parseValue(endExpr); err != nil {
+					return nil, err
+				}
+			} else if !hasStep {
+				end = start
+			}
+		}
+		if start > end {
+			return nil, fmt.Errorf("invalid range %s", item)
+		}
+		for value := start; value <= end; value += step {
+			values[value] = true
+		}
+	}
+	return values, nil
+}
+
+func (field cronField) parseValue(expression string) (int, error) {
+	for idx, name := range field.names {
+		if strings.EqualFold(expression, name) {
+			return idx + field.min, nil
+		}
+	}
+	value, err := strconv.Atoi(expression)
+	if err != nil || value < field.min || value > field.max {
+		return 0, fmt.Errorf("value %s is out of range %d-%d", expression, field.min, field.max)
+	}
+	return value, nil
+}
+
+func (cron cronSchedule) runsOn(day time.Time) bool {
+	if !cron.months[int(day.Month())] {
+		return false
+	}
+	dayMatches := cron.days[day.Day()]
+	weekdayMatches := cron.weekdays[int(day.Weekday())]
+	if cron.anyDay || cron.anyWeekday {
+		return dayMatches && weekdayMatches
+	}
+	return dayMatches || weekdayMatches
+}
+
+// minimumInterval returns the shortest time between two runs of the schedule, or false if it never runs twice
+func (cron cronSchedule) minimumInterval() (time.Duration, bool) {
+	if cron.every > 0 {
+		return cron.every, true
+	}
+	runs := []int{}
+	for hour, hourMatches := range cron.hours {
+		for minute, minuteMatches := range cron.minutes {
+			if hourMatches && minuteMatches {
+				runs = append(runs, hour*60+minute)
+			}
+		}
+	}
+	if len(runs) == 0 {
+		return 0, false
+	}
+	shortest := -1
+	for idx := 1; idx < len(runs); idx++ {
+		if gap := runs[idx] - runs[idx-1]; shortest < 0 || gap < shortest {
+			shortest = gap
+		}
+	}
+	// Four years cover every combination of weekday, month length and leap year
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(4, 0, 0)
+	lastDay := -1
+	runsOnAnyDay := false
+	for day, idx := start, 0; day.Before(end); day, idx = day.AddDate(0, 0, 1), idx+1 {
+		if !cron.runsOn(day) {
+			continue
+		}
+		runsOnAnyDay = true
+		if lastDay >= 0 {
+			gap := (idx-lastDay)*24*60 - runs[len(runs)-1] + runs[0]
+			if shortest < 0 || gap < shortest {
+				shortest = gap
+			}
+		}
+		lastDay = idx
+	}
+	if !runsOnAnyDay || shortest < 0 {
+		return 0, false
+	}
+	return time.Duration(shortest) * time.Minute, true
+}
+
+// formatInterval prints a duration without the zero units Go adds, e.g. 1h instead of 1h0m0s
+func formatInterval(interval time.Duration) string {
+	formatted := interval.String()
+	if strings.HasSuffix(formatted, "m0s") {
+		formatted = strings.TrimSuffix(formatted, "0s")
+	}
+	if strings.HasSuffix(formatted, "h0m") {
+		formatted = strings.TrimSuffix(formatted, "0m")
+	}
+	return formatted
+}
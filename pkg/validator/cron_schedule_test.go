@@ -0,0 +1,97 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	conf "github.com/fairwindsops/polaris/pkg/config"
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+func TestCronScheduleMinimumInterval(t *testing.T) {
+	intervals := map[string]time.Duration{
+		"* * * * *":                      time.Minute,
+		"*/15 * * * *":                   15 * time.Minute,
+		"0,45 * * * *":                   15 * time.Minute,
+		"30 9-17 * * 1-5":                time.Hour,
+		"0 23 * * 1-5":                   24 * time.Hour,
+		"0 0,23 * * mon":                 23 * time.Hour,
+		"0 0 1,15 * *":                   14 * 24 * time.Hour,
+		"0 0 1 * sun":                    24 * time.Hour,
+		"@hourly":                        time.Hour,
+		"@every 90s":                     90 * time.Second,
+		"CRON_TZ=Europe/Paris 5 4 * * *": 24 * time.Hour,
+	}
+	for schedule, expected := range intervals {
+		cron, err := parseCronSchedule(schedule)
+		assert.NoError(t, err, schedule)
+		interval, ok := cron.minimumInterval()
+		assert.True(t, ok, schedule)
+		assert.Equal(t, expected, interval, schedule)
+	}
+
+	cron, err := parseCronSchedule("0 0 30 feb *")
+	assert.NoError(t, err)
+	_, ok := cron.minimumInterval()
+	assert.False(t, ok)
+
+	for _, schedule := range []string{"* * * *", "60 * * * *", "0 0 * foo *", "*/0 * * * *", "5-1 * * * *", "@every 1x"} {
+		_, err := parseCronSchedule(schedule)
+		assert.Error(t, err, schedule)
+	}
+}
+
+func TestFormatInterval(t *testing.T) {
+	assert.Equal(t, "5m", formatInterval(5*time.Minute))
+	assert.Equal(t, "1h", formatInterval(time.Hour))
+	assert.Equal(t, "1h30m", formatInterval(90*time.Minute))
+	assert.Equal(t, "30s", formatInterval(30*time.Second))
+}
+
+func TestCronJobScheduleTooFrequent(t *testing.T) {
+	c := conf.Configuration{
+		Checks: map[string]conf.Severity{
+			"cronJobScheduleTooFrequent": conf.SeverityWarning,
+		},
+		CronJobMinimumInterval: "1h",
+	}
+	provider, err := kube.CreateResourceProviderFromYaml(`
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: sync
+spec:
+  schedule: "*/30 * * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+            - name: sync
+              image: busybox:1.36
+          restartPolicy: OnFailure
+`)
+	assert.NoError(t, err)
+	auditData, err := RunAudit(c, provider)
+	assert.NoError(t, err)
+	assert.Len(t, auditData.Results, 1)
+	result := auditData.Results[0].Results["cronJobScheduleTooFrequent"]
+	assert.False(t, result.Success)
+	assert.Equal(t, []string{`schedule "*/30 * * * *" runs every 30m, more often than the minimum of 1h`}, result.Details)
+}
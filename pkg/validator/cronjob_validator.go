@@ -0,0 +1,60 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+
+	"github.com/qri-io/jsonschema"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/fairwindsops/polaris/pkg/config"
+)
+
+func init() {
+	registerCustomChecks("cronJobScheduleTooFrequent", cronJobScheduleTooFrequent)
+}
+
+// cronJobScheduleTooFrequent fails if the CronJob can run more often than the configured cronJobMinimumInterval
+func cronJobScheduleTooFrequent(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	schedule, found, _ := unstructured.NestedString(test.Resource.Resource.Object, "spec", "schedule")
+	if !found {
+		return true, nil, nil
+	}
+	cron, err := parseCronSchedule(schedule)
+	if err != nil {
+		return false, []jsonschema.ValError{{
+			PropertyPath: "spec.schedule",
+			InvalidValue: schedule,
+			Message:      fmt.Sprintf("schedule %q is invalid: %v", schedule, err),
+		}}, nil
+	}
+	interval, ok := cron.minimumInterval()
+	if !ok {
+		return true, nil, nil
+	}
+	minimum := config.DefaultCronJobMinimumInterval
+	if test.Config != nil {
+		minimum = test.Config.GetCronJobMinimumInterval()
+	}
+	if interval >= minimum {
+		return true, nil, nil
+	}
+	return false, []jsonschema.ValError{{
+		PropertyPath: "spec.schedule",
+		InvalidValue: schedule,
+		Message:      fmt.Sprintf("schedule %q runs every %s, more often than the minimum of %s", schedule, formatInterval(interval), formatInterval(minimum)),
+	}}, nil
+}
@@ -4,11 +4,19 @@ import (
 	"sync"
 
 	"github.com/qri-io/jsonschema"
+
+	"github.com/fairwindsops/polaris/pkg/config"
 )
 
 type validatorFunction func(test schemaTestCase) (bool, []jsonschema.ValError, error)
 
+type mutationFunction func(test schemaTestCase) ([]config.Mutation, error)
+
+type applicabilityFunction func(test schemaTestCase) bool
+
 var validatorMapper = map[string]validatorFunction{}
+var mutationMapper = map[string]mutationFunction{}
+var applicabilityMapper = map[string]applicabilityFunction{}
 var lock = &sync.Mutex{}
 
 func registerCustomChecks(name string, check validatorFunction) {
@@ -17,3 +25,22 @@ func registerCustomChecks(name string, check validatorFunction) {
 
 	validatorMapper[name] = check
 }
+
+// registerCustomMutations registers a function that computes the mutations for a failing custom check,
+// for cases where they depend on the resource and can't be expressed in the check's YAML
+func registerCustomMutations(name string, mutation mutationFunction) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	mutationMapper[name] = mutation
+}
+
+// registerCustomApplicability registers a function that decides if a custom check applies to a resource.
+// Checks that don't apply, e.g. because the resource doesn't use what they check, are left out of the
+// results instead of succeeding, so they don't raise the score.
+func registerCustomApplicability(name string, applies applicabilityFunction) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	applicabilityMapper[name] = applies
+}
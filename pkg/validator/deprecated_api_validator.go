@@ -0,0 +1,172 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/qri-io/jsonschema"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/fairwindsops/polaris/pkg/config"
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+//go:embed deprecated_apis.yaml
+var deprecatedAPIsYAML []byte
+
+// deprecatedAPI is an entry of the embedded table of deprecated and removed API versions
+type deprecatedAPI struct {
+	APIVersion     string `json:"apiVersion"`
+	Kind           string `json:"kind"`
+	DeprecatedIn   string `json:"deprecatedIn"`
+	RemovedIn      string `json:"removedIn"`
+	ReplacementAPI string `json:"replacementAPI"`
+	Note           string `json:"note"`
+	Compatible     bool   `json:"compatible"`
+
+	deprecatedIn config.KubernetesVersion
+	removedIn    config.KubernetesVersion
+}
+
+// deprecatedAPIs is keyed by apiVersion/kind
+var deprecatedAPIs = map[string]deprecatedAPI{}
+
+func init() {
+	registerCustomChecks("deprecatedAPIVersion", deprecatedAPIVersion)
+	registerCustomMutations("deprecatedAPIVersion", deprecatedAPIVersionMutations)
+	registerCustomApplicability("deprecatedAPIVersion", func(test schemaTestCase) bool {
+		apiVersion, kind := getOriginalAPIVersion(test.Resource)
+		_, ok := deprecatedAPIs[apiVersion+"/"+kind]
+		return ok
+	})
+
+	apis := []deprecatedAPI{}
+	if err := yaml.Unmarshal(deprecatedAPIsYAML, &apis); err != nil {
+		panic(err)
+	}
+	for _, api := range apis {
+		var err error
+		if api.deprecatedIn, err = config.ParseKubernetesVersion(api.DeprecatedIn); err != nil {
+			panic(err)
+		}
+		if api.removedIn, err = config.ParseKubernetesVersion(api.RemovedIn); err != nil {
+			panic(err)
+		}
+		deprecatedAPIs[api.APIVersion+"/"+api.Kind] = api
+	}
+}
+
+// isDeprecated returns whether the API is deprecated and/or removed in a Kubernetes version.
+// Every API in the table is considered deprecated when the version is unknown.
+func (api deprecatedAPI) isDeprecated(version config.KubernetesVersion) (deprecated bool, removed bool) {
+	if version.IsZero() {
+		return true, false
+	}
+	removed = !api.removedIn.IsZero() && version.Compare(api.removedIn) >= 0
+	deprecated = removed || version.Compare(api.deprecatedIn) >= 0
+	return deprecated, removed
+}
+
+func (api deprecatedAPI) message(removed bool) string {
+	var msg string
+	if removed {
+		msg = fmt.Sprintf("%s %s was removed in Kubernetes %s", api.APIVersion, api.Kind, api.RemovedIn)
+	} else if api.RemovedIn != "" {
+		msg = fmt.Sprintf("%s %s is deprecated since Kubernetes %s and removed in %s", api.APIVersion, api.Kind, api.DeprecatedIn, api.RemovedIn)
+	} else {
+		msg = fmt.Sprintf("%s %s is deprecated since Kubernetes %s", api.APIVersion, api.Kind, api.DeprecatedIn)
+	}
+	if api.ReplacementAPI != "" {
+		msg += fmt.Sprintf(", use %s instead", api.ReplacementAPI)
+	}
+	if api.Note != "" {
+		msg += ", " + api.Note
+	}
+	return msg
+}
+
+// getOriginalAPIVersion returns the apiVersion and kind a resource was written with.
+// Objects read from a cluster are served in the preferred version, so the last applied
+// configuration is used when available.
+func getOriginalAPIVersion(resource kube.GenericResource) (string, string) {
+	apiVersion := resource.Resource.GetAPIVersion()
+	kind := resource.Resource.GetKind()
+	if resource.ObjectMeta == nil {
+		return apiVersion, kind
+	}
+	lastApplied, ok := resource.ObjectMeta.GetAnnotations()[corev1.LastAppliedConfigAnnotation]
+	if !ok {
+		return apiVersion, kind
+	}
+	original := struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+	}{}
+	if err := json.Unmarshal([]byte(lastApplied), &original); err != nil {
+		logrus.Debugf("could not parse %s annotation of %s: %v", corev1.LastAppliedConfigAnnotation, resource.ObjectMeta.GetName(), err)
+		return apiVersion, kind
+	}
+	if original.APIVersion != "" && original.Kind != "" {
+		return original.APIVersion, original.Kind
+	}
+	return apiVersion, kind
+}
+
+func findDeprecatedAPI(test schemaTestCase) (*deprecatedAPI, bool) {
+	apiVersion, kind := getOriginalAPIVersion(test.Resource)
+	api, ok := deprecatedAPIs[apiVersion+"/"+kind]
+	if !ok {
+		return nil, false
+	}
+	deprecated, removed := api.isDeprecated(test.KubernetesVersion)
+	if !deprecated {
+		return nil, false
+	}
+	return &api, removed
+}
+
+func deprecatedAPIVersion(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	api, removed := findDeprecatedAPI(test)
+	if api == nil {
+		return true, nil, nil
+	}
+	return false, []jsonschema.ValError{
+		{
+			PropertyPath: "apiVersion",
+			InvalidValue: api.APIVersion,
+			Message:      api.message(removed),
+		},
+	}, nil
+}
+
+// deprecatedAPIVersionMutations rewrites apiVersion when the replacement API shares the same schema
+func deprecatedAPIVersionMutations(test schemaTestCase) ([]config.Mutation, error) {
+	api, _ := findDeprecatedAPI(test)
+	if api == nil || !api.Compatible || api.ReplacementAPI == "" {
+		return nil, nil
+	}
+	return []config.Mutation{
+		{
+			Op:    "replace",
+			Path:  "/apiVersion",
+			Value: api.ReplacementAPI,
+		},
+	}, nil
+}
@@ -0,0 +1,458 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/fatih/color"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+// limitRequestRatioRanges are the upper bounds of the ranges of EfficiencyReport.Ratios
+var limitRequestRatioRanges = []struct {
+	Name    string
+	Maximum float64
+}{
+	{"1x", 1},
+	{"up to 2x", 2},
+	{"up to 4x", 4},
+	{"over 4x", math.Inf(1)},
+}
+
+const noLimitRange = "no limit"
+
+// ResourceTotals sums CPU requests and limits in millicores, and memory requests and limits in bytes.
+// A missing request defaults to the limit, like in Kubernetes.
+type ResourceTotals struct {
+	CPURequests    int64
+	CPULimits      int64
+	MemoryRequests int64
+	MemoryLimits   int64
+}
+
+// ResourceCapacity is an amount of CPU in millicores and memory in bytes
+type ResourceCapacity struct {
+	CPU    int64
+	Memory int64
+}
+
+// ResourceRatio compares amounts of CPU and memory, e.g. limits to requests
+type ResourceRatio struct {
+	CPU    float64
+	Memory float64
+}
+
+// EfficiencyReport sums the requests and limits of the audited workloads, and compares them to the allocatable
+// resources of the Nodes
+type EfficiencyReport struct {
+	Total ResourceTotals
+	// Allocatable sums the allocatable resources of the Nodes, if they were audited
+	Allocatable ResourceCapacity
+	// LargestNode has the largest allocatable CPU and memory of a single Node
+	LargestNode ResourceCapacity
+	// Overcommit is the ratio of the limits to the requests
+	Overcommit ResourceRatio
+	// Requested and Limited are the ratios of the requests and limits to the allocatable resources
+	Requested  ResourceRatio
+	Limited    ResourceRatio
+	Ratios     []LimitRequestRatioRange
+	Namespaces []NamespaceEfficiency
+	Workloads  []WorkloadEfficiency
+}
+
+// LimitRequestRatioRange counts the containers whose ratio of limit to request falls in a range. Containers are
+// counted once, whatever their number of replicas.
+type LimitRequestRatioRange struct {
+	Range  string
+	CPU    int
+	Memory int
+}
+
+// NamespaceEfficiency sums the requests and limits of the workloads in a namespace
+type NamespaceEfficiency struct {
+	Namespace string
+	Workloads int
+	Pods      int
+	Totals    ResourceTotals
+}
+
+// WorkloadEfficiency sums the requests and limits of a workload's pods
+type WorkloadEfficiency struct {
+	Kind       string
+	Namespace  string
+	Name       string
+	Replicas   int
+	Totals     ResourceTotals
+	Containers []ContainerEfficiency
+}
+
+// ContainerEfficiency sums the requests and limits of a container in every replica of its workload
+type ContainerEfficiency struct {
+	Name   string
+	Totals ResourceTotals
+}
+
+func (totals *ResourceTotals) add(other ResourceTotals) {
+	totals.CPURequests += other.CPURequests
+	totals.CPULimits += other.CPULimits
+	totals.MemoryRequests += other.MemoryRequests
+	totals.MemoryLimits += other.MemoryLimits
+}
+
+func (totals ResourceTotals) multiply(factor int) ResourceTotals {
+	return ResourceTotals{
+		CPURequests:    totals.CPURequests * int64(factor),
+		CPULimits:      totals.CPULimits * int64(factor),
+		MemoryRequests: totals.MemoryRequests * int64(factor),
+		MemoryLimits:   totals.MemoryLimits * int64(factor),
+	}
+}
+
+// getRequestAndLimit returns a container's request and limit of a resource, in millicores for CPU and bytes
+// otherwise. The request defaults to the limit.
+func getRequestAndLimit(container corev1.Container, name corev1.ResourceName) (request, limit int64, hasLimit bool) {
+	getValue := func(quantity resource.Quantity) int64 {
+		if name == corev1.ResourceCPU {
+			return quantity.MilliValue()
+		}
+		return quantity.Value()
+	}
+	limitQuantity, hasLimit := container.Resources.Limits[name]
+	if hasLimit {
+		limit = getValue(limitQuantity)
+	}
+	if requestQuantity, ok := container.Resources.Requests[name]; ok {
+		request = getValue(requestQuantity)
+	} else {
+		request = limit
+	}
+	return request, limit, hasLimit
+}
+
+func getContainerTotals(container corev1.Container) ResourceTotals {
+	totals := ResourceTotals{}
+	totals.CPURequests, totals.CPULimits, _ = getRequestAndLimit(container, corev1.ResourceCPU)
+	totals.MemoryRequests, totals.MemoryLimits, _ = getRequestAndLimit(container, corev1.ResourceMemory)
+	return totals
+}
+
+// getRunningContainers returns the containers that run for the lifetime of a pod, including sidecars declared
+// as init containers. Other init containers run before them, and are left out.
+func getRunningContainers(podSpec *corev1.PodSpec) []corev1.Container {
+	containers := []corev1.Container{}
+	for _, container := range podSpec.InitContainers {
+		if container.RestartPolicy != nil && *container.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			containers = append(containers, container)
+		}
+	}
+	return append(containers, podSpec.Containers...)
+}
+
+// getPodTotals sums the requests and limits of a single pod
+func getPodTotals(podSpec *corev1.PodSpec) ResourceTotals {
+	totals := ResourceTotals{}
+	for _, container := range getRunningContainers(podSpec) {
+		totals.add(getContainerTotals(container))
+	}
+	return totals
+}
+
+// getWorkloadReplicas returns how many pods a workload runs. Workloads scaled by a HorizontalPodAutoscaler
+// without spec.replicas run its minimum number of replicas.
+func getWorkloadReplicas(provider *kube.ResourceProvider, workload kube.GenericResource) int {
+	getInt := func(fields ...string) (int, bool) {
+		value, found, err := unstructured.NestedFieldNoCopy(workload.Resource.Object, fields...)
+		if !found || err != nil {
+			return 0, false
+		}
+		// The type of numbers depends on how the manifest was decoded
+		switch number := value.(type) {
+		case int:
+			return number, true
+		case int64:
+			return int(number), true
+		case float64:
+			return int(number), true
+		}
+		return 0, false
+	}
+	switch workload.Kind {
+	case "Pod":
+		return 1
+	case "DaemonSet":
+		if replicas, ok := getInt("status", "desiredNumberScheduled"); ok {
+			return replicas
+		}
+		if provider != nil && len(provider.Nodes) > 0 {
+			return len(provider.Nodes)
+		}
+		return 1
+	case "Job":
+		if replicas, ok := getInt("spec", "parallelism"); ok {
+			return replicas
+		}
+		return 1
+	case "CronJob":
+		if replicas, ok := getInt("spec", "jobTemplate", "spec", "parallelism"); ok {
+			return replicas
+		}
+		return 1
+	}
+	if replicas, ok := getInt("spec", "replicas"); ok {
+		return replicas
+	}
+	if hpa := findHorizontalPodAutoscaler(provider, workload); hpa != nil && hpa.Spec.MinReplicas != nil {
+		return int(*hpa.Spec.MinReplicas)
+	}
+	return 1
+}
+
+// getAllocatable returns the allocatable resources of a Node
+func getAllocatable(node corev1.Node) ResourceCapacity {
+	return ResourceCapacity{
+		CPU:    node.Status.Allocatable.Cpu().MilliValue(),
+		Memory: node.Status.Allocatable.Memory().Value(),
+	}
+}
+
+// getLargestNode returns the largest allocatable CPU and memory of a single Node
+func getLargestNode(nodes []corev1.Node) ResourceCapacity {
+	largest := ResourceCapacity{}
+	for _, node := range nodes {
+		allocatable := getAllocatable(node)
+		if allocatable.CPU > largest.CPU {
+			largest.CPU = allocatable.CPU
+		}
+		if allocatable.Memory > largest.Memory {
+			largest.Memory = allocatable.Memory
+		}
+	}
+	return largest
+}
+
+func getRatio(numerator, denominator int64) float64 {
+	if denominator == 0 {
+		return 0
+	}
+	return float64(numerator) / float64(denominator)
+}
+
+// getLimitRequestRatioRange returns the range of EfficiencyReport.Ratios a container's resource falls in
+func getLimitRequestRatioRange(container corev1.Container, name corev1.ResourceName) string {
+	request, limit, hasLimit := getRequestAndLimit(container, name)
+	if !hasLimit {
+		return noLimitRange
+	}
+	ratio := getRatio(limit, request)
+	for _, ratioRange := range limitRequestRatioRanges {
+		if ratio <= ratioRange.Maximum {
+			return ratioRange.Name
+		}
+	}
+	return ""
+}
+
+// getEfficiencyReport sums the requests and limits of every workload, multiplied by their replicas
+func getEfficiencyReport(provider *kube.ResourceProvider) *EfficiencyReport {
+	report := EfficiencyReport{}
+	namespaces := map[string]*NamespaceEfficiency{}
+	ratios := map[string]*LimitRequestRatioRange{}
+	for _, resources := range provider.Resources {
+		for _, workload := range resources {
+			if workload.PodSpec == nil {
+				continue
+			}
+			replicas := getWorkloadReplicas(provider, workload)
+			workloadEfficiency := WorkloadEfficiency{
+				Kind:      workload.Kind,
+				Namespace: workload.ObjectMeta.GetNamespace(),
+				Name:      workload.ObjectMeta.GetName(),
+				Replicas:  replicas,
+			}
+			for _, container := range getRunningContainers(workload.PodSpec) {
+				totals := getContainerTotals(container).multiply(replicas)
+				workloadEfficiency.Totals.add(totals)
+				workloadEfficiency.Containers = append(workloadEfficiency.Containers, ContainerEfficiency{Name: container.Name, Totals: totals})
+				for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+					ratioRange := getLimitRequestRatioRange(container, name)
+					if _, ok := ratios[ratioRange]; !ok {
+						ratios[ratioRange] = &LimitRequestRatioRange{Range: ratioRange}
+					}
+					if name == corev1.ResourceCPU {
+						ratios[ratioRange].CPU++
+					} else {
+						ratios[ratioRange].Memory++
+					}
+				}
+			}
+			report.Workloads = append(report.Workloads, workloadEfficiency)
+			report.Total.add(workloadEfficiency.Totals)
+			if _, ok := namespaces[workloadEfficiency.Namespace]; !ok {
+				namespaces[workloadEfficiency.Namespace] = &NamespaceEfficiency{Namespace: workloadEfficiency.Namespace}
+			}
+			namespace := namespaces[workloadEfficiency.Namespace]
+			namespace.Workloads++
+			namespace.Pods += replicas
+			namespace.Totals.add(workloadEfficiency.Totals)
+		}
+	}
+	if len(report.Workloads) == 0 {
+		return nil
+	}
+	for _, node := range provider.Nodes {
+		allocatable := getAllocatable(node)
+		report.Allocatable.CPU += allocatable.CPU
+		report.Allocatable.Memory += allocatable.Memory
+	}
+	report.LargestNode = getLargestNode(provider.Nodes)
+	report.Overcommit = ResourceRatio{
+		CPU:    getRatio(report.Total.CPULimits, report.Total.CPURequests),
+		Memory: getRatio(report.Total.MemoryLimits, report.Total.MemoryRequests),
+	}
+	report.Requested = ResourceRatio{
+		CPU:    getRatio(report.Total.CPURequests, report.Allocatable.CPU),
+		Memory: getRatio(report.Total.MemoryRequests, report.Allocatable.Memory),
+	}
+	report.Limited = ResourceRatio{
+		CPU:    getRatio(report.Total.CPULimits, report.Allocatable.CPU),
+		Memory: getRatio(report.Total.MemoryLimits, report.Allocatable.Memory),
+	}
+	for _, name := range append([]string{noLimitRange}, getLimitRequestRatioRangeNames()...) {
+		if ratioRange, ok := ratios[name]; ok {
+			report.Ratios = append(report.Ratios, *ratioRange)
+		}
+	}
+	for _, namespace := range namespaces {
+		report.Namespaces = append(report.Namespaces, *namespace)
+	}
+	sort.Slice(report.Namespaces, func(i, j int) bool {
+		return report.Namespaces[i].Namespace < report.Namespaces[j].Namespace
+	})
+	// The largest workloads come first
+	sort.Slice(report.Workloads, func(i, j int) bool {
+		a, b := report.Workloads[i], report.Workloads[j]
+		if a.Totals.CPURequests != b.Totals.CPURequests {
+			return a.Totals.CPURequests > b.Totals.CPURequests
+		}
+		if a.Totals.MemoryRequests != b.Totals.MemoryRequests {
+			return a.Totals.MemoryRequests > b.Totals.MemoryRequests
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return &report
+}
+
+func getLimitRequestRatioRangeNames() []string {
+	names := make([]string, len(limitRequestRatioRanges))
+	for idx, ratioRange := range limitRequestRatioRanges {
+		names[idx] = ratioRange.Name
+	}
+	return names
+}
+
+func formatCPU(millicores int64) string {
+	return resource.NewMilliQuantity(millicores, resource.DecimalSI).String()
+}
+
+func formatMemory(bytes int64) string {
+	if bytes == 0 {
+		return "0"
+	}
+	if bytes >= 1<<30 {
+		return fmt.Sprintf("%.1fGi", float64(bytes)/(1<<30))
+	}
+	return fmt.Sprintf("%.0fMi", float64(bytes)/(1<<20))
+}
+
+func formatPercent(ratio float64) string {
+	return fmt.Sprintf("%.0f%%", ratio*100)
+}
+
+// String describes the requests and limits
+func (totals ResourceTotals) String() string {
+	return fmt.Sprintf("cpu requests %s, limits %s | memory requests %s, limits %s",
+		formatCPU(totals.CPURequests), formatCPU(totals.CPULimits), formatMemory(totals.MemoryRequests), formatMemory(totals.MemoryLimits))
+}
+
+// GetPrettyOutput returns a human-readable summary, for the pretty output of an audit
+func (report EfficiencyReport) GetPrettyOutput() string {
+	str := fmt.Sprintf("      Total: %s\n", report.Total.String())
+	if report.Allocatable.CPU > 0 {
+		str += fmt.Sprintf("      Allocatable: cpu %s, memory %s | requested: cpu %s, memory %s | limited: cpu %s, memory %s\n",
+			formatCPU(report.Allocatable.CPU), formatMemory(report.Allocatable.Memory),
+			formatPercent(report.Requested.CPU), formatPercent(report.Requested.Memory),
+			formatPercent(report.Limited.CPU), formatPercent(report.Limited.Memory))
+	}
+	return color.CyanString(str)
+}
+
+// GetEfficiencyPrettyOutput returns a human-readable efficiency report
+func (res AuditData) GetEfficiencyPrettyOutput(useColor bool) string {
+	color.NoColor = !useColor
+	str := titleColor.Sprint(fmt.Sprintf("Polaris efficiency report for %s at %s\n", res.DisplayName, res.AuditTime))
+	report := res.Efficiency
+	if report == nil {
+		color.NoColor = false
+		return str + "    No workloads were audited\n"
+	}
+	str += fmt.Sprintf("    Total: %s\n", report.Total.String())
+	str += fmt.Sprintf("    Overcommit (limits/requests): cpu %.1fx, memory %.1fx\n", report.Overcommit.CPU, report.Overcommit.Memory)
+	if report.Allocatable.CPU > 0 {
+		str += fmt.Sprintf("    Allocatable: cpu %s, memory %s | largest node: cpu %s, memory %s\n",
+			formatCPU(report.Allocatable.CPU), formatMemory(report.Allocatable.Memory),
+			formatCPU(report.LargestNode.CPU), formatMemory(report.LargestNode.Memory))
+		str += fmt.Sprintf("    Requested: cpu %s, memory %s | limited: cpu %s, memory %s\n",
+			formatPercent(report.Requested.CPU), formatPercent(report.Requested.Memory),
+			formatPercent(report.Limited.CPU), formatPercent(report.Limited.Memory))
+	}
+
+	str += "\n" + titleColor.Sprint("Limit/request ratios") + "\n"
+	str += fmt.Sprintf("    %s %8s %8s\n", fillString("", 12), "cpu", "memory")
+	for _, ratioRange := range report.Ratios {
+		str += fmt.Sprintf("    %s %8d %8d\n", checkColor.Sprint(fillString(ratioRange.Range, 12)), ratioRange.CPU, ratioRange.Memory)
+	}
+
+	str += "\n" + titleColor.Sprint("Namespaces") + "\n"
+	for _, namespace := range report.Namespaces {
+		str += fmt.Sprintf("    %s: %d workload(s), %d pod(s) | %s\n", checkColor.Sprint(formatNamespace(namespace.Namespace)), namespace.Workloads, namespace.Pods, namespace.Totals.String())
+	}
+
+	str += "\n" + titleColor.Sprint("Workloads") + "\n"
+	for _, workload := range report.Workloads {
+		name := workload.Name
+		if workload.Namespace != "" {
+			name = workload.Namespace + "/" + name
+		}
+		str += fmt.Sprintf("    %s x%d | %s\n", checkColor.Sprint(workload.Kind+" "+name), workload.Replicas, workload.Totals.String())
+		for _, container := range workload.Containers {
+			str += fmt.Sprintf("      container %s | %s\n", container.Name, container.Totals.String())
+		}
+	}
+	color.NoColor = false
+	return str
+}
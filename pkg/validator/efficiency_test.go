@@ -0,0 +1,212 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	conf "github.com/fairwindsops/polaris/pkg/config"
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+const efficiencyTestResources = `
+apiVersion: v1
+kind: Node
+metadata:
+  name: worker-1
+status:
+  allocatable:
+    cpu: "4"
+    memory: 16Gi
+---
+apiVersion: v1
+kind: Node
+metadata:
+  name: worker-2
+status:
+  allocatable:
+    cpu: "2"
+    memory: 8Gi
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+spec:
+  replicas: 3
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      initContainers:
+        - name: migrate
+          image: migrate:1.0
+          resources:
+            requests:
+              cpu: "2"
+        - name: proxy
+          image: envoyproxy/envoy:v1.31.0
+          restartPolicy: Always
+          resources:
+            requests:
+              cpu: 100m
+              memory: 64Mi
+            limits:
+              cpu: 100m
+              memory: 128Mi
+      containers:
+        - name: web
+          image: nginx:1.27
+          resources:
+            requests:
+              cpu: 250m
+              memory: 256Mi
+            limits:
+              cpu: "1"
+              memory: 512Mi
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+  namespace: monitoring
+spec:
+  selector:
+    matchLabels:
+      app: agent
+  template:
+    metadata:
+      labels:
+        app: agent
+    spec:
+      containers:
+        - name: agent
+          image: agent:1.0
+          resources:
+            limits:
+              cpu: 50m
+              memory: 64Mi
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: report
+  namespace: shop
+spec:
+  parallelism: 2
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+        - name: report
+          image: report:1.0
+          resources:
+            requests:
+              cpu: 3500m
+              memory: 1Gi
+`
+
+func TestEfficiencyReport(t *testing.T) {
+	c := conf.Configuration{
+		Checks: map[string]conf.Severity{
+			"limitRequestRatioHigh":   conf.SeverityWarning,
+			"requestsExceedNodeShare": conf.SeverityWarning,
+		},
+	}
+	provider, err := kube.CreateResourceProviderFromYaml(efficiencyTestResources)
+	assert.NoError(t, err)
+	auditData, err := RunAudit(c, provider)
+	assert.NoError(t, err)
+	// The report is opt-in, so that the results and their pretty output don't change
+	assert.Nil(t, auditData.Efficiency)
+	assert.NotContains(t, auditData.GetPrettyOutput(false), "Resources:")
+
+	c.EfficiencyReport = true
+	auditData, err = RunAudit(c, provider)
+	assert.NoError(t, err)
+
+	report := auditData.Efficiency
+	if !assert.NotNil(t, report) {
+		return
+	}
+	// web: 3 x (350m, 1100m, 320Mi, 640Mi), agent: 2 x (50m, 50m, 64Mi, 64Mi), report: 2 x (3500m, 0, 1Gi, 0)
+	assert.Equal(t, ResourceTotals{
+		CPURequests:    3*350 + 2*50 + 2*3500,
+		CPULimits:      3*1100 + 2*50,
+		MemoryRequests: (3*320 + 2*64 + 2*1024) << 20,
+		MemoryLimits:   (3*640 + 2*64) << 20,
+	}, report.Total)
+	assert.Equal(t, ResourceCapacity{CPU: 6000, Memory: 24 << 30}, report.Allocatable)
+	assert.Equal(t, ResourceCapacity{CPU: 4000, Memory: 16 << 30}, report.LargestNode)
+	assert.InDelta(t, 3400.0/8150.0, report.Overcommit.CPU, 0.001)
+	assert.InDelta(t, 8150.0/6000.0, report.Requested.CPU, 0.001)
+
+	assert.Equal(t, []LimitRequestRatioRange{
+		{Range: "no limit", CPU: 1, Memory: 1},
+		{Range: "1x", CPU: 2, Memory: 1},
+		{Range: "up to 2x", CPU: 0, Memory: 2},
+		{Range: "up to 4x", CPU: 1, Memory: 0},
+	}, report.Ratios)
+
+	assert.Equal(t, []NamespaceEfficiency{
+		{Namespace: "monitoring", Workloads: 1, Pods: 2, Totals: ResourceTotals{CPURequests: 100, CPULimits: 100, MemoryRequests: 128 << 20, MemoryLimits: 128 << 20}},
+		{Namespace: "shop", Workloads: 2, Pods: 5, Totals: ResourceTotals{CPURequests: 8050, CPULimits: 3300, MemoryRequests: (960 + 2048) << 20, MemoryLimits: 1920 << 20}},
+	}, report.Namespaces)
+
+	assert.Len(t, report.Workloads, 3)
+	assert.Equal(t, "report", report.Workloads[0].Name)
+	assert.Equal(t, 2, report.Workloads[0].Replicas)
+	web := report.Workloads[1]
+	assert.Equal(t, "web", web.Name)
+	assert.Equal(t, 3, web.Replicas)
+	assert.Equal(t, []ContainerEfficiency{
+		{Name: "proxy", Totals: ResourceTotals{CPURequests: 300, CPULimits: 300, MemoryRequests: 192 << 20, MemoryLimits: 384 << 20}},
+		{Name: "web", Totals: ResourceTotals{CPURequests: 750, CPULimits: 3000, MemoryRequests: 768 << 20, MemoryLimits: 1536 << 20}},
+	}, web.Containers)
+	assert.Equal(t, 2, report.Workloads[2].Replicas)
+
+	failing := map[string][]string{}
+	for _, result := range auditData.Results {
+		for _, message := range result.Results {
+			if !message.Success {
+				failing[result.Name] = append(failing[result.Name], message.Details...)
+			}
+		}
+	}
+	assert.Equal(t, map[string][]string{
+		"report": {"pod requests 3500m cpu, 88% of the largest node's 4"},
+	}, failing)
+
+	c.EfficiencyPolicy.MaximumLimitRequestRatio = 2
+	auditData, err = RunAudit(c, provider)
+	assert.NoError(t, err)
+	for _, result := range auditData.Results {
+		if result.Name == "web" {
+			assert.Equal(t, []string{`container "web" cpu limit is 4.0x its request, more than 2x`}, result.Results["limitRequestRatioHigh"].Details)
+		}
+	}
+
+	output := auditData.GetEfficiencyPrettyOutput(false)
+	assert.Contains(t, output, "Total: cpu requests 8150m, limits 3400m | memory requests 3.1Gi, limits 2.0Gi\n")
+	assert.Contains(t, output, "Requested: cpu 136%, memory 13% | limited: cpu 57%, memory 8%\n")
+	assert.Contains(t, output, "    Deployment shop/web x3 | cpu requests 1050m, limits 3300m | memory requests 960Mi, limits 1.9Gi\n")
+}
@@ -0,0 +1,106 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+
+	"github.com/qri-io/jsonschema"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/fairwindsops/polaris/pkg/config"
+)
+
+func init() {
+	registerCustomChecks("limitRequestRatioHigh", limitRequestRatioHigh)
+	registerCustomChecks("requestsExceedNodeShare", requestsExceedNodeShare)
+	registerCustomApplicability("limitRequestRatioHigh", anyContainer(func(container corev1.Container) bool {
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			if request, _, hasLimit := getRequestAndLimit(container, name); hasLimit && request > 0 {
+				return true
+			}
+		}
+		return false
+	}))
+	registerCustomApplicability("requestsExceedNodeShare", func(test schemaTestCase) bool {
+		return test.ResourceProvider != nil && len(test.ResourceProvider.Nodes) > 0
+	})
+}
+
+// getEfficiencyPolicy returns the efficiencyPolicy of the configuration, which isn't set when a check is called directly
+func getEfficiencyPolicy(test schemaTestCase) config.EfficiencyPolicy {
+	if test.Config == nil {
+		return config.EfficiencyPolicy{}
+	}
+	return test.Config.EfficiencyPolicy
+}
+
+// limitRequestRatioHigh fails if a container's CPU or memory limit is more than
+// efficiencyPolicy.maximumLimitRequestRatio times its request
+func limitRequestRatioHigh(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	if test.Resource.PodSpec == nil {
+		return true, nil, nil
+	}
+	maximum := getEfficiencyPolicy(test).GetMaximumLimitRequestRatio()
+	issues := []jsonschema.ValError{}
+	// Ephemeral containers can't set resources
+	containers := append(append([]corev1.Container{}, test.Resource.PodSpec.InitContainers...), test.Resource.PodSpec.Containers...)
+	for _, container := range containers {
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			request, limit, hasLimit := getRequestAndLimit(container, name)
+			if !hasLimit || request == 0 {
+				continue
+			}
+			if ratio := getRatio(limit, request); ratio > maximum {
+				limitQuantity := container.Resources.Limits[name]
+				issues = append(issues, jsonschema.ValError{
+					PropertyPath: "resources.limits." + string(name),
+					InvalidValue: limitQuantity.String(),
+					Message:      fmt.Sprintf("container %q %s limit is %.1fx its request, more than %vx", container.Name, name, ratio, maximum),
+				})
+			}
+		}
+	}
+	return len(issues) == 0, issues, nil
+}
+
+// requestsExceedNodeShare fails if a pod requests more than efficiencyPolicy.maximumNodeSharePercent of the CPU or
+// memory of the largest Node, which leaves few Nodes it can be scheduled on. It doesn't apply when Nodes aren't audited.
+func requestsExceedNodeShare(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	if test.Resource.PodSpec == nil || test.ResourceProvider == nil || len(test.ResourceProvider.Nodes) == 0 {
+		return true, nil, nil
+	}
+	share := getEfficiencyPolicy(test).GetMaximumNodeSharePercent()
+	largest := getLargestNode(test.ResourceProvider.Nodes)
+	totals := getPodTotals(test.Resource.PodSpec)
+	issues := []jsonschema.ValError{}
+	if largest.CPU > 0 && totals.CPURequests*100 > largest.CPU*int64(share) {
+		issues = append(issues, jsonschema.ValError{
+			PropertyPath: "containers",
+			InvalidValue: formatCPU(totals.CPURequests),
+			Message: fmt.Sprintf("pod requests %s cpu, %s of the largest node's %s",
+				formatCPU(totals.CPURequests), formatPercent(getRatio(totals.CPURequests, largest.CPU)), formatCPU(largest.CPU)),
+		})
+	}
+	if largest.Memory > 0 && totals.MemoryRequests*100 > largest.Memory*int64(share) {
+		issues = append(issues, jsonschema.ValError{
+			PropertyPath: "containers",
+			InvalidValue: formatMemory(totals.MemoryRequests),
+			Message: fmt.Sprintf("pod requests %s memory, %s of the largest node's %s",
+				formatMemory(totals.MemoryRequests), formatPercent(getRatio(totals.MemoryRequests, largest.Memory)), formatMemory(largest.Memory)),
+		})
+	}
+	return len(issues) == 0, issues, nil
+}
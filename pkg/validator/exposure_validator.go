@@ -0,0 +1,266 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/qri-io/jsonschema"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+const (
+	ingressKind      = "networking.k8s.io/Ingress"
+	gatewayAPIGroup  = "gateway.networking.k8s.io"
+	gatewayKind      = gatewayAPIGroup + "/Gateway"
+	httpRouteKind    = gatewayAPIGroup + "/HTTPRoute"
+	ingressClassAnno = "kubernetes.io/ingress.class"
+)
+
+func init() {
+	registerCustomChecks("ingressDuplicateHostPath", ingressDuplicateHostPath)
+	registerCustomChecks("httpRouteTLSMissing", httpRouteTLSMissing)
+	registerCustomChecks("gatewayListenerTLSMissing", gatewayListenerTLSMissing)
+}
+
+// ingressRoute is a host and path routed by an Ingress. The host is empty for rules matching every host.
+type ingressRoute struct {
+	host string
+	path string
+}
+
+func getIngressRoutes(obj map[string]interface{}) []ingressRoute {
+	routes := []ingressRoute{}
+	rules, _ := getNestedSlice(obj, "spec", "rules")
+	for _, rule := range rules {
+		ruleMap, ok := rule.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		host, _, _ := unstructured.NestedString(ruleMap, "host")
+		paths, _ := getNestedSlice(ruleMap, "http", "paths")
+		for _, path := range paths {
+			pathMap, ok := path.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			pathValue, _, _ := unstructured.NestedString(pathMap, "path")
+			if pathValue == "" {
+				pathValue = "/"
+			}
+			routes = append(routes, ingressRoute{host: host, path: pathValue})
+		}
+	}
+	return routes
+}
+
+func getIngressClass(resource kube.GenericResource) string {
+	class, _, _ := unstructured.NestedString(resource.Resource.Object, "spec", "ingressClassName")
+	if class == "" {
+		class = resource.ObjectMeta.GetAnnotations()[ingressClassAnno]
+	}
+	return class
+}
+
+// ingressDuplicateHostPath fails if another Ingress of the same class routes one of the same host and path pairs.
+// The controller picks one of them, so one application can receive the traffic of another, even across namespaces.
+func ingressDuplicateHostPath(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	if test.ResourceProvider == nil {
+		return true, nil, nil
+	}
+	namespace := test.Resource.ObjectMeta.GetNamespace()
+	name := test.Resource.ObjectMeta.GetName()
+	class := getIngressClass(test.Resource)
+	routes := getIngressRoutes(test.Resource.Resource.Object)
+	issues := []jsonschema.ValError{}
+	for _, other := range test.ResourceProvider.Resources[ingressKind] {
+		otherNamespace := other.ObjectMeta.GetNamespace()
+		if otherNamespace == namespace && other.ObjectMeta.GetName() == name || getIngressClass(other) != class {
+			continue
+		}
+		otherRoutes := getIngressRoutes(other.Resource.Object)
+		for _, route := range routes {
+			for _, otherRoute := range otherRoutes {
+				if route != otherRoute {
+					continue
+				}
+				issues = append(issues, jsonschema.ValError{
+					PropertyPath: "spec.rules",
+					InvalidValue: route.host + route.path,
+					Message:      fmt.Sprintf("host %q and path %q are also routed by Ingress %s", route.host, route.path, getNamespacedName(otherNamespace, other.ObjectMeta.GetName())),
+				})
+			}
+		}
+	}
+	return len(issues) == 0, issues, nil
+}
+
+func getNamespacedName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
+
+// redirectsToHTTPS returns true if every rule of an HTTPRoute redirects to HTTPS
+func redirectsToHTTPS(obj map[string]interface{}) bool {
+	rules, _ := getNestedSlice(obj, "spec", "rules")
+	if len(rules) == 0 {
+		return false
+	}
+	for _, rule := range rules {
+		ruleMap, ok := rule.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		filters, _ := getNestedSlice(ruleMap, "filters")
+		redirects := false
+		for _, filter := range filters {
+			filterMap, ok := filter.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			scheme, _, _ := unstructured.NestedString(filterMap, "requestRedirect", "scheme")
+			if filterMap["type"] == "RequestRedirect" && strings.EqualFold(scheme, "https") {
+				redirects = true
+			}
+		}
+		if !redirects {
+			return false
+		}
+	}
+	return true
+}
+
+// getParentGateway returns the Gateway an HTTPRoute parentRef points to, if it's among the audited resources
+func getParentGateway(resourceProvider *kube.ResourceProvider, parentRef interface{}, routeNamespace string) (map[string]interface{}, *kube.GenericResource) {
+	ref, ok := parentRef.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	group, found, _ := unstructured.NestedString(ref, "group")
+	kind, _, _ := unstructured.NestedString(ref, "kind")
+	if found && group != gatewayAPIGroup || kind != "" && kind != "Gateway" {
+		return nil, nil
+	}
+	name, _, _ := unstructured.NestedString(ref, "name")
+	namespace, _, _ := unstructured.NestedString(ref, "namespace")
+	if namespace == "" {
+		namespace = routeNamespace
+	}
+	return ref, findResource(resourceProvider.Resources[gatewayKind], namespace, name)
+}
+
+// parentRefSelectsListener returns true if a parentRef attaches its route to a listener, either by name,
+// by port or to every listener of the Gateway
+func parentRefSelectsListener(ref map[string]interface{}, listener map[string]interface{}) bool {
+	sectionName, _, _ := unstructured.NestedString(ref, "sectionName")
+	listenerName, _, _ := unstructured.NestedString(listener, "name")
+	port, _, _ := unstructured.NestedFieldNoCopy(ref, "port")
+	return (sectionName == "" || listenerName == sectionName) && (port == nil || fmt.Sprint(listener["port"]) == fmt.Sprint(port))
+}
+
+func getListeners(gateway kube.GenericResource) []map[string]interface{} {
+	listeners := []map[string]interface{}{}
+	items, _ := getNestedSlice(gateway.Resource.Object, "spec", "listeners")
+	for _, item := range items {
+		if listener, ok := item.(map[string]interface{}); ok {
+			listeners = append(listeners, listener)
+		}
+	}
+	return listeners
+}
+
+// httpRouteTLSMissing fails if the HTTPRoute is attached to a Gateway listener using plain HTTP
+func httpRouteTLSMissing(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	if test.ResourceProvider == nil || redirectsToHTTPS(test.Resource.Resource.Object) {
+		return true, nil, nil
+	}
+	issues := []jsonschema.ValError{}
+	parentRefs, _ := getNestedSlice(test.Resource.Resource.Object, "spec", "parentRefs")
+	for _, parentRef := range parentRefs {
+		ref, gateway := getParentGateway(test.ResourceProvider, parentRef, test.Resource.ObjectMeta.GetNamespace())
+		if gateway == nil {
+			continue
+		}
+		for _, listener := range getListeners(*gateway) {
+			listenerName, _, _ := unstructured.NestedString(listener, "name")
+			protocol, _, _ := unstructured.NestedString(listener, "protocol")
+			if protocol == "HTTP" && parentRefSelectsListener(ref, listener) {
+				issues = append(issues, jsonschema.ValError{
+					PropertyPath: "spec.parentRefs",
+					InvalidValue: gateway.ObjectMeta.GetName(),
+					Message:      fmt.Sprintf("listener %q of Gateway %s does not use TLS", listenerName, gateway.ObjectMeta.GetName()),
+				})
+			}
+		}
+	}
+	return len(issues) == 0, issues, nil
+}
+
+// onlyRedirectsToHTTPS returns true if HTTPRoutes are attached to the listener, and all of them redirect to HTTPS
+func onlyRedirectsToHTTPS(resourceProvider *kube.ResourceProvider, gateway kube.GenericResource, listener map[string]interface{}) bool {
+	if resourceProvider == nil {
+		return false
+	}
+	attached := false
+	for _, route := range resourceProvider.Resources[httpRouteKind] {
+		parentRefs, _ := getNestedSlice(route.Resource.Object, "spec", "parentRefs")
+		for _, parentRef := range parentRefs {
+			ref, parent := getParentGateway(resourceProvider, parentRef, route.ObjectMeta.GetNamespace())
+			if parent == nil || parent.ObjectMeta.GetName() != gateway.ObjectMeta.GetName() ||
+				parent.ObjectMeta.GetNamespace() != gateway.ObjectMeta.GetNamespace() || !parentRefSelectsListener(ref, listener) {
+				continue
+			}
+			if !redirectsToHTTPS(route.Resource.Object) {
+				return false
+			}
+			attached = true
+		}
+	}
+	return attached
+}
+
+// gatewayListenerTLSMissing fails if a Gateway listener uses plain HTTP, or HTTPS without certificates. HTTP
+// listeners whose routes only redirect to HTTPS are allowed, like in httpRouteTLSMissing.
+func gatewayListenerTLSMissing(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	issues := []jsonschema.ValError{}
+	for idx, listener := range getListeners(test.Resource) {
+		listenerName, _, _ := unstructured.NestedString(listener, "name")
+		protocol, _, _ := unstructured.NestedString(listener, "protocol")
+		path := fmt.Sprintf("spec.listeners[%d]", idx)
+		switch protocol {
+		case "HTTP":
+			if !onlyRedirectsToHTTPS(test.ResourceProvider, test.Resource, listener) {
+				issues = append(issues, jsonschema.ValError{
+					PropertyPath: path + ".protocol",
+					InvalidValue: protocol,
+					Message:      fmt.Sprintf("listener %q uses plain HTTP", listenerName),
+				})
+			}
+		case "HTTPS":
+			if certificateRefs, _ := getNestedSlice(listener, "tls", "certificateRefs"); len(certificateRefs) == 0 {
+				issues = append(issues, jsonschema.ValError{
+					PropertyPath: path + ".tls.certificateRefs",
+					Message:      fmt.Sprintf("listener %q uses HTTPS without certificates", listenerName),
+				})
+			}
+		}
+	}
+	return len(issues) == 0, issues, nil
+}
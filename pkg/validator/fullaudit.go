@@ -35,7 +35,8 @@ func RunAudit(config conf.Configuration, kubeResources *kube.ResourceProvider) (
 		displayName = kubeResources.SourceName
 	}
 
-	results, err := ApplyAllSchemaChecksToResourceProvider(&config, kubeResources)
+	audit := newAuditContext(kubeResources)
+	results, err := applyAllSchemaChecksToResourceProvider(&config, audit)
 	if err != nil {
 		return AuditData{}, err
 	}
@@ -52,9 +53,17 @@ func RunAudit(config conf.Configuration, kubeResources *kube.ResourceProvider) (
 			Namespaces:  len(kubeResources.Namespaces),
 			Controllers: kubeResources.Resources.GetNumberOfControllers(),
 		},
-		Results: results,
+		Results:         results,
+		PodSecurity:     getNamespacePodSecurity(results, kubeResources.Namespaces),
+		NetworkPolicies: getNamespaceNetworkPolicies(results, kubeResources.Namespaces, kubeResources.Resources[networkPolicyKind]),
+		RBAC:            getRBACReport(&config, audit),
+		Nodes:           getNodeSummaries(results, kubeResources.Nodes),
+	}
+	if config.EfficiencyReport {
+		auditData.Efficiency = getEfficiencyReport(kubeResources)
 	}
 	auditData.Score = auditData.GetSummary().GetScore()
+	auditData.OwnerSummaries = auditData.GetSummaryByOwner()
 	return auditData, nil
 }
 
@@ -0,0 +1,258 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/thoas/go-funk"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/fairwindsops/polaris/pkg/config"
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+// sensitiveHostPaths give access to the container runtime, the kubelet, or the node's configuration and kernel
+var sensitiveHostPaths = []string{
+	"/boot",
+	"/dev",
+	"/etc",
+	"/proc",
+	"/root",
+	"/run",
+	"/sys",
+	"/var/lib/containerd",
+	"/var/lib/docker",
+	"/var/lib/kubelet",
+	"/var/run",
+}
+
+func init() {
+	registerCustomChecks("sensitiveHostPathMounted", validatePodSecurityControl(checkSensitiveHostPaths))
+	registerCustomChecks("unsafeSysctlsSet", validatePodSecurityControl(checkSysctls))
+	registerCustomChecks("seccompUnconfined", validatePodSecurityControl(checkBaselineSeccomp))
+	registerCustomChecks("appArmorUnconfined", validatePodSecurityControl(checkAppArmor))
+	registerCustomMutations("unsafeSysctlsSet", unsafeSysctlsMutations)
+	registerCustomMutations("seccompUnconfined", seccompUnconfinedMutations)
+	registerCustomMutations("appArmorUnconfined", appArmorUnconfinedMutations)
+	registerCustomMutations("hostUsersEnabled", hostUsersMutations)
+	registerCustomApplicability("sensitiveHostPathMounted", hasVolume(func(volume corev1.Volume) bool {
+		return volume.HostPath != nil
+	}))
+	// Pods that don't set a profile or sysctls get the container runtime's defaults
+	registerCustomApplicability("unsafeSysctlsSet", func(test schemaTestCase) bool {
+		podSpec := test.Resource.PodSpec
+		return podSpec != nil && podSpec.SecurityContext != nil && len(podSpec.SecurityContext.Sysctls) > 0
+	})
+	registerCustomApplicability("seccompUnconfined", setsSecurityContext(
+		func(sc *corev1.PodSecurityContext) bool { return sc.SeccompProfile != nil },
+		func(sc *corev1.SecurityContext) bool { return sc.SeccompProfile != nil },
+	))
+	registerCustomApplicability("appArmorUnconfined", func(test schemaTestCase) bool {
+		if podTemplate, ok := test.Resource.PodTemplate.(map[string]interface{}); ok {
+			annotations, _, _ := unstructured.NestedStringMap(podTemplate, "metadata", "annotations")
+			for key := range annotations {
+				if strings.HasPrefix(key, appArmorAnnotationPrefix) {
+					return true
+				}
+			}
+		}
+		return setsSecurityContext(
+			func(sc *corev1.PodSecurityContext) bool { return sc.AppArmorProfile != nil },
+			func(sc *corev1.SecurityContext) bool { return sc.AppArmorProfile != nil },
+		)(test)
+	})
+	registerCustomApplicability("procMountUnmasked", func(test schemaTestCase) bool {
+		return test.Container != nil && test.Container.SecurityContext != nil && test.Container.SecurityContext.ProcMount != nil
+	})
+}
+
+// setsSecurityContext returns an applicabilityFunction for checks that only apply to pods where the pod's or a
+// container's securityContext sets a field
+func setsSecurityContext(podSets func(sc *corev1.PodSecurityContext) bool, containerSets func(sc *corev1.SecurityContext) bool) applicabilityFunction {
+	return func(test schemaTestCase) bool {
+		podSpec := test.Resource.PodSpec
+		if podSpec == nil {
+			return false
+		}
+		if podSpec.SecurityContext != nil && podSets(podSpec.SecurityContext) {
+			return true
+		}
+		for _, container := range getAllContainers(podSpec) {
+			if container.SecurityContext != nil && containerSets(container.SecurityContext) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// isSensitiveHostPath returns true if the path is a sensitive path, is inside one, or contains one, e.g. /var
+func isSensitiveHostPath(hostPath string) bool {
+	hostPath = path.Clean("/" + hostPath)
+	if hostPath == "/" {
+		return true
+	}
+	for _, sensitivePath := range sensitiveHostPaths {
+		if hostPath == sensitivePath || strings.HasPrefix(hostPath, sensitivePath+"/") || strings.HasPrefix(sensitivePath, hostPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func checkSensitiveHostPaths(podSpec *corev1.PodSpec, _ map[string]string) []string {
+	violations := []string{}
+	for _, volume := range podSpec.Volumes {
+		if volume.HostPath != nil && isSensitiveHostPath(volume.HostPath.Path) {
+			violations = append(violations, fmt.Sprintf("volume %q mounts sensitive host path %s", volume.Name, volume.HostPath.Path))
+		}
+	}
+	return violations
+}
+
+// getPodTemplateJSONSchemaPrefix returns the path of the pod template's metadata, next to the pod spec
+func getPodTemplateJSONSchemaPrefix(resource kube.GenericResource) string {
+	prefix := getJSONSchemaPrefix(resource)
+	if !strings.HasSuffix(prefix, "/spec") {
+		return ""
+	}
+	return strings.TrimSuffix(prefix, "/spec") + "/metadata"
+}
+
+// unsafeSysctlsMutations keeps only the safe sysctls
+func unsafeSysctlsMutations(test schemaTestCase) ([]config.Mutation, error) {
+	podSpec := test.Resource.PodSpec
+	if podSpec == nil || podSpec.SecurityContext == nil {
+		return nil, nil
+	}
+	safe := []interface{}{}
+	for _, sysctl := range podSpec.SecurityContext.Sysctls {
+		if funk.ContainsString(safeSysctls, sysctl.Name) {
+			safe = append(safe, map[string]interface{}{"name": sysctl.Name, "value": sysctl.Value})
+		}
+	}
+	sysctlsPath := getJSONSchemaPrefix(test.Resource) + "/securityContext/sysctls"
+	mutations := []config.Mutation{{Op: "remove", Path: sysctlsPath}}
+	if len(safe) > 0 {
+		mutations = append(mutations, config.Mutation{Op: "add", Path: sysctlsPath, Value: safe})
+	}
+	return mutations, nil
+}
+
+// seccompUnconfinedMutations replaces Unconfined seccomp profiles with the container runtime's default profile
+func seccompUnconfinedMutations(test schemaTestCase) ([]config.Mutation, error) {
+	podSpec := test.Resource.PodSpec
+	if podSpec == nil {
+		return nil, nil
+	}
+	mutations := []config.Mutation{}
+	if sc := podSpec.SecurityContext; sc != nil && sc.SeccompProfile != nil && sc.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+		mutations = append(mutations, config.Mutation{
+			Op:    "replace",
+			Path:  getJSONSchemaPrefix(test.Resource) + "/securityContext/seccompProfile/type",
+			Value: string(corev1.SeccompProfileTypeRuntimeDefault),
+		})
+	}
+	forEachContainer(podSpec, func(container *corev1.Container, containerClass config.ContainerClass) {
+		if sc := container.SecurityContext; sc != nil && sc.SeccompProfile != nil && sc.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+			mutations = append(mutations, config.Mutation{
+				Op:    "replace",
+				Path:  getContainerJSONSchemaPrefix(test.Resource, container, containerClass) + "/securityContext/seccompProfile/type",
+				Value: string(corev1.SeccompProfileTypeRuntimeDefault),
+			})
+		}
+	})
+	return mutations, nil
+}
+
+// appArmorUnconfinedMutations replaces unconfined AppArmor profiles, in annotations or fields, with the container
+// runtime's default profile
+func appArmorUnconfinedMutations(test schemaTestCase) ([]config.Mutation, error) {
+	podSpec := test.Resource.PodSpec
+	if podSpec == nil {
+		return nil, nil
+	}
+	mutations := []config.Mutation{}
+	if metadataPrefix := getPodTemplateJSONSchemaPrefix(test.Resource); metadataPrefix != "" {
+		annotations := map[string]string{}
+		if podTemplate, ok := test.Resource.PodTemplate.(map[string]interface{}); ok {
+			annotations = getStringMap(podTemplate, "metadata", "annotations")
+		}
+		keys := []string{}
+		for key, value := range annotations {
+			if strings.HasPrefix(key, appArmorAnnotationPrefix) && value != corev1.DeprecatedAppArmorBetaProfileRuntimeDefault &&
+				!strings.HasPrefix(value, corev1.DeprecatedAppArmorBetaProfileNamePrefix) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			mutations = append(mutations, config.Mutation{
+				Op:    "replace",
+				Path:  metadataPrefix + "/annotations/" + strings.ReplaceAll(key, "/", "~1"),
+				Value: corev1.DeprecatedAppArmorBetaProfileRuntimeDefault,
+			})
+		}
+	}
+	if sc := podSpec.SecurityContext; sc != nil && sc.AppArmorProfile != nil && sc.AppArmorProfile.Type == corev1.AppArmorProfileTypeUnconfined {
+		mutations = append(mutations, config.Mutation{
+			Op:    "replace",
+			Path:  getJSONSchemaPrefix(test.Resource) + "/securityContext/appArmorProfile/type",
+			Value: string(corev1.AppArmorProfileTypeRuntimeDefault),
+		})
+	}
+	forEachContainer(podSpec, func(container *corev1.Container, containerClass config.ContainerClass) {
+		if sc := container.SecurityContext; sc != nil && sc.AppArmorProfile != nil && sc.AppArmorProfile.Type == corev1.AppArmorProfileTypeUnconfined {
+			mutations = append(mutations, config.Mutation{
+				Op:    "replace",
+				Path:  getContainerJSONSchemaPrefix(test.Resource, container, containerClass) + "/securityContext/appArmorProfile/type",
+				Value: string(corev1.AppArmorProfileTypeRuntimeDefault),
+			})
+		}
+	})
+	return mutations, nil
+}
+
+// hostUsersMutations runs the pods in a user namespace. The check passes for pods using the host's namespaces,
+// which can't, so the mutation is only computed for failing pods.
+func hostUsersMutations(test schemaTestCase) ([]config.Mutation, error) {
+	if test.Resource.PodSpec == nil {
+		return nil, nil
+	}
+	return []config.Mutation{{
+		Op:    "add",
+		Path:  getJSONSchemaPrefix(test.Resource) + "/hostUsers",
+		Value: false,
+	}}, nil
+}
+
+// forEachContainer calls fn with every container of a pod spec and its class
+func forEachContainer(podSpec *corev1.PodSpec, fn func(container *corev1.Container, containerClass config.ContainerClass)) {
+	for idx := range podSpec.InitContainers {
+		fn(&podSpec.InitContainers[idx], config.ContainerClassInit)
+	}
+	for idx := range podSpec.Containers {
+		fn(&podSpec.Containers[idx], config.ContainerClassContainer)
+	}
+	for _, ephemeralContainer := range podSpec.EphemeralContainers {
+		container := corev1.Container(ephemeralContainer.EphemeralContainerCommon)
+		fn(&container, config.ContainerClassEphemeral)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSensitiveHostPath(t *testing.T) {
+	sensitive := []string{"/", "/var/run/docker.sock", "/run/containerd/containerd.sock", "/etc", "/etc/kubernetes/",
+		"/proc", "/sys/fs/cgroup", "/var", "/var/lib", "/var/lib/kubelet/pods", "/dev/../etc"}
+	for _, hostPath := range sensitive {
+		assert.True(t, isSensitiveHostPath(hostPath), hostPath)
+	}
+	safe := []string{"/var/log", "/var/lib/app", "/data", "/opt/cni/bin", "/etcd", "/mnt/disks/ssd0"}
+	for _, hostPath := range safe {
+		assert.False(t, isSensitiveHostPath(hostPath), hostPath)
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/qri-io/jsonschema"
+	"github.com/sirupsen/logrus"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+const (
+	hpaGroupKind = "autoscaling/HorizontalPodAutoscaler"
+	// autoscaling/v1 HorizontalPodAutoscalers keep the fields of later versions in these annotations
+	hpaMetricsAnnotation  = "autoscaling.alpha.kubernetes.io/metrics"
+	hpaBehaviorAnnotation = "autoscaling.alpha.kubernetes.io/behavior"
+	// hpaFieldManager is the field manager of the HorizontalPodAutoscaler controller
+	hpaFieldManager = "kube-controller-manager"
+	// defaultTargetCPUUtilizationPercentage is used by autoscaling/v1 when targetCPUUtilizationPercentage isn't set
+	defaultTargetCPUUtilizationPercentage = 80
+)
+
+func init() {
+	registerCustomChecks("hpaTargetRequestsMissing", hpaTargetRequestsMissing)
+	registerCustomChecks("hpaTargetReplicasSet", hpaTargetReplicasSet)
+	registerCustomChecks("hpaMetricsRequireV2", hpaMetricsRequireV2)
+	registerCustomApplicability("hpaTargetReplicasSet", func(test schemaTestCase) bool {
+		return findHorizontalPodAutoscaler(test.ResourceProvider, test.Resource) != nil
+	})
+}
+
+// getHorizontalPodAutoscaler converts a HorizontalPodAutoscaler of any version to autoscaling/v2
+func getHorizontalPodAutoscaler(generic kube.GenericResource) (*autoscalingv2.HorizontalPodAutoscaler, bool) {
+	if generic.Resource.GetAPIVersion() != autoscalingv1.SchemeGroupVersion.String() {
+		var hpa autoscalingv2.HorizontalPodAutoscaler
+		if !fromUnstructured(generic, &hpa) {
+			return nil, false
+		}
+		return &hpa, true
+	}
+	var v1 autoscalingv1.HorizontalPodAutoscaler
+	if !fromUnstructured(generic, &v1) {
+		return nil, false
+	}
+	hpa := autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: v1.ObjectMeta,
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference(v1.Spec.ScaleTargetRef),
+			MinReplicas:    v1.Spec.MinReplicas,
+			MaxReplicas:    v1.Spec.MaxReplicas,
+		},
+	}
+	if metrics, ok := v1.Annotations[hpaMetricsAnnotation]; ok {
+		if err := json.Unmarshal([]byte(metrics), &hpa.Spec.Metrics); err != nil {
+			logrus.Warnf("could not parse %s annotation of HorizontalPodAutoscaler %s: %v", hpaMetricsAnnotation, v1.Name, err)
+		}
+	}
+	if behavior, ok := v1.Annotations[hpaBehaviorAnnotation]; ok {
+		hpa.Spec.Behavior = &autoscalingv2.HorizontalPodAutoscalerBehavior{}
+		if err := json.Unmarshal([]byte(behavior), hpa.Spec.Behavior); err != nil {
+			logrus.Warnf("could not parse %s annotation of HorizontalPodAutoscaler %s: %v", hpaBehaviorAnnotation, v1.Name, err)
+		}
+	}
+	utilization := int32(defaultTargetCPUUtilizationPercentage)
+	if v1.Spec.TargetCPUUtilizationPercentage != nil {
+		utilization = *v1.Spec.TargetCPUUtilizationPercentage
+	}
+	hpa.Spec.Metrics = append([]autoscalingv2.MetricSpec{{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name:   corev1.ResourceCPU,
+			Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: &utilization},
+		},
+	}}, hpa.Spec.Metrics...)
+	return &hpa, true
+}
+
+// getScaleTargetGroupKind returns the key of the resources a scaleTargetRef can refer to in a ResourceProvider
+func getScaleTargetGroupKind(apiVersion, kind string) string {
+	gvk := schema.FromAPIVersionAndKind(apiVersion, kind)
+	if gvk.Group != "" {
+		return gvk.Group + "/" + gvk.Kind
+	}
+	return gvk.Kind
+}
+
+// findScaleTarget returns the workload a HorizontalPodAutoscaler scales, or nil if it isn't part of the audit
+func findScaleTarget(provider *kube.ResourceProvider, hpa autoscalingv2.HorizontalPodAutoscaler) *kube.GenericResource {
+	if provider == nil {
+		return nil
+	}
+	ref := hpa.Spec.ScaleTargetRef
+	resources := provider.Resources[getScaleTargetGroupKind(ref.APIVersion, ref.Kind)]
+	for idx, resource := range resources {
+		if resource.ObjectMeta.GetName() == ref.Name && resource.ObjectMeta.GetNamespace() == hpa.Namespace {
+			return &resources[idx]
+		}
+	}
+	return nil
+}
+
+// findHorizontalPodAutoscaler returns the HorizontalPodAutoscaler scaling a workload, or nil. A HorizontalPodAutoscaler
+// can only scale workloads in its own namespace.
+func findHorizontalPodAutoscaler(provider *kube.ResourceProvider, resource kube.GenericResource) *autoscalingv2.HorizontalPodAutoscaler {
+	if provider == nil {
+		return nil
+	}
+	groupKind := getScaleTargetGroupKind(resource.Resource.GetAPIVersion(), resource.Kind)
+	for _, generic := range provider.Resources[hpaGroupKind] {
+		hpa, ok := getHorizontalPodAutoscaler(generic)
+		if !ok {
+			continue
+		}
+		ref := hpa.Spec.ScaleTargetRef
+		if getScaleTargetGroupKind(ref.APIVersion, ref.Kind) == groupKind && ref.Name == resource.ObjectMeta.GetName() &&
+			hpa.Namespace == resource.ObjectMeta.GetNamespace() {
+			return hpa
+		}
+	}
+	return nil
+}
+
+// getUtilizationMetrics returns the resources, e.g. cpu, whose utilization a metric targets. Utilization is relative
+// to the requests of every container, or of a single container for ContainerResource metrics.
+func getUtilizationMetrics(metric autoscalingv2.MetricSpec) (resource corev1.ResourceName, container string, ok bool) {
+	switch {
+	case metric.Resource != nil && metric.Resource.Target.Type == autoscalingv2.UtilizationMetricType:
+		return metric.Resource.Name, "", true
+	case metric.ContainerResource != nil && metric.ContainerResource.Target.Type == autoscalingv2.UtilizationMetricType:
+		return metric.ContainerResource.Name, metric.ContainerResource.Container, true
+	}
+	return "", "", false
+}
+
+// hpaTargetRequestsMissing fails if the HorizontalPodAutoscaler scales on the utilization of a resource that the
+// containers of its target don't request, which it can't compute
+func hpaTargetRequestsMissing(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	hpa, ok := getHorizontalPodAutoscaler(test.Resource)
+	if !ok {
+		return true, nil, nil
+	}
+	target := findScaleTarget(test.ResourceProvider, *hpa)
+	if target == nil || target.PodSpec == nil {
+		return true, nil, nil
+	}
+	issues := []jsonschema.ValError{}
+	for _, metric := range hpa.Spec.Metrics {
+		resource, containerName, ok := getUtilizationMetrics(metric)
+		if !ok {
+			continue
+		}
+		// Only regular containers count, not init containers
+		for _, container := range target.PodSpec.Containers {
+			if containerName != "" && container.Name != containerName {
+				continue
+			}
+			if _, requested := container.Resources.Requests[resource]; requested {
+				continue
+			}
+			issues = append(issues, jsonschema.ValError{
+				PropertyPath: "spec.metrics",
+				InvalidValue: string(resource),
+				Message: fmt.Sprintf("%s %q container %q doesn't request %s, which is needed to compute its utilization",
+					target.Kind, target.ObjectMeta.GetName(), container.Name, resource),
+			})
+		}
+	}
+	return len(issues) == 0, issues, nil
+}
+
+// getManagedReplicas returns true if the workload sets spec.replicas in its manifest. Objects read from a cluster
+// always have replicas, so the last applied configuration and the managed fields are used instead.
+func getManagedReplicas(test schemaTestCase) bool {
+	if canVerifyReferences(test.ResourceProvider) {
+		_, found, _ := unstructured.NestedFieldNoCopy(test.Resource.Resource.Object, "spec", "replicas")
+		return found
+	}
+	if managesReplicas(test.Resource.ObjectMeta.GetManagedFields()) {
+		return true
+	}
+	lastApplied, ok := test.Resource.ObjectMeta.GetAnnotations()[corev1.LastAppliedConfigAnnotation]
+	if !ok {
+		return false
+	}
+	original := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(lastApplied), &original); err != nil {
+		logrus.Debugf("could not parse %s annotation of %s: %v", corev1.LastAppliedConfigAnnotation, test.Resource.ObjectMeta.GetName(), err)
+		return false
+	}
+	_, found, _ := unstructured.NestedFieldNoCopy(original, "spec", "replicas")
+	return found
+}
+
+// managesReplicas returns true if a field manager other than the HorizontalPodAutoscaler owns spec.replicas, e.g.
+// server-side apply or a GitOps controller. The HorizontalPodAutoscaler scales through the scale subresource, which
+// older clusters recorded as an update of the object by kube-controller-manager.
+func managesReplicas(entries []metav1.ManagedFieldsEntry) bool {
+	for _, entry := range entries {
+		if entry.Subresource != "" || entry.Manager == hpaFieldManager || entry.FieldsV1 == nil {
+			continue
+		}
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			logrus.Debugf("could not parse the managed fields of %s: %v", entry.Manager, err)
+			continue
+		}
+		if _, found, _ := unstructured.NestedFieldNoCopy(fields, "f:spec", "f:replicas"); found {
+			return true
+		}
+	}
+	return false
+}
+
+// hpaTargetReplicasSet fails if a workload scaled by a HorizontalPodAutoscaler sets spec.replicas, which resets
+// the number of replicas every time the manifest is applied
+func hpaTargetReplicasSet(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	hpa := findHorizontalPodAutoscaler(test.ResourceProvider, test.Resource)
+	if hpa == nil || !getManagedReplicas(test) {
+		return true, nil, nil
+	}
+	return false, []jsonschema.ValError{{
+		PropertyPath: "spec.replicas",
+		InvalidValue: hpa.Name,
+		Message:      fmt.Sprintf("spec.replicas is set, but the replicas are managed by HorizontalPodAutoscaler %q", hpa.Name),
+	}}, nil
+}
+
+// hpaMetricsRequireV2 fails if an autoscaling/v1 HorizontalPodAutoscaler uses metrics or behaviors that only exist
+// in autoscaling/v2, through annotations
+func hpaMetricsRequireV2(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	apiVersion, _ := getOriginalAPIVersion(test.Resource)
+	if apiVersion != autoscalingv1.SchemeGroupVersion.String() {
+		return true, nil, nil
+	}
+	hpa, ok := getHorizontalPodAutoscaler(test.Resource)
+	if !ok {
+		return true, nil, nil
+	}
+	issues := []jsonschema.ValError{}
+	// Objects read from a cluster are served in autoscaling/v2, where autoscaling/v1 has a single CPU metric
+	if len(hpa.Spec.Metrics) > 1 {
+		issues = append(issues, jsonschema.ValError{
+			PropertyPath: "metadata.annotations",
+			InvalidValue: len(hpa.Spec.Metrics),
+			Message:      fmt.Sprintf("%d metrics are set, but autoscaling/v1 only supports CPU utilization", len(hpa.Spec.Metrics)),
+		})
+	} else if len(hpa.Spec.Metrics) == 1 {
+		if resource, container, ok := getUtilizationMetrics(hpa.Spec.Metrics[0]); !ok || resource != corev1.ResourceCPU || container != "" {
+			issues = append(issues, jsonschema.ValError{
+				PropertyPath: "metadata.annotations",
+				InvalidValue: hpa.Spec.Metrics[0].Type,
+				Message:      fmt.Sprintf("a %s metric is set, but autoscaling/v1 only supports CPU utilization", hpa.Spec.Metrics[0].Type),
+			})
+		}
+	}
+	if hpa.Spec.Behavior != nil {
+		issues = append(issues, jsonschema.ValError{
+			PropertyPath: "metadata.annotations",
+			InvalidValue: hpaBehaviorAnnotation,
+			Message:      "scaling behavior is set, but autoscaling/v1 doesn't support it",
+		})
+	}
+	return len(issues) == 0, issues, nil
+}
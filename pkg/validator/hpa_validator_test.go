@@ -0,0 +1,149 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+const hpaV1TestResource = `
+apiVersion: autoscaling/v1
+kind: HorizontalPodAutoscaler
+metadata:
+  name: web
+  annotations:
+    autoscaling.alpha.kubernetes.io/metrics: '[{"type":"Pods","pods":{"metric":{"name":"requests_per_second"},"target":{"type":"AverageValue","averageValue":"100"}}}]'
+    autoscaling.alpha.kubernetes.io/behavior: '{"scaleDown":{"stabilizationWindowSeconds":600}}'
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: web
+  minReplicas: 2
+  maxReplicas: 10
+`
+
+func TestGetHorizontalPodAutoscaler(t *testing.T) {
+	resource, err := kube.NewGenericResourceFromBytes([]byte(hpaV1TestResource))
+	assert.NoError(t, err)
+	hpa, ok := getHorizontalPodAutoscaler(resource)
+	assert.True(t, ok)
+	assert.Equal(t, "Deployment", hpa.Spec.ScaleTargetRef.Kind)
+	assert.Equal(t, int32(2), *hpa.Spec.MinReplicas)
+	assert.Len(t, hpa.Spec.Metrics, 2)
+	assert.Equal(t, corev1.ResourceCPU, hpa.Spec.Metrics[0].Resource.Name)
+	assert.Equal(t, int32(defaultTargetCPUUtilizationPercentage), *hpa.Spec.Metrics[0].Resource.Target.AverageUtilization)
+	assert.Equal(t, autoscalingv2.PodsMetricSourceType, hpa.Spec.Metrics[1].Type)
+	assert.Equal(t, "requests_per_second", hpa.Spec.Metrics[1].Pods.Metric.Name)
+	assert.Equal(t, int32(600), *hpa.Spec.Behavior.ScaleDown.StabilizationWindowSeconds)
+}
+
+func TestGetScaleTargetGroupKind(t *testing.T) {
+	assert.Equal(t, "apps/Deployment", getScaleTargetGroupKind("apps/v1", "Deployment"))
+	assert.Equal(t, "ReplicationController", getScaleTargetGroupKind("v1", "ReplicationController"))
+}
+
+func TestGetManagedReplicas(t *testing.T) {
+	deployment := func(managedFields string) schemaTestCase {
+		resource, err := kube.NewGenericResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  managedFields:` + managedFields + `
+spec:
+  replicas: 3
+`))
+		assert.NoError(t, err)
+		return schemaTestCase{Resource: resource, ResourceProvider: &kube.ResourceProvider{SourceType: "Cluster"}}
+	}
+	assert.True(t, getManagedReplicas(deployment(`
+  - manager: argocd-controller
+    operation: Apply
+    fieldsType: FieldsV1
+    fieldsV1:
+      f:spec:
+        f:replicas: {}
+        f:template: {}`)))
+	assert.False(t, getManagedReplicas(deployment(`
+  - manager: argocd-controller
+    operation: Apply
+    fieldsType: FieldsV1
+    fieldsV1:
+      f:spec:
+        f:template: {}
+  - manager: kube-controller-manager
+    operation: Update
+    subresource: scale
+    fieldsType: FieldsV1
+    fieldsV1:
+      f:spec:
+        f:replicas: {}`)))
+	assert.False(t, getManagedReplicas(deployment(` []`)))
+}
+
+func TestFindHorizontalPodAutoscaler(t *testing.T) {
+	provider, err := kube.CreateResourceProviderFromYaml(`
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: web
+  namespace: apps
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: web
+  maxReplicas: 10
+---
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: rollout
+  namespace: apps
+spec:
+  scaleTargetRef:
+    apiVersion: argoproj.io/v1alpha1
+    kind: Deployment
+    name: worker
+  maxReplicas: 10
+`)
+	assert.NoError(t, err)
+	deployment := func(namespace, name string) kube.GenericResource {
+		resource, err := kube.NewGenericResourceFromBytes([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: ` + name + `
+  namespace: ` + namespace + `
+`))
+		assert.NoError(t, err)
+		return resource
+	}
+	hpa := findHorizontalPodAutoscaler(provider, deployment("apps", "web"))
+	assert.NotNil(t, hpa)
+	assert.Equal(t, "web", hpa.Name)
+	// Namespaces must match exactly, even for resources without one
+	assert.Nil(t, findHorizontalPodAutoscaler(provider, deployment("other", "web")))
+	assert.Nil(t, findHorizontalPodAutoscaler(provider, deployment(`""`, "web")))
+	// The scale target must be in the same group
+	assert.Nil(t, findHorizontalPodAutoscaler(provider, deployment("apps", "worker")))
+}
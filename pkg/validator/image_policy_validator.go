@@ -0,0 +1,274 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/qri-io/jsonschema"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/fairwindsops/polaris/pkg/config"
+)
+
+const defaultRegistry = "docker.io"
+
+func init() {
+	registerCustomChecks("imageRegistryNotAllowed", imageRegistryNotAllowed)
+	registerCustomChecks("imageDigestMissing", imageDigestMissing)
+	registerCustomChecks("imageTagNotAllowed", imageTagNotAllowed)
+	registerCustomChecks("imageTagMutable", imageTagMutable)
+	registerCustomMutations("imageDigestMissing", imageDigestMutations)
+	// Without a policy, there is nothing to check images against
+	registerCustomApplicability("imageRegistryNotAllowed", func(test schemaTestCase) bool {
+		policy := getImagePolicy(test)
+		return len(policy.AllowedRegistries) > 0 || len(policy.DeniedRegistries) > 0
+	})
+	registerCustomApplicability("imageDigestMissing", requiresDigest)
+	registerCustomApplicability("imageTagNotAllowed", func(test schemaTestCase) bool {
+		return getImagePolicy(test).TagPattern != ""
+	})
+}
+
+// imageReference is a parsed container image, e.g. registry.example.com/team/app:1.0@sha256:...
+type imageReference struct {
+	registry   string
+	repository string
+	tag        string
+	digest     string
+}
+
+func parseImageReference(image string) imageReference {
+	ref := imageReference{}
+	name, digest, found := strings.Cut(image, "@")
+	if found {
+		ref.digest = digest
+	}
+	if idx := strings.LastIndex(name, ":"); idx > strings.LastIndex(name, "/") {
+		ref.tag = name[idx+1:]
+		name = name[:idx]
+	}
+	first, rest, found := strings.Cut(name, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.registry = first
+		ref.repository = rest
+	} else {
+		ref.registry = defaultRegistry
+		ref.repository = name
+	}
+	// Official images on Docker Hub live under library/, e.g. nginx is docker.io/library/nginx
+	if ref.registry == defaultRegistry && !strings.Contains(ref.repository, "/") {
+		ref.repository = "library/" + ref.repository
+	}
+	return ref
+}
+
+// name returns the fully qualified repository, e.g. docker.io/library/nginx
+func (ref imageReference) name() string {
+	return ref.registry + "/" + ref.repository
+}
+
+// matchesRegistry returns true if the image matches one of the patterns. Patterns without a slash match
+// the registry, e.g. *.gcr.io, and the others match the repository, e.g. registry.example.com/team/*
+func (ref imageReference) matchesRegistry(patterns []string) bool {
+	for _, pattern := range patterns {
+		target := ref.registry
+		if strings.Contains(pattern, "/") {
+			target = ref.name()
+		}
+		if matches, _ := path.Match(pattern, target); matches {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matches, _ := path.Match(pattern, value); matches {
+			return true
+		}
+	}
+	return false
+}
+
+func getImagePolicy(test schemaTestCase) config.ImagePolicy {
+	if test.Config == nil {
+		return config.ImagePolicy{}
+	}
+	return test.Config.ImagePolicy
+}
+
+// validateImages runs a rule against the image of every container, and fails with the messages it returns
+func validateImages(test schemaTestCase, rule func(container corev1.Container, ref imageReference) string) (bool, []jsonschema.ValError, error) {
+	if test.Resource.PodSpec == nil {
+		return true, nil, nil
+	}
+	issues := []jsonschema.ValError{}
+	for _, container := range getAllContainers(test.Resource.PodSpec) {
+		if message := rule(container, parseImageReference(container.Image)); message != "" {
+			issues = append(issues, jsonschema.ValError{
+				PropertyPath: "image",
+				InvalidValue: container.Image,
+				Message:      message,
+			})
+		}
+	}
+	return len(issues) == 0, issues, nil
+}
+
+func imageRegistryNotAllowed(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	policy := getImagePolicy(test)
+	return validateImages(test, func(container corev1.Container, ref imageReference) string {
+		if ref.matchesRegistry(policy.DeniedRegistries) {
+			return fmt.Sprintf("container %q uses image %s from a denied registry", container.Name, container.Image)
+		}
+		if len(policy.AllowedRegistries) > 0 && !ref.matchesRegistry(policy.AllowedRegistries) {
+			return fmt.Sprintf("container %q uses image %s from a registry that is not allowed", container.Name, container.Image)
+		}
+		return ""
+	})
+}
+
+// requiresDigest returns true if the imagePolicy requires digests in the resource's namespace
+func requiresDigest(test schemaTestCase) bool {
+	policy := getImagePolicy(test)
+	return len(policy.DigestNamespaces) == 0 || matchesAny(policy.DigestNamespaces, test.Resource.ObjectMeta.GetNamespace())
+}
+
+func imageDigestMissing(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	if !requiresDigest(test) {
+		return true, nil, nil
+	}
+	return validateImages(test, func(container corev1.Container, ref imageReference) string {
+		if strings.HasPrefix(ref.digest, "sha256:") {
+			return ""
+		}
+		return fmt.Sprintf("container %q image %s is not pinned by digest", container.Name, container.Image)
+	})
+}
+
+func imageTagNotAllowed(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	policy := getImagePolicy(test)
+	if policy.TagPattern == "" {
+		return true, nil, nil
+	}
+	pattern, err := regexp.Compile(policy.TagPattern)
+	if err != nil {
+		return false, nil, err
+	}
+	return validateImages(test, func(container corev1.Container, ref imageReference) string {
+		// Images without a tag are either pinned by digest or reported by tagNotSpecified
+		if ref.tag == "" || pattern.MatchString(ref.tag) {
+			return ""
+		}
+		return fmt.Sprintf("container %q image tag %s doesn't match %s", container.Name, ref.tag, policy.TagPattern)
+	})
+}
+
+func imageTagMutable(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	mutableTags := getImagePolicy(test).GetMutableTags()
+	return validateImages(test, func(container corev1.Container, ref imageReference) string {
+		if ref.digest != "" {
+			return ""
+		}
+		for _, tag := range mutableTags {
+			if strings.EqualFold(ref.tag, tag) {
+				return fmt.Sprintf("container %q image tag %s is mutable", container.Name, ref.tag)
+			}
+		}
+		return ""
+	})
+}
+
+// digestLockFiles caches the digest lock files by path
+var digestLockFiles = map[string]map[string]string{}
+
+// loadDigestLockFile reads a YAML or JSON file mapping image references to their digest, e.g.
+// `nginx:1.25: sha256:...`
+func loadDigestLockFile(path string) (map[string]string, error) {
+	lock.Lock()
+	defer lock.Unlock()
+	if digests, ok := digestLockFiles[path]; ok {
+		return digests, nil
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	digests := map[string]string{}
+	if err := yaml.Unmarshal(contents, &digests); err != nil {
+		return nil, fmt.Errorf("parsing digest lock file %s: %v", path, err)
+	}
+	digestLockFiles[path] = digests
+	return digests, nil
+}
+
+// qualifiedName returns the fully qualified repository and tag, e.g. docker.io/library/nginx:latest for nginx
+func (ref imageReference) qualifiedName() string {
+	if ref.tag == "" {
+		return ref.name() + ":latest"
+	}
+	return ref.name() + ":" + ref.tag
+}
+
+// lookupDigest finds the digest of an image in the lock file, either as written or after qualifying both the
+// image and the lock file entries, so that nginx matches docker.io/library/nginx:latest
+func lookupDigest(digests map[string]string, image string) string {
+	if digest, ok := digests[image]; ok {
+		return digest
+	}
+	qualified := parseImageReference(image).qualifiedName()
+	for entry, digest := range digests {
+		if parseImageReference(entry).qualifiedName() == qualified {
+			return digest
+		}
+	}
+	return ""
+}
+
+// imageDigestMutations pins the images found in the digest lock file, keeping their tag for readability
+func imageDigestMutations(test schemaTestCase) ([]config.Mutation, error) {
+	lockFile := getImagePolicy(test).DigestLockFile
+	if lockFile == "" || test.Resource.PodSpec == nil || !requiresDigest(test) {
+		return nil, nil
+	}
+	digests, err := loadDigestLockFile(lockFile)
+	if err != nil {
+		return nil, err
+	}
+	mutations := []config.Mutation{}
+	forEachContainer(test.Resource.PodSpec, func(container *corev1.Container, containerClass config.ContainerClass) {
+		ref := parseImageReference(container.Image)
+		if ref.digest != "" {
+			return
+		}
+		digest := lookupDigest(digests, container.Image)
+		if digest == "" {
+			return
+		}
+		mutations = append(mutations, config.Mutation{
+			Op:    "replace",
+			Path:  getContainerJSONSchemaPrefix(test.Resource, container, containerClass) + "/image",
+			Value: container.Image + "@" + digest,
+		})
+	})
+	return mutations, nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseImageReference(t *testing.T) {
+	testCases := []struct {
+		image    string
+		expected imageReference
+	}{
+		{"nginx", imageReference{registry: "docker.io", repository: "library/nginx"}},
+		{"nginx:1.25", imageReference{registry: "docker.io", repository: "library/nginx", tag: "1.25"}},
+		{"bitnami/redis:7.2", imageReference{registry: "docker.io", repository: "bitnami/redis", tag: "7.2"}},
+		{"docker.io/busybox", imageReference{registry: "docker.io", repository: "library/busybox"}},
+		{"localhost:5000/api", imageReference{registry: "localhost:5000", repository: "api"}},
+		{"localhost/api:dev", imageReference{registry: "localhost", repository: "api", tag: "dev"}},
+		{"registry.example.com/team/api:1.0@sha256:abc", imageReference{registry: "registry.example.com", repository: "team/api", tag: "1.0", digest: "sha256:abc"}},
+		{"ghcr.io/example/api@sha256:abc", imageReference{registry: "ghcr.io", repository: "example/api", digest: "sha256:abc"}},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, parseImageReference(tc.image), tc.image)
+	}
+}
+
+func TestImageMatchesRegistry(t *testing.T) {
+	patterns := []string{"*.gcr.io", "ghcr.io/example/*", "docker.io/library/*"}
+	assert.True(t, parseImageReference("eu.gcr.io/project/api").matchesRegistry(patterns))
+	assert.True(t, parseImageReference("ghcr.io/example/api:1.0").matchesRegistry(patterns))
+	assert.True(t, parseImageReference("nginx:1.25").matchesRegistry(patterns))
+	assert.False(t, parseImageReference("gcr.io/project/api").matchesRegistry(patterns))
+	assert.False(t, parseImageReference("ghcr.io/other/api").matchesRegistry(patterns))
+	assert.False(t, parseImageReference("bitnami/redis").matchesRegistry(patterns))
+	assert.False(t, parseImageReference("nginx").matchesRegistry(nil))
+}
+
+func TestLookupDigest(t *testing.T) {
+	digests := map[string]string{
+		"nginx:1.25":                        "sha256:1",
+		"docker.io/library/busybox:latest":  "sha256:2",
+		"registry.example.com/team/api:1.0": "sha256:3",
+	}
+	assert.Equal(t, "sha256:1", lookupDigest(digests, "nginx:1.25"))
+	assert.Equal(t, "sha256:2", lookupDigest(digests, "busybox"))
+	assert.Equal(t, "sha256:2", lookupDigest(digests, "docker.io/busybox:latest"))
+	assert.Equal(t, "sha256:3", lookupDigest(digests, "registry.example.com/team/api:1.0"))
+	assert.Equal(t, "sha256:1", lookupDigest(digests, "docker.io/library/nginx:1.25"))
+	assert.Equal(t, "", lookupDigest(digests, "registry.example.com/team/api:1.1"))
+}
@@ -0,0 +1,115 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fairwindsops/polaris/pkg/config"
+)
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\n", " ", "\r", "")
+
+// markdownFinding is a failing check of a resource, or of one of its containers
+type markdownFinding struct {
+	resource string
+	message  ResultMessage
+}
+
+// getFailures returns the failing checks of a result set, dangers first and then by ID
+func (res ResultSet) getFailures() []ResultMessage {
+	failures := []ResultMessage{}
+	for _, msg := range res {
+		if !msg.Success {
+			failures = append(failures, msg)
+		}
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		if failures[i].Severity != failures[j].Severity {
+			return failures[i].Severity == config.SeverityDanger
+		}
+		return failures[i].ID < failures[j].ID
+	})
+	return failures
+}
+
+func (res Result) getMarkdownFindings() []markdownFinding {
+	name := fmt.Sprintf("%s %s", res.Kind, getNamespacedName(res.Namespace, res.Name))
+	findings := []markdownFinding{}
+	for _, msg := range res.Results.getFailures() {
+		findings = append(findings, markdownFinding{resource: name, message: msg})
+	}
+	if res.PodResult == nil {
+		return findings
+	}
+	for _, msg := range res.PodResult.Results.getFailures() {
+		findings = append(findings, markdownFinding{resource: name, message: msg})
+	}
+	for _, container := range res.PodResult.ContainerResults {
+		for _, msg := range container.Results.getFailures() {
+			findings = append(findings, markdownFinding{resource: fmt.Sprintf("%s, container %s", name, container.Name), message: msg})
+		}
+	}
+	return findings
+}
+
+func getMarkdownTable(results []*Result) string {
+	findings := []markdownFinding{}
+	for _, result := range results {
+		findings = append(findings, result.getMarkdownFindings()...)
+	}
+	if len(findings) == 0 {
+		return "No failing checks.\n"
+	}
+	str := "| Resource | Check | Severity | Message |\n"
+	str += "|----------|-------|----------|---------|\n"
+	for _, finding := range findings {
+		message := finding.message.Message
+		if len(finding.message.Details) > 0 {
+			message += ": " + strings.Join(finding.message.Details, "; ")
+		}
+		str += fmt.Sprintf("| %s | `%s` | %s | %s |\n", markdownEscaper.Replace(finding.resource), finding.message.ID,
+			finding.message.Severity, markdownEscaper.Replace(message))
+	}
+	return str
+}
+
+func getMarkdownSummary(summary CountSummary) string {
+	return fmt.Sprintf("Score: %d%% | %d danger, %d warning and %d passing check(s)\n", summary.GetScore(), summary.Dangers, summary.Warnings, summary.Successes)
+}
+
+// GetMarkdownOutput returns the failing checks as Markdown tables, e.g. for pull request comments or issues. The
+// results are grouped by owner, along with their score, if groupBy is GroupByOwner.
+func (res AuditData) GetMarkdownOutput(groupBy string) string {
+	str := fmt.Sprintf("# Polaris audit of %s %s\n\n", res.SourceType, res.SourceName)
+	str += getMarkdownSummary(res.GetSummary())
+	if groupBy != GroupByOwner {
+		results := []*Result{}
+		for idx := range res.Results {
+			results = append(results, &res.Results[idx])
+		}
+		return str + "\n## Findings\n\n" + getMarkdownTable(results)
+	}
+	resultsByOwner := res.GetResultsByOwner()
+	summaries := res.GetSummaryByOwner()
+	for _, owner := range getSortedOwners(resultsByOwner) {
+		str += fmt.Sprintf("\n## Owner: %s\n\n", markdownEscaper.Replace(owner))
+		str += getMarkdownSummary(summaries[owner]) + "\n"
+		str += getMarkdownTable(resultsByOwner[owner])
+	}
+	return str
+}
@@ -0,0 +1,81 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/qri-io/jsonschema"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fairwindsops/polaris/pkg/config"
+)
+
+func init() {
+	registerCustomChecks("namespaceRequiredLabelsMissing", namespaceRequiredLabelsMissing)
+	registerCustomChecks("namespaceDefaultInUse", namespaceDefaultInUse)
+}
+
+// namespaceRequiredLabelsMissing fails if the Namespace doesn't set every label of requiredNamespaceLabels
+func namespaceRequiredLabelsMissing(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	required := config.DefaultRequiredNamespaceLabels
+	if test.Config != nil {
+		required = test.Config.GetRequiredNamespaceLabels()
+	}
+	labels := test.Resource.ObjectMeta.GetLabels()
+	issues := []jsonschema.ValError{}
+	for _, label := range required {
+		if labels[label] != "" {
+			continue
+		}
+		issues = append(issues, jsonschema.ValError{
+			PropertyPath: "metadata.labels",
+			InvalidValue: label,
+			Message:      fmt.Sprintf("label %s is missing", label),
+		})
+	}
+	return len(issues) == 0, issues, nil
+}
+
+// namespaceDefaultInUse fails for the default Namespace when workloads run in it, instead of in a Namespace with
+// its own owner, quotas and policies
+func namespaceDefaultInUse(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	if test.ResourceProvider == nil || test.Resource.ObjectMeta.GetName() != metav1.NamespaceDefault {
+		return true, nil, nil
+	}
+	workloads := []string{}
+	for _, resources := range test.ResourceProvider.Resources {
+		for _, resource := range resources {
+			// Workloads without a namespace in manifests are deployed to the namespace of the kubectl context
+			if resource.PodSpec != nil && resource.ObjectMeta.GetNamespace() == metav1.NamespaceDefault {
+				workloads = append(workloads, fmt.Sprintf("%s %s", resource.Kind, resource.ObjectMeta.GetName()))
+			}
+		}
+	}
+	if len(workloads) == 0 {
+		return true, nil, nil
+	}
+	sort.Strings(workloads)
+	issues := make([]jsonschema.ValError, len(workloads))
+	for idx, workload := range workloads {
+		issues[idx] = jsonschema.ValError{
+			PropertyPath: "metadata.name",
+			InvalidValue: workload,
+			Message:      fmt.Sprintf("%s runs in the default namespace", workload),
+		}
+	}
+	return false, issues, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	conf "github.com/fairwindsops/polaris/pkg/config"
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+const namespaceTestResources = `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: default
+  labels:
+    owner: platform
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+        - name: web
+          image: web:1.0
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  namespace: default
+spec:
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+        - name: migrate
+          image: web:1.0
+`
+
+func TestNamespaceChecks(t *testing.T) {
+	c := conf.Configuration{
+		Checks: map[string]conf.Severity{
+			"namespaceRequiredLabelsMissing": conf.SeverityWarning,
+			"namespaceDefaultInUse":          conf.SeverityWarning,
+		},
+		RequiredNamespaceLabels: []string{"owner", "team", "tier"},
+	}
+	provider, err := kube.CreateResourceProviderFromYaml(namespaceTestResources)
+	assert.NoError(t, err)
+	results, err := ApplyAllSchemaChecksToAllResources(&c, provider, provider.Resources["Namespace"])
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, []string{
+		"label team is missing",
+		"label tier is missing",
+	}, results[0].Results["namespaceRequiredLabelsMissing"].Details)
+	assert.Equal(t, []string{
+		"Deployment web runs in the default namespace",
+		"Job migrate runs in the default namespace",
+	}, results[0].Results["namespaceDefaultInUse"].Details)
+}
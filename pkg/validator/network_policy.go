@@ -0,0 +1,183 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+const networkPolicyKind = "networking.k8s.io/NetworkPolicy"
+
+// NetworkPolicyCoverage describes how the NetworkPolicies selecting a workload's pods restrict their traffic
+type NetworkPolicyCoverage struct {
+	// Policies are the names of the NetworkPolicies that select the pods
+	Policies []string
+	// IngressDefaultDeny is true if the pods only accept the ingress traffic allowed by Policies
+	IngressDefaultDeny bool
+	// EgressDefaultDeny is true if the pods only send the egress traffic allowed by Policies
+	EgressDefaultDeny bool
+	ingressRules      int
+	egressRules       int
+}
+
+// NamespaceNetworkPolicy summarizes the NetworkPolicy coverage of the workloads in a namespace
+type NamespaceNetworkPolicy struct {
+	Namespace string
+	Policies  int
+	// IngressDefaultDeny is true if a policy selecting every pod in the namespace denies all ingress traffic
+	IngressDefaultDeny bool
+	// EgressDefaultDeny is true if a policy selecting every pod in the namespace denies all egress traffic
+	EgressDefaultDeny bool
+	Workloads         int
+	// IngressCovered is the number of workloads whose ingress traffic is restricted by a policy
+	IngressCovered int
+	// EgressCovered is the number of workloads whose egress traffic is restricted by a policy
+	EgressCovered int
+}
+
+func getNetworkPolicies(resources []kube.GenericResource) []networkingv1.NetworkPolicy {
+	policies := []networkingv1.NetworkPolicy{}
+	for _, generic := range resources {
+		policy := networkingv1.NetworkPolicy{}
+		err := runtime.DefaultUnstructuredConverter.FromUnstructured(generic.Resource.Object, &policy)
+		if err != nil {
+			logrus.Warnf("error converting unstructured to NetworkPolicy: %v", err)
+			continue
+		}
+		policies = append(policies, policy)
+	}
+	return policies
+}
+
+// getPolicyTypes returns whether the policy applies to ingress and egress traffic. Without policyTypes,
+// a policy always applies to ingress, and applies to egress if it has egress rules.
+func getPolicyTypes(policy networkingv1.NetworkPolicy) (ingress bool, egress bool) {
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return true, len(policy.Spec.Egress) > 0
+	}
+	for _, policyType := range policy.Spec.PolicyTypes {
+		if strings.EqualFold(string(policyType), string(networkingv1.PolicyTypeIngress)) {
+			ingress = true
+		} else if strings.EqualFold(string(policyType), string(networkingv1.PolicyTypeEgress)) {
+			egress = true
+		}
+	}
+	return ingress, egress
+}
+
+// selectsPods returns true if the policy applies to pods with the given labels. An empty podSelector
+// selects every pod in the namespace. Namespaces must match exactly, since a policy in one namespace
+// never covers a workload that may be deployed to another.
+func selectsPods(policy networkingv1.NetworkPolicy, namespace string, podLabels map[string]string) bool {
+	if policy.ObjectMeta.Namespace != namespace {
+		return false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+	if err != nil {
+		logrus.Warnf("invalid podSelector in NetworkPolicy %s: %v", policy.ObjectMeta.Name, err)
+		return false
+	}
+	return selector.Matches(labels.Set(podLabels))
+}
+
+func isEmptySelector(selector metav1.LabelSelector) bool {
+	return len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0
+}
+
+func getNetworkPolicyCoverage(resource kube.GenericResource, policies []networkingv1.NetworkPolicy) NetworkPolicyCoverage {
+	coverage := NetworkPolicyCoverage{Policies: []string{}}
+	podLabels := getPodLabels(resource)
+	for _, policy := range policies {
+		if !selectsPods(policy, resource.ObjectMeta.GetNamespace(), podLabels) {
+			continue
+		}
+		coverage.Policies = append(coverage.Policies, policy.ObjectMeta.Name)
+		ingress, egress := getPolicyTypes(policy)
+		if ingress {
+			coverage.IngressDefaultDeny = true
+			coverage.ingressRules += len(policy.Spec.Ingress)
+		}
+		if egress {
+			coverage.EgressDefaultDeny = true
+			coverage.egressRules += len(policy.Spec.Egress)
+		}
+	}
+	sort.Strings(coverage.Policies)
+	return coverage
+}
+
+// getNamespaceNetworkPolicies summarizes the coverage of each namespace. It's empty if no workload coverage was computed.
+func getNamespaceNetworkPolicies(results []Result, namespaces []corev1.Namespace, resources []kube.GenericResource) []NamespaceNetworkPolicy {
+	summaries := map[string]*NamespaceNetworkPolicy{}
+	getSummary := func(namespace string) *NamespaceNetworkPolicy {
+		if _, ok := summaries[namespace]; !ok {
+			summaries[namespace] = &NamespaceNetworkPolicy{Namespace: namespace}
+		}
+		return summaries[namespace]
+	}
+	hasCoverage := false
+	for _, result := range results {
+		if result.NetworkPolicy == nil {
+			continue
+		}
+		hasCoverage = true
+		summary := getSummary(result.Namespace)
+		summary.Workloads++
+		if result.NetworkPolicy.IngressDefaultDeny {
+			summary.IngressCovered++
+		}
+		if result.NetworkPolicy.EgressDefaultDeny {
+			summary.EgressCovered++
+		}
+	}
+	if !hasCoverage {
+		return nil
+	}
+	for _, namespace := range namespaces {
+		getSummary(namespace.ObjectMeta.GetName())
+	}
+	for _, policy := range getNetworkPolicies(resources) {
+		summary := getSummary(policy.ObjectMeta.Namespace)
+		summary.Policies++
+		if !isEmptySelector(policy.Spec.PodSelector) {
+			continue
+		}
+		ingress, egress := getPolicyTypes(policy)
+		if ingress && len(policy.Spec.Ingress) == 0 {
+			summary.IngressDefaultDeny = true
+		}
+		if egress && len(policy.Spec.Egress) == 0 {
+			summary.EgressDefaultDeny = true
+		}
+	}
+	nsSummaries := []NamespaceNetworkPolicy{}
+	for _, summary := range summaries {
+		nsSummaries = append(nsSummaries, *summary)
+	}
+	sort.Slice(nsSummaries, func(i, j int) bool {
+		return nsSummaries[i].Namespace < nsSummaries[j].Namespace
+	})
+	return nsSummaries
+}
@@ -0,0 +1,178 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	conf "github.com/fairwindsops/polaris/pkg/config"
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+const networkPolicyTestResources = `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: apps
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: tools
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: default-deny
+  namespace: apps
+spec:
+  podSelector: {}
+  policyTypes: [Ingress]
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: web
+  namespace: apps
+spec:
+  podSelector:
+    matchExpressions:
+      - key: app
+        operator: In
+        values: [web, api]
+  ingress:
+    - from:
+        - podSelector: {}
+  egress:
+    - to:
+        - podSelector: {}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: apps
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.25
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: worker
+  namespace: apps
+spec:
+  selector:
+    matchLabels:
+      app: worker
+  template:
+    metadata:
+      labels:
+        app: worker
+    spec:
+      containers:
+        - name: worker
+          image: nginx:1.25
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: shell
+  namespace: tools
+spec:
+  containers:
+    - name: shell
+      image: busybox:1.36
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: scratch
+spec:
+  containers:
+    - name: scratch
+      image: busybox:1.36
+`
+
+func TestNetworkPolicyCoverage(t *testing.T) {
+	c := conf.Configuration{
+		Checks: map[string]conf.Severity{
+			"missingNetworkPolicy": conf.SeverityDanger,
+		},
+	}
+	provider, err := kube.CreateResourceProviderFromYaml(networkPolicyTestResources)
+	assert.NoError(t, err)
+	auditData, err := RunAudit(c, provider)
+	assert.NoError(t, err)
+
+	coverage := map[string]*NetworkPolicyCoverage{}
+	details := map[string][]string{}
+	for _, result := range auditData.Results {
+		if result.NetworkPolicy != nil {
+			coverage[result.Name] = result.NetworkPolicy
+			details[result.Name] = result.Results["missingNetworkPolicy"].Details
+		}
+	}
+	assert.Len(t, coverage, 4)
+	assert.Equal(t, []string{"default-deny", "web"}, coverage["web"].Policies)
+	assert.True(t, coverage["web"].IngressDefaultDeny)
+	assert.True(t, coverage["web"].EgressDefaultDeny)
+	assert.Nil(t, details["web"])
+
+	assert.Equal(t, []string{"default-deny"}, coverage["worker"].Policies)
+	assert.True(t, coverage["worker"].IngressDefaultDeny)
+	assert.False(t, coverage["worker"].EgressDefaultDeny)
+	assert.Equal(t, []string{"no NetworkPolicy defines ingress rules", "no NetworkPolicy restricts egress traffic"}, details["worker"])
+
+	assert.Equal(t, []string{}, coverage["shell"].Policies)
+	assert.Equal(t, []string{"no NetworkPolicy selects the pods"}, details["shell"])
+
+	assert.Equal(t, []string{}, coverage["scratch"].Policies)
+	assert.Equal(t, []string{"no NetworkPolicy selects the pods"}, details["scratch"])
+
+	assert.Equal(t, []NamespaceNetworkPolicy{
+		{Namespace: "", Workloads: 1},
+		{Namespace: "apps", Policies: 2, IngressDefaultDeny: true, Workloads: 2, IngressCovered: 2, EgressCovered: 1},
+		{Namespace: "tools", Workloads: 1},
+	}, auditData.NetworkPolicies)
+	assert.Contains(t, auditData.GetPrettyOutput(false), "(no namespace): 0 policy(s)")
+}
+
+func TestNetworkPolicyCoverageDisabled(t *testing.T) {
+	c := conf.Configuration{
+		Checks: map[string]conf.Severity{
+			"hostNetworkSet": conf.SeverityDanger,
+		},
+	}
+	provider, err := kube.CreateResourceProviderFromYaml(networkPolicyTestResources)
+	assert.NoError(t, err)
+	auditData, err := RunAudit(c, provider)
+	assert.NoError(t, err)
+	for _, result := range auditData.Results {
+		assert.Nil(t, result.NetworkPolicy)
+	}
+	assert.Nil(t, auditData.NetworkPolicies)
+}
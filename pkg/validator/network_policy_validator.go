@@ -0,0 +1,47 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"github.com/qri-io/jsonschema"
+)
+
+func init() {
+	registerCustomChecks("missingNetworkPolicy", missingNetworkPolicy)
+}
+
+// missingNetworkPolicy requires the NetworkPolicies selecting a workload's pods to restrict
+// both ingress and egress traffic, and to define rules for each
+func missingNetworkPolicy(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	if test.ResourceProvider == nil || test.Resource.PodSpec == nil {
+		return true, nil, nil
+	}
+	coverage := getNetworkPolicyCoverage(test.Resource, getNetworkPolicies(test.ResourceProvider.Resources[networkPolicyKind]))
+	if len(coverage.Policies) == 0 {
+		return false, []jsonschema.ValError{{Message: "no NetworkPolicy selects the pods"}}, nil
+	}
+	issues := []jsonschema.ValError{}
+	if !coverage.IngressDefaultDeny {
+		issues = append(issues, jsonschema.ValError{Message: "no NetworkPolicy restricts ingress traffic"})
+	} else if coverage.ingressRules == 0 {
+		issues = append(issues, jsonschema.ValError{Message: "no NetworkPolicy defines ingress rules"})
+	}
+	if !coverage.EgressDefaultDeny {
+		issues = append(issues, jsonschema.ValError{Message: "no NetworkPolicy restricts egress traffic"})
+	} else if coverage.egressRules == 0 {
+		issues = append(issues, jsonschema.ValError{Message: "no NetworkPolicy defines egress rules"})
+	}
+	return len(issues) == 0, issues, nil
+}
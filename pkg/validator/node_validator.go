@@ -0,0 +1,292 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qri-io/jsonschema"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/fairwindsops/polaris/pkg/config"
+)
+
+// deprecatedNodeLabels maps deprecated Node labels that Kubernetes no longer sets to their replacements. Labels that
+// are deprecated but still set, like failure-domain.beta.kubernetes.io/zone or beta.kubernetes.io/arch, are ignored,
+// since removing them would break the workloads that select them.
+var deprecatedNodeLabels = map[string]string{
+	"node-role.kubernetes.io/master": "node-role.kubernetes.io/control-plane",
+}
+
+// nodeConditionTaintPrefixes are the prefixes of taints added by Kubernetes, e.g. when the Node is cordoned
+var nodeConditionTaintPrefixes = []string{"node.kubernetes.io/", "node.cloudprovider.kubernetes.io/"}
+
+func init() {
+	registerCustomChecks("nodeKubeletVersionSkew", nodeKubeletVersionSkew)
+	registerCustomChecks("nodeUnschedulableTooLong", nodeUnschedulableTooLong)
+	registerCustomChecks("nodeDedicatedPoolTaintMissing", nodeDedicatedPoolTaintMissing)
+	registerCustomChecks("nodeContainerRuntimeOutdated", nodeContainerRuntimeOutdated)
+	registerCustomChecks("nodeDeprecatedLabels", nodeDeprecatedLabels)
+	// A Node cordoned at an unknown time may have just been cordoned, e.g. to be drained
+	registerCustomApplicability("nodeUnschedulableTooLong", func(test schemaTestCase) bool {
+		node, ok := getNode(test)
+		return !ok || !node.Spec.Unschedulable || !getUnschedulableSince(*node).IsZero()
+	})
+}
+
+// NodeSummary describes a Node of the audited cluster
+type NodeSummary struct {
+	Name                    string
+	KubeletVersion          string
+	ContainerRuntimeVersion string
+	Unschedulable           bool
+	// Failures is the number of Node checks the Node fails
+	Failures int
+}
+
+// getNode returns the Node being checked. Nodes are loaded with their status in ResourceProvider.Nodes.
+func getNode(test schemaTestCase) (*corev1.Node, bool) {
+	name := test.Resource.ObjectMeta.GetName()
+	if test.ResourceProvider != nil {
+		for idx := range test.ResourceProvider.Nodes {
+			if test.ResourceProvider.Nodes[idx].Name == name {
+				return &test.ResourceProvider.Nodes[idx], true
+			}
+		}
+	}
+	var node corev1.Node
+	if !fromUnstructured(test.Resource, &node) {
+		return nil, false
+	}
+	return &node, true
+}
+
+// getNodePolicy returns the nodePolicy of the configuration, which isn't set when a check is called directly
+func getNodePolicy(test schemaTestCase) config.NodePolicy {
+	if test.Config == nil {
+		return config.NodePolicy{}
+	}
+	return test.Config.NodePolicy
+}
+
+// getMaximumKubeletSkew returns how many minor versions kubelets can be older than the control plane
+func getMaximumKubeletSkew(controlPlane config.KubernetesVersion) int {
+	if controlPlane.Compare(config.KubernetesVersion{Major: 1, Minor: 28}) >= 0 {
+		return 3
+	}
+	return 2
+}
+
+// nodeKubeletVersionSkew fails if the kubelet is newer than the control plane, or older than the supported skew
+func nodeKubeletVersionSkew(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	node, ok := getNode(test)
+	if !ok {
+		return true, nil, nil
+	}
+	var controlPlane config.KubernetesVersion
+	if test.ResourceProvider != nil {
+		controlPlane, _ = config.ParseKubernetesVersion(test.ResourceProvider.ServerVersion)
+	}
+	if controlPlane.IsZero() {
+		controlPlane = test.KubernetesVersion
+	}
+	kubelet, err := config.ParseKubernetesVersion(node.Status.NodeInfo.KubeletVersion)
+	if err != nil || kubelet.IsZero() || controlPlane.IsZero() {
+		return true, nil, nil
+	}
+	var message string
+	if kubelet.Compare(controlPlane) > 0 {
+		message = fmt.Sprintf("kubelet %s is newer than the control plane %s", node.Status.NodeInfo.KubeletVersion, controlPlane)
+	} else if maxSkew := getMaximumKubeletSkew(controlPlane); kubelet.Major != controlPlane.Major || controlPlane.Minor-kubelet.Minor > maxSkew {
+		message = fmt.Sprintf("kubelet %s is more than %d minor versions older than the control plane %s", node.Status.NodeInfo.KubeletVersion, maxSkew, controlPlane)
+	} else {
+		return true, nil, nil
+	}
+	return false, []jsonschema.ValError{{
+		PropertyPath: "status.nodeInfo.kubeletVersion",
+		InvalidValue: node.Status.NodeInfo.KubeletVersion,
+		Message:      message,
+	}}, nil
+}
+
+// getUnschedulableSince returns when the Node was cordoned, from its unschedulable taint or the managed fields
+// of spec.unschedulable. The time is zero if it isn't recorded.
+func getUnschedulableSince(node corev1.Node) time.Time {
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == corev1.TaintNodeUnschedulable && taint.TimeAdded != nil {
+			return taint.TimeAdded.Time
+		}
+	}
+	since := time.Time{}
+	for _, entry := range node.ManagedFields {
+		if entry.Time == nil || entry.FieldsV1 == nil || !bytes.Contains(entry.FieldsV1.Raw, []byte(`"f:unschedulable"`)) {
+			continue
+		}
+		if entry.Time.Time.After(since) {
+			since = entry.Time.Time
+		}
+	}
+	return since
+}
+
+// nodeUnschedulableTooLong fails if the Node has been cordoned for longer than nodePolicy.unschedulableMaximum
+func nodeUnschedulableTooLong(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	node, ok := getNode(test)
+	if !ok || !node.Spec.Unschedulable {
+		return true, nil, nil
+	}
+	now := time.Now()
+	if test.ResourceProvider != nil && !test.ResourceProvider.CreationTime.IsZero() {
+		now = test.ResourceProvider.CreationTime
+	}
+	maximum := getNodePolicy(test).GetUnschedulableMaximum()
+	since := getUnschedulableSince(*node)
+	duration := now.Sub(since)
+	if since.IsZero() || duration <= maximum {
+		return true, nil, nil
+	}
+	return false, []jsonschema.ValError{{
+		PropertyPath: "spec.unschedulable",
+		InvalidValue: true,
+		Message:      fmt.Sprintf("node has been unschedulable for %s, longer than %s", duration.Truncate(time.Minute), maximum),
+	}}, nil
+}
+
+// isNodeConditionTaint returns true for taints managed by Kubernetes rather than by the cluster operators
+func isNodeConditionTaint(taint corev1.Taint) bool {
+	for _, prefix := range nodeConditionTaintPrefixes {
+		if strings.HasPrefix(taint.Key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeDedicatedPoolTaintMissing fails if the Node has one of nodePolicy.dedicatedLabels, but no taint keeping
+// other pods away
+func nodeDedicatedPoolTaintMissing(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	node, ok := getNode(test)
+	if !ok {
+		return true, nil, nil
+	}
+	for _, taint := range node.Spec.Taints {
+		if !isNodeConditionTaint(taint) && (taint.Effect == corev1.TaintEffectNoSchedule || taint.Effect == corev1.TaintEffectNoExecute) {
+			return true, nil, nil
+		}
+	}
+	issues := []jsonschema.ValError{}
+	for _, dedicatedLabel := range getNodePolicy(test).GetDedicatedLabels() {
+		key, value, hasValue := strings.Cut(dedicatedLabel, "=")
+		actual, found := node.Labels[key]
+		if !found || (hasValue && actual != value) {
+			continue
+		}
+		issues = append(issues, jsonschema.ValError{
+			PropertyPath: "spec.taints",
+			InvalidValue: dedicatedLabel,
+			Message:      fmt.Sprintf("node has label %s=%s of a dedicated pool, but no NoSchedule or NoExecute taint", key, actual),
+		})
+	}
+	return len(issues) == 0, issues, nil
+}
+
+// nodeContainerRuntimeOutdated fails if the container runtime is older than its minimum version in nodePolicy
+func nodeContainerRuntimeOutdated(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	node, ok := getNode(test)
+	if !ok {
+		return true, nil, nil
+	}
+	// e.g. containerd://1.7.2
+	runtimeVersion := node.Status.NodeInfo.ContainerRuntimeVersion
+	runtime, versionString, found := strings.Cut(runtimeVersion, "://")
+	if !found {
+		return true, nil, nil
+	}
+	minimumString, ok := getNodePolicy(test).GetMinimumRuntimeVersion(runtime)
+	if !ok {
+		return true, nil, nil
+	}
+	actual, err := version.ParseGeneric(versionString)
+	if err != nil {
+		return true, nil, nil
+	}
+	minimum, err := version.ParseGeneric(minimumString)
+	if err != nil || !actual.LessThan(minimum) {
+		return true, nil, nil
+	}
+	return false, []jsonschema.ValError{{
+		PropertyPath: "status.nodeInfo.containerRuntimeVersion",
+		InvalidValue: runtimeVersion,
+		Message:      fmt.Sprintf("container runtime %s is older than %s %s", runtimeVersion, runtime, minimumString),
+	}}, nil
+}
+
+// nodeDeprecatedLabels fails if the Node has labels that were replaced, which workloads may still select
+func nodeDeprecatedLabels(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	node, ok := getNode(test)
+	if !ok {
+		return true, nil, nil
+	}
+	issues := []jsonschema.ValError{}
+	for key := range node.Labels {
+		replacement, deprecated := deprecatedNodeLabels[key]
+		if !deprecated {
+			continue
+		}
+		issues = append(issues, jsonschema.ValError{
+			PropertyPath: "metadata.labels",
+			InvalidValue: key,
+			Message:      fmt.Sprintf("label %s is deprecated, use %s", key, replacement),
+		})
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		return issues[i].Message < issues[j].Message
+	})
+	return len(issues) == 0, issues, nil
+}
+
+// getNodeSummaries describes each Node, with the number of Node checks it fails
+func getNodeSummaries(results []Result, nodes []corev1.Node) []NodeSummary {
+	failures := map[string]int{}
+	for _, result := range results {
+		if result.Kind != string(config.TargetNode) {
+			continue
+		}
+		for _, message := range result.Results {
+			if !message.Success {
+				failures[result.Name]++
+			}
+		}
+	}
+	summaries := make([]NodeSummary, 0, len(nodes))
+	for _, node := range nodes {
+		summaries = append(summaries, NodeSummary{
+			Name:                    node.Name,
+			KubeletVersion:          node.Status.NodeInfo.KubeletVersion,
+			ContainerRuntimeVersion: node.Status.NodeInfo.ContainerRuntimeVersion,
+			Unschedulable:           node.Spec.Unschedulable,
+			Failures:                failures[node.Name],
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+	return summaries
+}
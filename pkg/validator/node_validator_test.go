@@ -0,0 +1,113 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	conf "github.com/fairwindsops/polaris/pkg/config"
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+const nodeTestResources = `
+apiVersion: v1
+kind: Node
+metadata:
+  name: gpu-1
+  labels:
+    pool: gpu
+    failure-domain.beta.kubernetes.io/zone: us-east-1a
+    node-role.kubernetes.io/master: ""
+spec:
+  unschedulable: true
+  taints:
+    - key: node.kubernetes.io/unschedulable
+      effect: NoSchedule
+      timeAdded: "2024-01-01T00:00:00Z"
+status:
+  nodeInfo:
+    kubeletVersion: v1.29.4
+    containerRuntimeVersion: containerd://1.7.13
+---
+apiVersion: v1
+kind: Node
+metadata:
+  name: worker-1
+status:
+  nodeInfo:
+    kubeletVersion: v1.30.2
+    containerRuntimeVersion: docker://20.10.7
+`
+
+func TestNodeChecks(t *testing.T) {
+	c := conf.Configuration{
+		Checks: map[string]conf.Severity{
+			"nodeKubeletVersionSkew":        conf.SeverityWarning,
+			"nodeUnschedulableTooLong":      conf.SeverityWarning,
+			"nodeDedicatedPoolTaintMissing": conf.SeverityWarning,
+			"nodeContainerRuntimeOutdated":  conf.SeverityWarning,
+			"nodeDeprecatedLabels":          conf.SeverityWarning,
+		},
+		KubernetesVersion: "1.30",
+		NodePolicy: conf.NodePolicy{
+			DedicatedLabels: []string{"pool=gpu"},
+		},
+	}
+	provider, err := kube.CreateResourceProviderFromYaml(nodeTestResources)
+	assert.NoError(t, err)
+	assert.Len(t, provider.Nodes, 2)
+	auditData, err := RunAudit(c, provider)
+	assert.NoError(t, err)
+	assert.Equal(t, []NodeSummary{
+		{Name: "gpu-1", KubeletVersion: "v1.29.4", ContainerRuntimeVersion: "containerd://1.7.13", Unschedulable: true, Failures: 3},
+		{Name: "worker-1", KubeletVersion: "v1.30.2", ContainerRuntimeVersion: "docker://20.10.7", Failures: 1},
+	}, auditData.Nodes)
+	results := map[string]ResultSet{}
+	for _, result := range auditData.Results {
+		results[result.Name] = result.Results
+	}
+	assert.True(t, results["gpu-1"]["nodeKubeletVersionSkew"].Success)
+	assert.False(t, results["gpu-1"]["nodeUnschedulableTooLong"].Success)
+	assert.Equal(t, []string{
+		"node has label pool=gpu of a dedicated pool, but no NoSchedule or NoExecute taint",
+	}, results["gpu-1"]["nodeDedicatedPoolTaintMissing"].Details)
+	assert.Equal(t, []string{
+		"label node-role.kubernetes.io/master is deprecated, use node-role.kubernetes.io/control-plane",
+	}, results["gpu-1"]["nodeDeprecatedLabels"].Details)
+	assert.Equal(t, []string{
+		"container runtime docker://20.10.7 is older than docker 24.0.0",
+	}, results["worker-1"]["nodeContainerRuntimeOutdated"].Details)
+	assert.Contains(t, auditData.GetPrettyOutput(false), "Nodes\n    gpu-1: kubelet v1.29.4, containerd://1.7.13, unschedulable | 3 failing check(s)\n")
+}
+
+func TestGetUnschedulableSince(t *testing.T) {
+	cordoned := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated := metav1.NewTime(cordoned.Add(time.Hour))
+	node := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{ManagedFields: []metav1.ManagedFieldsEntry{
+			{Manager: "kubelet", Time: &updated, FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:status":{}}`)}},
+			{Manager: "kubectl-cordon", Time: &metav1.Time{Time: cordoned}, FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:unschedulable":{}}}`)}},
+		}},
+	}
+	assert.Equal(t, cordoned, getUnschedulableSince(node))
+	node.Spec.Taints = []corev1.Taint{{Key: corev1.TaintNodeUnschedulable, TimeAdded: &updated}}
+	assert.Equal(t, updated.Time, getUnschedulableSince(node))
+	assert.True(t, getUnschedulableSince(corev1.Node{}).IsZero())
+}
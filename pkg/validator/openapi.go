@@ -0,0 +1,407 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qri-io/jsonschema"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/fairwindsops/polaris/pkg/config"
+)
+
+const (
+	openAPIRefPrefix     = "#/components/schemas/"
+	objectMetaSchemaName = "io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"
+)
+
+// openAPIFiles are the OpenAPI v3 documents of each Kubernetes minor version, e.g. openapi/v1.27.json.gz,
+// generated with scripts/generate-openapi-schemas
+//
+//go:embed openapi
+var openAPIFiles embed.FS
+
+// openAPIAnyValue are the schemas whose values are decoded by custom code, and accept more than their declared type.
+// Quantities are declared as strings, but numbers like `cpu: 1` are accepted too.
+var openAPIAnyValue = map[string]bool{
+	"io.k8s.apimachinery.pkg.api.resource.Quantity": true,
+}
+
+// openAPINode is the subset of an OpenAPI schema needed to validate a manifest:
+// field names, value types and required fields
+type openAPINode struct {
+	// Type is one of object, array, string, integer, number or boolean. Any value is accepted when empty.
+	Type                  string
+	Properties            map[string]*openAPINode
+	Required              []string
+	Items                 *openAPINode
+	AdditionalProperties  *openAPINode
+	PreserveUnknownFields bool
+	IntOrString           bool
+}
+
+// openAPIDocument is the OpenAPI document of a Kubernetes version. Schemas are converted on first use.
+type openAPIDocument struct {
+	schemas map[string]map[string]interface{}
+	kinds   map[schema.GroupVersionKind]string
+	nodes   map[string]*openAPINode
+}
+
+var (
+	// openAPIVersions are the Kubernetes versions with an OpenAPI document, from the oldest
+	openAPIVersions  = []config.KubernetesVersion{}
+	openAPIDocuments = map[config.KubernetesVersion]*openAPIDocument{}
+	openAPILock      = &sync.Mutex{}
+)
+
+func init() {
+	files, err := openAPIFiles.ReadDir("openapi")
+	if err != nil {
+		panic(err)
+	}
+	for _, file := range files {
+		version, err := config.ParseKubernetesVersion(strings.TrimSuffix(file.Name(), ".json.gz"))
+		if err != nil {
+			panic(err)
+		}
+		openAPIVersions = append(openAPIVersions, version)
+	}
+	sort.Slice(openAPIVersions, func(i, j int) bool {
+		return openAPIVersions[i].Compare(openAPIVersions[j]) < 0
+	})
+}
+
+// getOpenAPIVersions lists the Kubernetes versions with an OpenAPI document, e.g. `1.27, 1.30`
+func getOpenAPIVersions() string {
+	versions := make([]string, 0, len(openAPIVersions))
+	for _, version := range openAPIVersions {
+		versions = append(versions, version.String())
+	}
+	return strings.Join(versions, ", ")
+}
+
+// getOpenAPIDocument loads the OpenAPI document of a version. Versions without a document are an error rather
+// than validated against the schemas of another release. Callers must hold openAPILock.
+func getOpenAPIDocument(version config.KubernetesVersion) (*openAPIDocument, error) {
+	if doc, ok := openAPIDocuments[version]; ok {
+		return doc, nil
+	}
+	if version.IsZero() {
+		return nil, fmt.Errorf("the target Kubernetes version is unknown, set it with --kubernetes-version or kubernetesVersion to one of %s", getOpenAPIVersions())
+	}
+	contents, err := openAPIFiles.ReadFile(path.Join("openapi", "v"+version.String()+".json.gz"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no OpenAPI document is embedded for Kubernetes %s, schemas are available for %s", version, getOpenAPIVersions())
+	} else if err != nil {
+		return nil, err
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(contents))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	parsed := struct {
+		Components struct {
+			Schemas map[string]map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}{}
+	if err := json.NewDecoder(reader).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("parsing the OpenAPI document of Kubernetes %s: %w", version, err)
+	}
+	doc := &openAPIDocument{
+		schemas: parsed.Components.Schemas,
+		kinds:   map[schema.GroupVersionKind]string{},
+		nodes:   map[string]*openAPINode{},
+	}
+	for name, s := range doc.schemas {
+		gvks, _ := s["x-kubernetes-group-version-kind"].([]interface{})
+		for _, gvk := range gvks {
+			if gvkMap, ok := gvk.(map[string]interface{}); ok {
+				group, _ := gvkMap["group"].(string)
+				version, _ := gvkMap["version"].(string)
+				kind, _ := gvkMap["kind"].(string)
+				doc.kinds[schema.GroupVersionKind{Group: group, Version: version, Kind: kind}] = name
+			}
+		}
+	}
+	openAPIDocuments[version] = doc
+	return doc, nil
+}
+
+// getBuiltinSchema returns the schema of a kind served by a version of the Kubernetes API
+func getBuiltinSchema(gvk schema.GroupVersionKind, version config.KubernetesVersion) (*openAPINode, error) {
+	openAPILock.Lock()
+	defer openAPILock.Unlock()
+	doc, err := getOpenAPIDocument(version)
+	if err != nil {
+		return nil, err
+	}
+	name, ok := doc.kinds[gvk]
+	if !ok {
+		return nil, nil
+	}
+	return doc.resolve(openAPIRefPrefix + name), nil
+}
+
+// resolve converts the schema a $ref points to
+func (doc *openAPIDocument) resolve(ref string) *openAPINode {
+	name := strings.TrimPrefix(ref, openAPIRefPrefix)
+	if node, ok := doc.nodes[name]; ok {
+		return node
+	}
+	node := &openAPINode{}
+	// Cache before converting, so self-referencing schemas terminate
+	doc.nodes[name] = node
+	if s, ok := doc.schemas[name]; ok && !openAPIAnyValue[name] {
+		fillFromOpenAPI(node, s, doc.resolve)
+	}
+	return node
+}
+
+// schemaFromOpenAPI converts an OpenAPI schema, e.g. the openAPIV3Schema of a CustomResourceDefinition.
+// References are converted with resolve, or accept any value when resolve is nil.
+func schemaFromOpenAPI(s map[string]interface{}, resolve func(ref string) *openAPINode) *openAPINode {
+	if ref, ok := s["$ref"].(string); ok {
+		if resolve == nil {
+			return &openAPINode{}
+		}
+		return resolve(ref)
+	}
+	// OpenAPI v3 documents wrap references in allOf to add a default or a description
+	if allOf, ok := s["allOf"].([]interface{}); ok && len(allOf) == 1 {
+		if inner, ok := allOf[0].(map[string]interface{}); ok {
+			return schemaFromOpenAPI(inner, resolve)
+		}
+	}
+	node := &openAPINode{}
+	fillFromOpenAPI(node, s, resolve)
+	return node
+}
+
+func fillFromOpenAPI(node *openAPINode, s map[string]interface{}, resolve func(ref string) *openAPINode) {
+	node.Type, _ = s["type"].(string)
+	node.PreserveUnknownFields, _ = s["x-kubernetes-preserve-unknown-fields"].(bool)
+	node.IntOrString, _ = s["x-kubernetes-int-or-string"].(bool)
+	if format, _ := s["format"].(string); format == "int-or-string" {
+		node.IntOrString = true
+	}
+	if props, ok := s["properties"].(map[string]interface{}); ok {
+		node.Properties = map[string]*openAPINode{}
+		for name, prop := range props {
+			if propSchema, ok := prop.(map[string]interface{}); ok {
+				node.Properties[name] = schemaFromOpenAPI(propSchema, resolve)
+			}
+		}
+	}
+	switch required := s["required"].(type) {
+	case []interface{}:
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				node.Required = append(node.Required, name)
+			}
+		}
+	case []string:
+		node.Required = append(node.Required, required...)
+	}
+	if items, ok := s["items"].(map[string]interface{}); ok {
+		node.Items = schemaFromOpenAPI(items, resolve)
+	}
+	switch additional := s["additionalProperties"].(type) {
+	case map[string]interface{}:
+		node.AdditionalProperties = schemaFromOpenAPI(additional, resolve)
+	case bool:
+		if additional {
+			node.AdditionalProperties = &openAPINode{}
+		}
+	}
+	if embedded, _ := s["x-kubernetes-embedded-resource"].(bool); embedded {
+		addImplicitResourceFields(node, resolve)
+	}
+}
+
+// addImplicitResourceFields adds the apiVersion, kind and metadata fields that
+// CRD schemas are not required to declare
+func addImplicitResourceFields(node *openAPINode, resolve func(ref string) *openAPINode) {
+	if node.Properties == nil {
+		// Without declared properties any field is accepted
+		node.Properties = map[string]*openAPINode{}
+		node.PreserveUnknownFields = true
+	}
+	if _, ok := node.Properties["apiVersion"]; !ok {
+		node.Properties["apiVersion"] = &openAPINode{Type: "string"}
+	}
+	if _, ok := node.Properties["kind"]; !ok {
+		node.Properties["kind"] = &openAPINode{Type: "string"}
+	}
+	if resolve != nil {
+		node.Properties["metadata"] = resolve(openAPIRefPrefix + objectMetaSchemaName)
+	} else {
+		node.Properties["metadata"] = getObjectMetaSchema()
+	}
+}
+
+// getObjectMetaSchema returns the metadata schema of the newest Kubernetes version
+func getObjectMetaSchema() *openAPINode {
+	openAPILock.Lock()
+	defer openAPILock.Unlock()
+	doc, err := getOpenAPIDocument(openAPIVersions[len(openAPIVersions)-1])
+	if err != nil {
+		// The embedded documents are covered by tests
+		panic(err)
+	}
+	return doc.resolve(openAPIRefPrefix + objectMetaSchemaName)
+}
+
+// validate walks a value against the schema and returns an error for each unknown
+// field, mismatched type or missing required field
+func (node *openAPINode) validate(path string, value interface{}) []jsonschema.ValError {
+	// null is treated as unset by the API server
+	if value == nil || node == nil {
+		return nil
+	}
+	if node.IntOrString {
+		if _, ok := value.(string); ok || isInteger(value) {
+			return nil
+		}
+		return []jsonschema.ValError{typeError(path, "integer or string", value)}
+	}
+	switch node.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return []jsonschema.ValError{typeError(path, node.Type, value)}
+		}
+		return node.validateObject(path, obj)
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return []jsonschema.ValError{typeError(path, node.Type, value)}
+		}
+		errs := []jsonschema.ValError{}
+		for idx, item := range arr {
+			errs = append(errs, node.Items.validate(fmt.Sprintf("%s[%d]", path, idx), item)...)
+		}
+		return errs
+	case "string":
+		switch value.(type) {
+		case string, time.Time:
+			return nil
+		}
+	case "integer":
+		if isInteger(value) {
+			return nil
+		}
+	case "number":
+		if isInteger(value) {
+			return nil
+		}
+		if _, ok := value.(float64); ok {
+			return nil
+		}
+	case "boolean":
+		if _, ok := value.(bool); ok {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return []jsonschema.ValError{typeError(path, node.Type, value)}
+}
+
+func (node *openAPINode) validateObject(path string, obj map[string]interface{}) []jsonschema.ValError {
+	errs := []jsonschema.ValError{}
+	for _, name := range node.Required {
+		if _, ok := obj[name]; !ok {
+			errs = append(errs, jsonschema.ValError{
+				PropertyPath: joinFieldPath(path, name),
+				Message:      fmt.Sprintf("missing required field %q", joinFieldPath(path, name)),
+			})
+		}
+	}
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fieldPath := joinFieldPath(path, key)
+		if prop, ok := node.Properties[key]; ok {
+			errs = append(errs, prop.validate(fieldPath, obj[key])...)
+		} else if node.AdditionalProperties != nil {
+			errs = append(errs, node.AdditionalProperties.validate(fieldPath, obj[key])...)
+		} else if !node.PreserveUnknownFields && node.Properties != nil {
+			errs = append(errs, jsonschema.ValError{
+				PropertyPath: fieldPath,
+				Message:      fmt.Sprintf("unknown field %q", fieldPath),
+			})
+		}
+	}
+	return errs
+}
+
+func joinFieldPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func typeError(path, expected string, value interface{}) jsonschema.ValError {
+	return jsonschema.ValError{
+		PropertyPath: path,
+		InvalidValue: value,
+		Message:      fmt.Sprintf("%s: expected %s, got %s", path, expected, jsonTypeName(value)),
+	}
+}
+
+func isInteger(value interface{}) bool {
+	switch v := value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return true
+	case float64:
+		return v == math.Trunc(v)
+	}
+	return false
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	if isInteger(value) {
+		return "integer"
+	}
+	return "number"
+}
@@ -0,0 +1,263 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	conf "github.com/fairwindsops/polaris/pkg/config"
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+const schemaTestCRD = `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: crontabs.stable.example.com
+spec:
+  group: stable.example.com
+  names:
+    kind: CronTab
+  versions:
+    - name: v1
+      schema:
+        openAPIV3Schema:
+          type: object
+          properties:
+            spec:
+              type: object
+              required: ["cronSpec"]
+              properties:
+                cronSpec:
+                  type: string
+                replicas:
+                  type: integer
+                  minimum: 1
+`
+
+// schemaTestVersion is the version of the embedded OpenAPI document
+var schemaTestVersion = conf.KubernetesVersion{Major: 1, Minor: 27}
+
+func getSchemaTestCase(t *testing.T, manifest string) schemaTestCase {
+	provider, err := kube.CreateResourceProviderFromYaml(manifest)
+	assert.NoError(t, err)
+	for _, resources := range provider.Resources {
+		return schemaTestCase{Resource: resources[0], ResourceProvider: provider}
+	}
+	t.Fatal("no resources found")
+	return schemaTestCase{}
+}
+
+func TestValidateKubernetesSchema(t *testing.T) {
+	testCases := []struct {
+		manifest string
+		version  conf.KubernetesVersion
+		messages []string
+	}{
+		{
+			manifest: `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: foo
+spec:
+  containers:
+    - name: foo
+      image: nginx
+      imagePullPolcy: Always
+      env:
+        - value: bar
+        - name: PORT
+          value: 8080
+`,
+			messages: []string{
+				`missing required field "spec.containers[0].env[0].name"`,
+				`unknown field "spec.containers[0].imagePullPolcy"`,
+				"spec.containers[0].env[1].value: expected string, got integer",
+			},
+		},
+		{
+			manifest: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: foo
+  creationTimestamp: null
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: foo
+  template:
+    spec:
+      containers:
+        - name: foo
+          image: nginx
+          resources:
+            limits:
+              cpu: 1
+              memory: 1Gi
+          livenessProbe:
+            tcpSocket:
+              port: 8080
+`,
+		},
+		{
+			manifest: `
+apiVersion: batch/v1beta1
+kind: CronJob
+metadata:
+  name: foo
+`,
+			version:  conf.KubernetesVersion{Major: 1, Minor: 25},
+			messages: []string{"batch/v1beta1 CronJob is not served since Kubernetes 1.25"},
+		},
+		{
+			manifest: `
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: foo
+spec:
+  rules:
+    - http:
+        paths:
+          - path: /
+            backend:
+              service:
+                name: foo
+                port:
+                  number: 80
+`,
+			messages: []string{`missing required field "spec.rules[0].http.paths[0].pathType"`},
+		},
+		{
+			manifest: `
+apiVersion: unknown.example.com/v1
+kind: Widget
+metadata:
+  name: foo
+spec:
+  anything: goes
+`,
+		},
+	}
+	for _, tc := range testCases {
+		test := getSchemaTestCase(t, tc.manifest)
+		test.KubernetesVersion = tc.version
+		if test.KubernetesVersion.IsZero() {
+			test.KubernetesVersion = schemaTestVersion
+		}
+		passes, issues, err := validateKubernetesSchema(test)
+		assert.NoError(t, err)
+		assert.Equal(t, len(tc.messages) == 0, passes)
+		messages := []string{}
+		for _, issue := range issues {
+			messages = append(messages, issue.Message)
+		}
+		assert.ElementsMatch(t, tc.messages, messages)
+	}
+}
+
+func TestValidateKubernetesSchemaCRDPaths(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "crd.yaml"), []byte(schemaTestCRD), 0644))
+
+	test := getSchemaTestCase(t, `
+apiVersion: stable.example.com/v1
+kind: CronTab
+metadata:
+  name: foo
+spec:
+  replicas: 3
+  schedule: "* * * * *"
+`)
+	test.KubernetesVersion = schemaTestVersion
+	passes, issues, err := validateKubernetesSchema(test)
+	assert.NoError(t, err)
+	assert.True(t, passes, "CRDs are only used when configured")
+	assert.Len(t, issues, 0)
+
+	test.Config = &conf.Configuration{CRDPaths: []string{dir}}
+	passes, issues, err = validateKubernetesSchema(test)
+	assert.NoError(t, err)
+	assert.False(t, passes)
+	messages := []string{}
+	for _, issue := range issues {
+		messages = append(messages, issue.Message)
+	}
+	assert.ElementsMatch(t, []string{`missing required field "spec.cronSpec"`, `unknown field "spec.schedule"`}, messages)
+}
+
+func TestValidateKubernetesSchemaVersions(t *testing.T) {
+	test := getSchemaTestCase(t, `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: foo
+spec:
+  initContainers:
+    - name: proxy
+      image: envoy
+      restartPolicy: Always
+  containers:
+    - name: foo
+      image: nginx
+`)
+	_, _, err := validateKubernetesSchema(test)
+	assert.EqualError(t, err, "the target Kubernetes version is unknown, set it with --kubernetes-version or kubernetesVersion to one of 1.27")
+
+	test.KubernetesVersion = conf.KubernetesVersion{Major: 1, Minor: 30}
+	_, _, err = validateKubernetesSchema(test)
+	assert.EqualError(t, err, "no OpenAPI document is embedded for Kubernetes 1.30, schemas are available for 1.27")
+
+	test.KubernetesVersion = conf.KubernetesVersion{Major: 1, Minor: 24}
+	_, _, err = validateKubernetesSchema(test)
+	assert.EqualError(t, err, "no OpenAPI document is embedded for Kubernetes 1.24, schemas are available for 1.27")
+}
+
+func TestAuditContextCRDSchemas(t *testing.T) {
+	provider, err := kube.CreateResourceProviderFromYaml(schemaTestCRD + `
+---
+apiVersion: stable.example.com/v1
+kind: CronTab
+metadata:
+  name: foo
+spec:
+  schedule: "* * * * *"
+`)
+	assert.NoError(t, err)
+	audit := newAuditContext(provider)
+	gvk := schema.GroupVersionKind{Group: "stable.example.com", Version: "v1", Kind: "CronTab"}
+	node := audit.getCRDSchemas()[gvk]
+	assert.NotNil(t, node)
+	assert.Same(t, node, audit.getCRDSchemas()[gvk], "schemas are built once per audit")
+
+	test := schemaTestCase{
+		Resource:          provider.Resources["stable.example.com/CronTab"][0],
+		ResourceProvider:  provider,
+		KubernetesVersion: schemaTestVersion,
+		Audit:             audit,
+	}
+	passes, issues, err := validateKubernetesSchema(test)
+	assert.NoError(t, err)
+	assert.False(t, passes)
+	assert.Len(t, issues, 2)
+}
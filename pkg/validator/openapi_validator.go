@@ -0,0 +1,172 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/qri-io/jsonschema"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+const crdGroupKind = "apiextensions.k8s.io/CustomResourceDefinition"
+
+var (
+	crdPathSchemas     = map[string]map[schema.GroupVersionKind]*openAPINode{}
+	crdPathSchemasLock = &sync.Mutex{}
+)
+
+func init() {
+	registerCustomChecks("kubernetesSchema", validateKubernetesSchema)
+}
+
+func validateKubernetesSchema(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	obj, err := getManifestObject(test.Resource)
+	if err != nil {
+		return false, nil, err
+	}
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	if api, ok := deprecatedAPIs[apiVersion+"/"+kind]; ok && api.RemovedIn != "" {
+		if _, removed := api.isDeprecated(test.KubernetesVersion); removed {
+			return false, []jsonschema.ValError{
+				{
+					PropertyPath: "apiVersion",
+					InvalidValue: apiVersion,
+					Message:      fmt.Sprintf("%s %s is not served since Kubernetes %s", apiVersion, kind, api.RemovedIn),
+				},
+			}, nil
+		}
+	}
+	gvk := schema.FromAPIVersionAndKind(apiVersion, kind)
+	node, err := getBuiltinSchema(gvk, test.KubernetesVersion)
+	if err != nil {
+		return false, nil, err
+	}
+	if node == nil {
+		node, err = getCRDSchema(test, gvk)
+		if err != nil {
+			return false, nil, err
+		}
+	}
+	if node == nil {
+		logrus.Debugf("no schema found for %s %s, skipping schema validation", apiVersion, kind)
+		return true, nil, nil
+	}
+	issues := node.validate("", obj)
+	return len(issues) == 0, issues, nil
+}
+
+// getManifestObject returns the resource as it was written. Resources parsed from YAML are
+// re-read from their original content, since typed decoding (e.g. for Pods) drops unknown fields.
+func getManifestObject(resource kube.GenericResource) (map[string]interface{}, error) {
+	if len(resource.OriginalObjectYAML) == 0 {
+		return resource.Resource.Object, nil
+	}
+	obj := map[string]interface{}{}
+	if err := yaml.Unmarshal(resource.OriginalObjectYAML, &obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// getCRDSchema looks for a CustomResourceDefinition among the audited resources, then in the configured crdPaths
+func getCRDSchema(test schemaTestCase, gvk schema.GroupVersionKind) (*openAPINode, error) {
+	if node := test.Audit.getCRDSchemas()[gvk]; node != nil {
+		return node, nil
+	}
+	if test.Config == nil {
+		return nil, nil
+	}
+	for _, path := range test.Config.CRDPaths {
+		schemas, err := loadCRDSchemas(path)
+		if err != nil {
+			return nil, err
+		}
+		if node := schemas[gvk]; node != nil {
+			return node, nil
+		}
+	}
+	return nil, nil
+}
+
+func loadCRDSchemas(path string) (map[schema.GroupVersionKind]*openAPINode, error) {
+	crdPathSchemasLock.Lock()
+	defer crdPathSchemasLock.Unlock()
+	if schemas, ok := crdPathSchemas[path]; ok {
+		return schemas, nil
+	}
+	provider, err := kube.CreateResourceProviderFromPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not load CustomResourceDefinitions from %s: %w", path, err)
+	}
+	schemas := getCRDSchemas(provider.Resources[crdGroupKind])
+	crdPathSchemas[path] = schemas
+	return schemas, nil
+}
+
+// getCRDSchemas returns the schemas of the CustomResourceDefinitions among the audited resources
+func (audit *auditContext) getCRDSchemas() map[schema.GroupVersionKind]*openAPINode {
+	if audit.getProvider() == nil {
+		return nil
+	}
+	audit.crdSchemasOnce.Do(func() {
+		audit.crdSchemas = getCRDSchemas(audit.provider.Resources[crdGroupKind])
+	})
+	return audit.crdSchemas
+}
+
+// getCRDSchemas returns the schema of every served version of the CustomResourceDefinitions
+func getCRDSchemas(crds []kube.GenericResource) map[schema.GroupVersionKind]*openAPINode {
+	schemas := map[schema.GroupVersionKind]*openAPINode{}
+	for _, crd := range crds {
+		group, _, _ := unstructured.NestedString(crd.Resource.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(crd.Resource.Object, "spec", "names", "kind")
+		// apiextensions.k8s.io/v1beta1 CRDs may declare a single schema for all versions
+		sharedSchema, _, _ := unstructured.NestedFieldNoCopy(crd.Resource.Object, "spec", "validation", "openAPIV3Schema")
+		versions, _, _ := unstructured.NestedFieldNoCopy(crd.Resource.Object, "spec", "versions")
+		versionList, _ := versions.([]interface{})
+		if len(versionList) == 0 {
+			if version, _, _ := unstructured.NestedString(crd.Resource.Object, "spec", "version"); version != "" {
+				versionList = append(versionList, map[string]interface{}{"name": version})
+			}
+		}
+		for _, v := range versionList {
+			version, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(version, "name")
+			openAPISchema, found, _ := unstructured.NestedFieldNoCopy(version, "schema", "openAPIV3Schema")
+			if !found {
+				openAPISchema = sharedSchema
+			}
+			schemaMap, ok := openAPISchema.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			node := schemaFromOpenAPI(schemaMap, nil)
+			addImplicitResourceFields(node, nil)
+			schemas[schema.GroupVersionKind{Group: group, Version: name, Kind: kind}] = node
+		}
+	}
+	return schemas
+}
@@ -28,6 +28,8 @@ import (
 const (
 	// PolarisOutputVersion is the version of the current output structure
 	PolarisOutputVersion = "1.0"
+	// GroupByOwner groups the results of the pretty and markdown output by owner
+	GroupByOwner = "owner"
 )
 
 var (
@@ -51,6 +53,13 @@ type AuditData struct {
 	ClusterInfo          ClusterInfo
 	Results              []Result
 	Score                uint
+	// OwnerSummaries summarizes the results of each owner, like Score, before they're filtered
+	OwnerSummaries  map[string]CountSummary
+	PodSecurity     []NamespacePodSecurity
+	NetworkPolicies []NamespaceNetworkPolicy
+	RBAC            []RBACSubject
+	Nodes           []NodeSummary
+	Efficiency      *EfficiencyReport
 }
 
 // FilterResultsBySeverityLevel includes results according to the provided severity level:
@@ -155,6 +164,20 @@ type Result struct {
 	Results     ResultSet
 	PodResult   *PodResult
 	CreatedTime time.Time
+	// PodSecurityLevel is the most restrictive Pod Security Standards profile the workload satisfies
+	PodSecurityLevel config.PodSecurityLevel
+	// NetworkPolicy describes the NetworkPolicies selecting the workload's pods
+	NetworkPolicy *NetworkPolicyCoverage
+	// ServiceAccount is the RBAC analysis of the workload's ServiceAccount, if it's bound to any role
+	ServiceAccount *RBACSubject
+	// Risk is the severity of the failing checks, weighted by the RiskFactors of the resource
+	Risk        uint
+	RiskFactors []RiskFactor
+	// Owner is the team that can fix the findings of the resource, see config.Ownership
+	Owner string
+	// Exemptions lists the checks that were skipped for the resource because of an exemption.
+	// It's only set when config.ComplianceReport is enabled.
+	Exemptions []string
 }
 
 func (res Result) removeSuccessfulResults() Result {
@@ -178,8 +201,8 @@ func (res Result) filterResultsBySeverityLevel(severityLevel config.Severity) Re
 }
 
 func (res Result) isNotEmpty() bool {
-	if res.PodResult != nil {
-		return res.PodResult.isNotEmpty()
+	if res.PodResult != nil && res.PodResult.isNotEmpty() {
+		return true
 	}
 	return res.Results.isNotEmpty()
 }
@@ -220,8 +243,9 @@ func (res PodResult) isNotEmpty() bool {
 
 // ContainerResult provides a list of validation messages for each container.
 type ContainerResult struct {
-	Name    string
-	Results ResultSet
+	Name           string
+	ContainerClass config.ContainerClass
+	Results        ResultSet
 }
 
 func (res ContainerResult) removeSuccessfulResults() ContainerResult {
@@ -249,13 +273,76 @@ func fillString(id string, l int) string {
 
 // GetPrettyOutput returns a human-readable string
 func (res AuditData) GetPrettyOutput(useColor bool) string {
+	return res.GetGroupedPrettyOutput(useColor, "")
+}
+
+// GetGroupedPrettyOutput returns a human-readable string, with the results grouped by owner if groupBy is
+// GroupByOwner
+func (res AuditData) GetGroupedPrettyOutput(useColor bool, groupBy string) string {
 	color.NoColor = !useColor
 	str := titleColor.Sprint(fmt.Sprintf("Polaris audited %s %s at %s\n", res.SourceType, res.SourceName, res.AuditTime))
 	str += color.CyanString(fmt.Sprintf("    Nodes: %d | Namespaces: %d | Controllers: %d\n", res.ClusterInfo.Nodes, res.ClusterInfo.Namespaces, res.ClusterInfo.Controllers))
 	str += color.GreenString(fmt.Sprintf("    Final score: %d\n", res.Score))
+	if len(res.PodSecurity) > 0 {
+		str += color.CyanString("    Pod Security Standards:\n")
+		for _, ns := range res.PodSecurity {
+			str += ns.GetPrettyOutput()
+		}
+	}
+	if len(res.NetworkPolicies) > 0 {
+		str += color.CyanString("    Network Policies:\n")
+		for _, ns := range res.NetworkPolicies {
+			str += ns.GetPrettyOutput()
+		}
+	}
+	escalations := ""
+	for _, subject := range res.RBAC {
+		if subject.isEscalating() {
+			escalations += subject.GetPrettyOutput()
+		}
+	}
+	if escalations != "" {
+		str += color.CyanString("    RBAC escalation paths:\n") + escalations
+	}
+	if res.Efficiency != nil {
+		str += color.CyanString("    Resources:\n") + res.Efficiency.GetPrettyOutput()
+	}
+	if riskiest := res.GetRiskiestWorkloads(RiskiestWorkloadsCount); len(riskiest) > 0 {
+		str += color.CyanString(fmt.Sprintf("    Top %d riskiest workloads:\n", len(riskiest)))
+		for _, result := range riskiest {
+			str += color.CyanString(fmt.Sprintf("      %d: %s %s", result.Risk, result.Kind, getNamespacedName(result.Namespace, result.Name)))
+			if factors := result.GetRiskFactorTypes(); len(factors) > 0 {
+				str += color.CyanString(fmt.Sprintf(" (%s)", strings.Join(factors, ", ")))
+			}
+			str += "\n"
+		}
+	}
 	str += "\n"
-	for _, result := range res.Results {
-		str += result.GetPrettyOutput() + "\n"
+	nodeResults := ""
+	if groupBy == GroupByOwner {
+		resultsByOwner := res.GetResultsByOwner()
+		summaries := res.GetSummaryByOwner()
+		for _, owner := range getSortedOwners(resultsByOwner) {
+			str += titleColor.Sprint(fmt.Sprintf("Owner %s: score %d, %d resource(s)\n", owner, summaries[owner].GetScore(), len(resultsByOwner[owner])))
+			for _, result := range resultsByOwner[owner] {
+				str += result.GetPrettyOutput() + "\n"
+			}
+		}
+	} else {
+		for _, result := range res.Results {
+			if result.Kind == string(config.TargetNode) {
+				nodeResults += result.GetPrettyOutput() + "\n"
+				continue
+			}
+			str += result.GetPrettyOutput() + "\n"
+		}
+	}
+	if len(res.Nodes) > 0 || nodeResults != "" {
+		str += titleColor.Sprint("Nodes\n")
+		for _, node := range res.Nodes {
+			str += node.GetPrettyOutput()
+		}
+		str += "\n" + nodeResults
 	}
 	color.NoColor = false
 	return str
@@ -268,6 +355,24 @@ func (res Result) GetPrettyOutput() string {
 		str += titleColor.Sprint(fmt.Sprintf(" in namespace %s", res.Namespace))
 	}
 	str += "\n"
+	if res.Owner != "" {
+		str += fmt.Sprintf("    Owner: %s\n", res.Owner)
+	}
+	if res.PodSecurityLevel != "" {
+		str += fmt.Sprintf("    Pod Security Standards: %s\n", res.PodSecurityLevel)
+	}
+	if res.NetworkPolicy != nil {
+		str += res.NetworkPolicy.GetPrettyOutput()
+	}
+	if res.ServiceAccount != nil {
+		str += fmt.Sprintf("    %s: %d RBAC rule(s), %d escalation path(s)\n", res.ServiceAccount.String(), len(res.ServiceAccount.Permissions), len(res.ServiceAccount.Escalations))
+	}
+	if res.Risk > 0 {
+		str += fmt.Sprintf("    Risk: %d\n", res.Risk)
+		for _, factor := range res.RiskFactors {
+			str += fmt.Sprintf("      %s\n", factor.Message)
+		}
+	}
 	str += res.Results.GetPrettyOutput()
 	if res.PodResult != nil {
 		str += res.PodResult.GetPrettyOutput()
@@ -275,6 +380,67 @@ func (res Result) GetPrettyOutput() string {
 	return str
 }
 
+// GetPrettyOutput returns a human-readable string
+func (res NamespacePodSecurity) GetPrettyOutput() string {
+	enforce := "no profile"
+	if res.Enforce != "" {
+		enforce = string(res.Enforce)
+	}
+	return color.CyanString(fmt.Sprintf("      %s: can enforce %s (currently %s), %d workload(s)\n", formatNamespace(res.Namespace), res.Recommended, enforce, res.Workloads))
+}
+
+// GetPrettyOutput returns a human-readable string
+func (res NamespaceNetworkPolicy) GetPrettyOutput() string {
+	return color.CyanString(fmt.Sprintf("      %s: %d policy(s), default deny ingress: %s, egress: %s | ingress restricted for %d/%d workload(s), egress for %d/%d\n",
+		formatNamespace(res.Namespace), res.Policies, formatYesNo(res.IngressDefaultDeny), formatYesNo(res.EgressDefaultDeny),
+		res.IngressCovered, res.Workloads, res.EgressCovered, res.Workloads))
+}
+
+// GetPrettyOutput returns a human-readable string
+func (res NodeSummary) GetPrettyOutput() string {
+	str := fmt.Sprintf("    %s: kubelet %s, %s", res.Name, res.KubeletVersion, res.ContainerRuntimeVersion)
+	if res.Unschedulable {
+		str += ", unschedulable"
+	}
+	return str + fmt.Sprintf(" | %d failing check(s)\n", res.Failures)
+}
+
+// GetPrettyOutput returns a human-readable string
+func (res RBACSubject) GetPrettyOutput() string {
+	str := color.CyanString(fmt.Sprintf("      %s\n", res.String()))
+	if len(res.Workloads) > 0 {
+		str += color.CyanString(fmt.Sprintf("        used by %s\n", strings.Join(res.Workloads, ", ")))
+	}
+	for _, escalation := range res.Escalations {
+		str += color.CyanString(fmt.Sprintf("        %s (%s)\n", escalation.Message, escalation.Binding))
+	}
+	return str
+}
+
+// GetPrettyOutput returns a human-readable string
+func (res NetworkPolicyCoverage) GetPrettyOutput() string {
+	policies := "none"
+	if len(res.Policies) > 0 {
+		policies = strings.Join(res.Policies, ", ")
+	}
+	return fmt.Sprintf("    Network Policies: %s | ingress restricted: %s | egress restricted: %s\n", policies, formatYesNo(res.IngressDefaultDeny), formatYesNo(res.EgressDefaultDeny))
+}
+
+// formatNamespace names the namespace of resources without one, e.g. manifests that are applied to the current namespace
+func formatNamespace(namespace string) string {
+	if namespace == "" {
+		return "(no namespace)"
+	}
+	return namespace
+}
+
+func formatYesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
+
 // GetPrettyOutput returns a human-readable string
 func (res PodResult) GetPrettyOutput() string {
 	str := res.Results.GetPrettyOutput()
@@ -286,7 +452,11 @@ func (res PodResult) GetPrettyOutput() string {
 
 // GetPrettyOutput returns a human-readable string
 func (res ContainerResult) GetPrettyOutput() string {
-	str := titleColor.Sprint(fmt.Sprintf("  Container %s\n", res.Name))
+	name := res.Name
+	if res.ContainerClass == config.ContainerClassEphemeral {
+		name += " (ephemeral)"
+	}
+	str := titleColor.Sprint(fmt.Sprintf("  Container %s\n", name))
 	str += res.Results.GetPrettyOutput()
 	return str
 }
@@ -311,6 +481,9 @@ func (res ResultSet) GetPrettyOutput() string {
 		}
 		str += fmt.Sprintf("%s%s %s\n", indent, checkColor.Sprint(fillString(msg.ID, minIDLength-len(indent))), status)
 		str += fmt.Sprintf("%s    %s - %s\n", indent, msg.Category, msg.Message)
+		for _, detail := range msg.Details {
+			str += fmt.Sprintf("%s      %s\n", indent, detail)
+		}
 	}
 	return str
 }
@@ -0,0 +1,78 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+
+	"github.com/qri-io/jsonschema"
+	nodev1 "k8s.io/api/node/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func init() {
+	registerCustomChecks("podOverheadMismatch", podOverheadMismatch)
+	registerCustomApplicability("podOverheadMismatch", hasPodOverhead)
+}
+
+func hasPodOverhead(test schemaTestCase) bool {
+	return test.Resource.PodSpec != nil && len(test.Resource.PodSpec.Overhead) > 0
+}
+
+// podOverheadMismatch reports pod overhead that the RuntimeClass admission controller rejects. It sets the overhead
+// from the pod's RuntimeClass, and only accepts pods that already set the same overhead.
+func podOverheadMismatch(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	podSpec := test.Resource.PodSpec
+	if podSpec.RuntimeClassName == nil || *podSpec.RuntimeClassName == "" {
+		return false, []jsonschema.ValError{
+			{
+				PropertyPath: "overhead",
+				Message:      "overhead is set without a runtimeClassName, so the pod will be rejected",
+			},
+		}, nil
+	}
+	if test.ResourceProvider == nil {
+		return true, nil, nil
+	}
+	name := *podSpec.RuntimeClassName
+	generic := findResource(test.ResourceProvider.Resources["node.k8s.io/RuntimeClass"], "", name)
+	if generic == nil {
+		// The RuntimeClass isn't part of the audit, so the overhead can't be compared
+		return true, nil, nil
+	}
+	runtimeClass := nodev1.RuntimeClass{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(generic.Resource.Object, &runtimeClass)
+	if err != nil {
+		return false, nil, err
+	}
+	if runtimeClass.Overhead == nil || len(runtimeClass.Overhead.PodFixed) == 0 {
+		return false, []jsonschema.ValError{
+			{
+				PropertyPath: "overhead",
+				Message:      fmt.Sprintf("overhead is set, but RuntimeClass %s has no overhead, so the pod will be rejected", name),
+			},
+		}, nil
+	}
+	if !equality.Semantic.DeepEqual(podSpec.Overhead, runtimeClass.Overhead.PodFixed) {
+		return false, []jsonschema.ValError{
+			{
+				PropertyPath: "overhead",
+				Message:      fmt.Sprintf("overhead doesn't match the overhead of RuntimeClass %s, so the pod will be rejected", name),
+			},
+		}, nil
+	}
+	return true, nil, nil
+}
@@ -0,0 +1,255 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fairwindsops/polaris/pkg/config"
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+// UnownedResults is the owner results are grouped under when no owner was found
+const UnownedResults = "unowned"
+
+// codeOwnersRule is a line of a CODEOWNERS file. Rules without owners unset the owner of the files they match.
+type codeOwnersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// codeOwnersFile is a parsed CODEOWNERS file. Its patterns are relative to the root of the repository.
+type codeOwnersFile struct {
+	root  string
+	rules []codeOwnersRule
+}
+
+// codeOwnersFiles caches the parsed CODEOWNERS files by path
+var codeOwnersFiles = map[string]codeOwnersFile{}
+
+// codeOwnersPatternToRegexp converts a CODEOWNERS pattern, which follows the gitignore syntax, to a regular
+// expression. Patterns without a slash, or with only a trailing one, match at any depth. A trailing /* only
+// matches the files of the directory, not of its subdirectories.
+func codeOwnersPatternToRegexp(pattern string) (*regexp.Regexp, error) {
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	directory := strings.HasSuffix(pattern, "/")
+	nonRecursive := strings.HasSuffix(pattern, "/*")
+	pattern = strings.Trim(pattern, "/")
+
+	expr := "^"
+	if !anchored {
+		expr += "(.*/)?"
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr += "(.*/)?"
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr += ".*"
+			i++
+		case pattern[i] == '*':
+			expr += "[^/]*"
+		case pattern[i] == '?':
+			expr += "[^/]"
+		default:
+			expr += regexp.QuoteMeta(string(pattern[i]))
+		}
+	}
+	if directory {
+		expr += "/.*$"
+	} else if nonRecursive {
+		expr += "$"
+	} else {
+		expr += "(/.*)?$"
+	}
+	return regexp.Compile(expr)
+}
+
+func parseCodeOwners(contents string) ([]codeOwnersRule, error) {
+	rules := []codeOwnersRule{}
+	for idx, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		owners := []string{}
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "#") {
+				break
+			}
+			owners = append(owners, owner)
+		}
+		pattern, err := codeOwnersPatternToRegexp(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid pattern %s: %v", idx+1, fields[0], err)
+		}
+		rules = append(rules, codeOwnersRule{pattern: pattern, owners: owners})
+	}
+	return rules, nil
+}
+
+// getCodeOwnersRoot returns the root of the repository of a CODEOWNERS file, which GitHub looks for in the root,
+// .github/ and docs/ directories
+func getCodeOwnersRoot(path string) (string, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	root := filepath.Dir(absolute)
+	if base := filepath.Base(root); base == ".github" || base == "docs" {
+		root = filepath.Dir(root)
+	}
+	return root, nil
+}
+
+// loadCodeOwnersFile reads a CODEOWNERS file, with lines of a path pattern followed by its owners, e.g.
+// `/deploy/payments/ @org/payments`
+func loadCodeOwnersFile(path string) (codeOwnersFile, error) {
+	lock.Lock()
+	defer lock.Unlock()
+	if file, ok := codeOwnersFiles[path]; ok {
+		return file, nil
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return codeOwnersFile{}, err
+	}
+	rules, err := parseCodeOwners(string(contents))
+	if err != nil {
+		return codeOwnersFile{}, fmt.Errorf("parsing CODEOWNERS file %s: %v", path, err)
+	}
+	root, err := getCodeOwnersRoot(path)
+	if err != nil {
+		return codeOwnersFile{}, err
+	}
+	codeOwnersFiles[path] = codeOwnersFile{root: root, rules: rules}
+	return codeOwnersFiles[path], nil
+}
+
+// getCodeOwners returns the owners of the last rule matching a file. Paths are resolved against the working
+// directory, and matched relative to the root of the repository, if the file has one. Files outside of the
+// repository have no owners.
+func getCodeOwners(file codeOwnersFile, path string) []string {
+	if file.root != "" {
+		absolute, err := filepath.Abs(path)
+		if err != nil {
+			return nil
+		}
+		relative, err := filepath.Rel(file.root, absolute)
+		if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			return nil
+		}
+		path = relative
+	}
+	path = filepath.ToSlash(filepath.Clean(path))
+	for idx := len(file.rules) - 1; idx >= 0; idx-- {
+		if file.rules[idx].pattern.MatchString(path) {
+			return file.rules[idx].owners
+		}
+	}
+	return nil
+}
+
+// getOwner finds the team owning a resource, from its labels or annotations, the CODEOWNERS file of audited files,
+// or the labels of its Namespace, in that order
+func getOwner(conf *config.Configuration, resourceProvider *kube.ResourceProvider, resource kube.GenericResource) (string, error) {
+	if conf == nil {
+		return "", nil
+	}
+	ownerLabels := conf.Ownership.GetLabels()
+	for _, label := range ownerLabels {
+		if owner := resource.ObjectMeta.GetLabels()[label]; owner != "" {
+			return owner, nil
+		}
+		if owner := resource.ObjectMeta.GetAnnotations()[label]; owner != "" {
+			return owner, nil
+		}
+	}
+	if conf.Ownership.CodeOwnersFile != "" && resource.SourcePath != "" {
+		file, err := loadCodeOwnersFile(conf.Ownership.CodeOwnersFile)
+		if err != nil {
+			return "", err
+		}
+		if owners := getCodeOwners(file, resource.SourcePath); len(owners) > 0 {
+			return strings.Join(owners, " "), nil
+		}
+	}
+	namespace := resource.ObjectMeta.GetNamespace()
+	if namespace == "" || resourceProvider == nil {
+		return "", nil
+	}
+	for _, ns := range resourceProvider.Namespaces {
+		if ns.ObjectMeta.GetName() != namespace {
+			continue
+		}
+		for _, label := range conf.Ownership.GetNamespaceLabels() {
+			if owner := ns.ObjectMeta.GetLabels()[label]; owner != "" {
+				return owner, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// GetResultsByOwner organizes results by the team owning them. Results without an owner are under UnownedResults.
+func (res AuditData) GetResultsByOwner() map[string][]*Result {
+	allResults := map[string][]*Result{}
+	for idx, result := range res.Results {
+		owner := result.Owner
+		if owner == "" {
+			owner = UnownedResults
+		}
+		allResults[owner] = append(allResults[owner], &res.Results[idx])
+	}
+	return allResults
+}
+
+// GetSummaryByOwner summarizes the results of each owner, e.g. to score teams. Audits summarize their owners
+// before results are filtered, in OwnerSummaries.
+func (res AuditData) GetSummaryByOwner() map[string]CountSummary {
+	if res.OwnerSummaries != nil {
+		return res.OwnerSummaries
+	}
+	summaries := map[string]CountSummary{}
+	for owner, results := range res.GetResultsByOwner() {
+		summary := CountSummary{}
+		for _, result := range results {
+			summary.AddSummary(result.GetSummary())
+		}
+		summaries[owner] = summary
+	}
+	return summaries
+}
+
+// getSortedOwners returns the owners of the results in alphabetical order, with UnownedResults last
+func getSortedOwners(resultsByOwner map[string][]*Result) []string {
+	owners := []string{}
+	for owner := range resultsByOwner {
+		owners = append(owners, owner)
+	}
+	sort.Slice(owners, func(i, j int) bool {
+		if owners[i] == UnownedResults || owners[j] == UnownedResults {
+			return owners[j] == UnownedResults && owners[i] != UnownedResults
+		}
+		return owners[i] < owners[j]
+	})
+	return owners
+}
@@ -0,0 +1,198 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	conf "github.com/fairwindsops/polaris/pkg/config"
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+const ownershipTestResources = `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: shop
+  labels:
+    owner: storefront
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.27
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: checkout
+  namespace: shop
+  annotations:
+    app.kubernetes.io/part-of: payments
+spec:
+  selector:
+    matchLabels:
+      app: checkout
+  template:
+    metadata:
+      labels:
+        app: checkout
+    spec:
+      containers:
+        - name: checkout
+          image: checkout:1.0
+          resources:
+            requests:
+              cpu: 100m
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: report
+  namespace: batch
+spec:
+  selector:
+    matchLabels:
+      app: report
+  template:
+    metadata:
+      labels:
+        app: report
+    spec:
+      containers:
+        - name: report
+          image: report:1.0
+`
+
+func TestCodeOwners(t *testing.T) {
+	rules, err := parseCodeOwners(`
+# comment
+*                  @org/platform
+*.yml              @org/legacy # inline comment
+/deploy/payments/  @org/payments @alice
+deploy/**/jobs     @org/batch
+charts/            @org/charts
+/docs/*            @org/docs
+/deploy/payments/vendor.yaml
+`)
+	assert.NoError(t, err)
+	testCases := map[string][]string{
+		"README.md":                        {"@org/platform"},
+		"deploy/shop/app.yml":              {"@org/legacy"},
+		"deploy/payments/app.yaml":         {"@org/payments", "@alice"},
+		"./deploy/payments/db/db.yaml":     {"@org/payments", "@alice"},
+		"deploy/shop/jobs/report.yaml":     {"@org/batch"},
+		"deploy/jobs/report.yaml":          {"@org/batch"},
+		"infra/charts/web/deployment.yaml": {"@org/charts"},
+		"deploy/payments/vendor.yaml":      {},
+		"docs/a.yaml":                      {"@org/docs"},
+		"docs/sub/b.yaml":                  {"@org/platform"},
+	}
+	for path, owners := range testCases {
+		assert.Equal(t, owners, getCodeOwners(codeOwnersFile{rules: rules}, path), path)
+	}
+}
+
+func TestGetCodeOwnersRoot(t *testing.T) {
+	repository := t.TempDir()
+	for _, path := range []string{"CODEOWNERS", ".github/CODEOWNERS", "docs/CODEOWNERS"} {
+		root, err := getCodeOwnersRoot(filepath.Join(repository, path))
+		assert.NoError(t, err)
+		assert.Equal(t, repository, root, path)
+	}
+	file := codeOwnersFile{root: repository, rules: []codeOwnersRule{{pattern: regexp.MustCompile("^deploy/.*$"), owners: []string{"@org/platform"}}}}
+	assert.Equal(t, []string{"@org/platform"}, getCodeOwners(file, filepath.Join(repository, "deploy", "web.yaml")))
+	assert.Empty(t, getCodeOwners(file, filepath.Join(filepath.Dir(repository), "deploy", "web.yaml")))
+}
+
+func TestOwnership(t *testing.T) {
+	repository := t.TempDir()
+	codeOwners := filepath.Join(repository, ".github", "CODEOWNERS")
+	assert.NoError(t, os.Mkdir(filepath.Dir(codeOwners), 0755))
+	assert.NoError(t, os.WriteFile(codeOwners, []byte("/jobs/ @org/batch\n"), 0644))
+	c := conf.Configuration{
+		Checks: map[string]conf.Severity{
+			"cpuRequestsMissing": conf.SeverityWarning,
+		},
+		Ownership: conf.Ownership{CodeOwnersFile: codeOwners},
+	}
+	provider, err := kube.CreateResourceProviderFromYaml(ownershipTestResources)
+	assert.NoError(t, err)
+	for idx := range provider.Resources["apps/Deployment"] {
+		provider.Resources["apps/Deployment"][idx].SourcePath = filepath.Join(repository, "jobs", provider.Resources["apps/Deployment"][idx].ObjectMeta.GetName()+".yaml")
+	}
+	auditData, err := RunAudit(c, provider)
+	assert.NoError(t, err)
+
+	owners := map[string]string{}
+	for _, result := range auditData.Results {
+		owners[result.Kind+" "+result.Name] = result.Owner
+	}
+	assert.Equal(t, map[string]string{
+		"Namespace shop":      "storefront",
+		"Deployment web":      "@org/batch",
+		"Deployment checkout": "payments",
+		"Deployment report":   "@org/batch",
+	}, owners)
+
+	c.Ownership.CodeOwnersFile = ""
+	auditData, err = RunAudit(c, provider)
+	assert.NoError(t, err)
+	resultsByOwner := auditData.GetResultsByOwner()
+	assert.Equal(t, []string{"payments", "storefront", UnownedResults}, getSortedOwners(resultsByOwner))
+	assert.Len(t, resultsByOwner["storefront"], 2)
+	assert.Equal(t, map[string]CountSummary{
+		"payments":     {Successes: 1},
+		"storefront":   {Warnings: 1},
+		UnownedResults: {Warnings: 1},
+	}, auditData.GetSummaryByOwner())
+
+	markdown := auditData.GetMarkdownOutput(GroupByOwner)
+	assert.Contains(t, markdown, "## Owner: payments\n\nScore: 100% | 0 danger, 0 warning and 1 passing check(s)\n\nNo failing checks.\n")
+	assert.Contains(t, markdown, "## Owner: storefront\n\nScore: 0% | 0 danger, 1 warning and 0 passing check(s)\n\n"+
+		"| Resource | Check | Severity | Message |\n|----------|-------|----------|---------|\n"+
+		"| Deployment shop/web, container web | `cpuRequestsMissing` | warning | CPU requests should be set |\n")
+	assert.Contains(t, auditData.GetGroupedPrettyOutput(false, GroupByOwner), "Owner unowned: score 0, 1 resource(s)\nDeployment report in namespace batch\n")
+
+	// Owners are scored on all their results, even when only the failures are shown
+	c.Checks["hostIPCSet"] = conf.SeverityDanger
+	auditData, err = RunAudit(c, provider)
+	assert.NoError(t, err)
+	failures := auditData.RemoveSuccessfulResults()
+	assert.Equal(t, CountSummary{Successes: 1, Warnings: 1}, failures.GetSummaryByOwner()["storefront"])
+	assert.Contains(t, failures.GetGroupedPrettyOutput(false, GroupByOwner), "Owner storefront: score 66, 1 resource(s)\n")
+
+	c.Ownership.CodeOwnersFile = filepath.Join(t.TempDir(), "missing")
+	_, err = RunAudit(c, provider)
+	assert.Error(t, err)
+}
@@ -8,8 +8,6 @@ import (
 	"github.com/fairwindsops/polaris/pkg/kube"
 	"github.com/qri-io/jsonschema"
 	"github.com/sirupsen/logrus"
-	appsv1 "k8s.io/api/apps/v1"
-	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -24,27 +22,16 @@ func pdbMinAvailableGreaterThanHPAMinReplicas(test schemaTestCase) (bool, []json
 		return true, nil, nil
 	}
 
-	deployment := &appsv1.Deployment{}
-	err := runtime.DefaultUnstructuredConverter.FromUnstructured(test.Resource.Resource.Object, deployment)
-	if err != nil {
-		logrus.Warnf("error converting unstructured to Deployment: %v", err)
-		return true, nil, nil
-	}
-
-	attachedPDB, err := hasPDBAttached(*deployment, test.ResourceProvider.Resources["policy/PodDisruptionBudget"])
+	attachedPDB, err := hasPDBAttached(test.Resource, test.ResourceProvider.Resources["policy/PodDisruptionBudget"])
 	if err != nil {
 		logrus.Warnf("error getting PodDisruptionBudget: %v", err)
 		return true, nil, nil
 	}
 
-	attachedHPA, err := hasHPAAttached(*deployment, test.ResourceProvider.Resources["autoscaling/HorizontalPodAutoscaler"])
-	if err != nil {
-		logrus.Warnf("error getting HorizontalPodAutoscaler: %v", err)
-		return true, nil, nil
-	}
+	attachedHPA := findHorizontalPodAutoscaler(test.ResourceProvider, test.Resource)
 
 	if attachedPDB != nil && attachedHPA != nil {
-		logrus.Debugf("both PDB and HPA are attached to deployment %s", deployment.Name)
+		logrus.Debugf("both PDB and HPA are attached to %s %s", test.Resource.Kind, test.Resource.ObjectMeta.GetName())
 
 		if attachedPDB.Spec.MinAvailable == nil {
 			return true, nil, nil
@@ -83,7 +70,8 @@ func pdbMinAvailableGreaterThanHPAMinReplicas(test schemaTestCase) (bool, []json
 	return true, nil, nil
 }
 
-func hasPDBAttached(deployment appsv1.Deployment, pdbs []kube.GenericResource) (*policyv1.PodDisruptionBudget, error) {
+func hasPDBAttached(resource kube.GenericResource, pdbs []kube.GenericResource) (*policyv1.PodDisruptionBudget, error) {
+	podLabels := getPodLabels(resource)
 	for _, generic := range pdbs {
 		pdb := &policyv1.PodDisruptionBudget{}
 		err := runtime.DefaultUnstructuredConverter.FromUnstructured(generic.Resource.Object, pdb)
@@ -91,11 +79,11 @@ func hasPDBAttached(deployment appsv1.Deployment, pdbs []kube.GenericResource) (
 			return nil, fmt.Errorf("error converting unstructured to PodDisruptionBudget: %v", err)
 		}
 
-		if pdb.Spec.Selector == nil {
+		if pdb.Spec.Selector == nil || pdb.Namespace != resource.ObjectMeta.GetNamespace() {
 			continue
 		}
 
-		if matchesPDBForDeployment(deployment.Spec.Template.Labels, pdb.Spec.Selector.MatchLabels) {
+		if matchesPDBForDeployment(podLabels, pdb.Spec.Selector.MatchLabels) {
 			return pdb, nil
 		}
 	}
@@ -112,21 +100,6 @@ func matchesPDBForDeployment(deploymentLabels, pdbLabels map[string]string) bool
 	return false
 }
 
-func hasHPAAttached(deployment appsv1.Deployment, hpas []kube.GenericResource) (*autoscalingv1.HorizontalPodAutoscaler, error) {
-	for _, generic := range hpas {
-		hpa := &autoscalingv1.HorizontalPodAutoscaler{}
-		err := runtime.DefaultUnstructuredConverter.FromUnstructured(generic.Resource.Object, hpa)
-		if err != nil {
-			return nil, fmt.Errorf("error converting unstructured to HorizontalPodAutoscaler: %v", err)
-		}
-
-		if hpa.Spec.ScaleTargetRef.Kind == "Deployment" && hpa.Spec.ScaleTargetRef.Name == deployment.Name {
-			return hpa, nil
-		}
-	}
-	return nil, nil
-}
-
 // getIntOrPercentValueSafely is a safer version of getIntOrPercentValue based on private function intstr.getIntOrPercentValueSafely
 func getIntOrPercentValueSafely(intOrStr *intstr.IntOrString) (int, bool, error) {
 	switch intOrStr.Type {
@@ -0,0 +1,109 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/fairwindsops/polaris/pkg/config"
+)
+
+// PodSecurityEnforceLabel is the namespace label used by Pod Security Admission to enforce a profile
+const PodSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+
+// NamespacePodSecurity summarizes the Pod Security Standards profiles of the workloads in a namespace
+type NamespacePodSecurity struct {
+	Namespace string
+	// Enforce is the current value of the pod-security.kubernetes.io/enforce label, if any
+	Enforce config.PodSecurityLevel
+	// Recommended is the most restrictive profile every workload in the namespace satisfies
+	Recommended config.PodSecurityLevel
+	Workloads   int
+}
+
+// getPodSecurityLevel returns the most restrictive Pod Security Standards profile the workload satisfies,
+// according to the Pod Security Standards checks that were run. It's empty if none were run.
+func (res Result) getPodSecurityLevel() config.PodSecurityLevel {
+	level := config.PodSecurityLevel("")
+	for _, msg := range res.Results {
+		check, ok := config.BuiltInChecks[msg.ID]
+		if !ok || check.PodSecurityLevel == "" {
+			continue
+		}
+		if level == "" {
+			level = config.PodSecurityLevelRestricted
+		}
+		if !msg.Success && level.Includes(check.PodSecurityLevel) {
+			// Failing a control drops the workload to the profile below the control's
+			level = getPreviousPodSecurityLevel(check.PodSecurityLevel)
+		}
+	}
+	return level
+}
+
+func getPreviousPodSecurityLevel(level config.PodSecurityLevel) config.PodSecurityLevel {
+	previous := config.PodSecurityLevelPrivileged
+	for _, l := range config.PodSecurityLevels {
+		if l == level {
+			return previous
+		}
+		previous = l
+	}
+	return previous
+}
+
+// getNamespacePodSecurity recommends the profile each namespace could enforce without rejecting
+// any of its workloads. Namespaces without workloads can enforce the restricted profile.
+func getNamespacePodSecurity(results []Result, namespaces []corev1.Namespace) []NamespacePodSecurity {
+	summaries := map[string]*NamespacePodSecurity{}
+	getSummary := func(namespace string) *NamespacePodSecurity {
+		if _, ok := summaries[namespace]; !ok {
+			summaries[namespace] = &NamespacePodSecurity{
+				Namespace:   namespace,
+				Recommended: config.PodSecurityLevelRestricted,
+			}
+		}
+		return summaries[namespace]
+	}
+	hasLevels := false
+	for _, result := range results {
+		if result.PodSecurityLevel == "" {
+			continue
+		}
+		hasLevels = true
+		summary := getSummary(result.Namespace)
+		summary.Workloads++
+		if !result.PodSecurityLevel.Includes(summary.Recommended) {
+			summary.Recommended = result.PodSecurityLevel
+		}
+	}
+	if !hasLevels {
+		return nil
+	}
+	for _, namespace := range namespaces {
+		summary := getSummary(namespace.ObjectMeta.GetName())
+		summary.Enforce = config.PodSecurityLevel(namespace.ObjectMeta.GetLabels()[PodSecurityEnforceLabel])
+	}
+	nsSummaries := []NamespacePodSecurity{}
+	for _, summary := range summaries {
+		nsSummaries = append(nsSummaries, *summary)
+	}
+	sort.Slice(nsSummaries, func(i, j int) bool {
+		return nsSummaries[i].Namespace < nsSummaries[j].Namespace
+	})
+	return nsSummaries
+}
@@ -0,0 +1,188 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	conf "github.com/fairwindsops/polaris/pkg/config"
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+const podSecurityTestResources = `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: apps
+  labels:
+    pod-security.kubernetes.io/enforce: baseline
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: empty
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: restricted
+  namespace: apps
+spec:
+  selector:
+    matchLabels:
+      app: restricted
+  template:
+    metadata:
+      labels:
+        app: restricted
+    spec:
+      securityContext:
+        runAsNonRoot: true
+        seccompProfile:
+          type: RuntimeDefault
+      containers:
+        - name: app
+          image: nginx:1.25
+          securityContext:
+            allowPrivilegeEscalation: false
+            capabilities:
+              drop: ["ALL"]
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: baseline
+  namespace: apps
+spec:
+  selector:
+    matchLabels:
+      app: baseline
+  template:
+    metadata:
+      labels:
+        app: baseline
+    spec:
+      containers:
+        - name: app
+          image: nginx:1.25
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: unscoped
+spec:
+  selector:
+    matchLabels:
+      app: unscoped
+  template:
+    metadata:
+      labels:
+        app: unscoped
+    spec:
+      containers:
+        - name: app
+          image: nginx:1.25
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: privileged
+  namespace: tools
+spec:
+  hostNetwork: true
+  containers:
+    - name: shell
+      image: busybox:1.36
+      securityContext:
+        capabilities:
+          add: ["NET_ADMIN"]
+`
+
+func TestPodSecurityStandards(t *testing.T) {
+	c := conf.Configuration{}
+	c.EnablePodSecurityStandards()
+	assert.Len(t, c.Checks, 17)
+	assert.Equal(t, conf.SeverityDanger, c.Checks["pssHostNamespaces"])
+
+	provider, err := kube.CreateResourceProviderFromYaml(podSecurityTestResources)
+	assert.NoError(t, err)
+	auditData, err := RunAudit(c, provider)
+	assert.NoError(t, err)
+
+	levels := map[string]conf.PodSecurityLevel{}
+	for _, result := range auditData.Results {
+		levels[result.Kind+"/"+result.Name] = result.PodSecurityLevel
+	}
+	assert.Equal(t, map[string]conf.PodSecurityLevel{
+		"Namespace/apps":        "",
+		"Namespace/empty":       "",
+		"Deployment/restricted": conf.PodSecurityLevelRestricted,
+		"Deployment/baseline":   conf.PodSecurityLevelBaseline,
+		"Deployment/unscoped":   conf.PodSecurityLevelBaseline,
+		"Pod/privileged":        conf.PodSecurityLevelPrivileged,
+	}, levels)
+
+	assert.Equal(t, []NamespacePodSecurity{
+		{Namespace: "", Recommended: conf.PodSecurityLevelBaseline, Workloads: 1},
+		{Namespace: "apps", Enforce: conf.PodSecurityLevelBaseline, Recommended: conf.PodSecurityLevelBaseline, Workloads: 2},
+		{Namespace: "empty", Recommended: conf.PodSecurityLevelRestricted},
+		{Namespace: "tools", Recommended: conf.PodSecurityLevelPrivileged, Workloads: 1},
+	}, auditData.PodSecurity)
+	assert.Contains(t, auditData.GetPrettyOutput(false), "(no namespace): can enforce baseline (currently no profile), 1 workload(s)")
+}
+
+func TestPodSecurityStandardsDisabled(t *testing.T) {
+	c := conf.Configuration{
+		Checks: map[string]conf.Severity{
+			"hostNetworkSet": conf.SeverityDanger,
+		},
+	}
+	provider, err := kube.CreateResourceProviderFromYaml(podSecurityTestResources)
+	assert.NoError(t, err)
+	auditData, err := RunAudit(c, provider)
+	assert.NoError(t, err)
+	for _, result := range auditData.Results {
+		assert.Equal(t, conf.PodSecurityLevel(""), result.PodSecurityLevel)
+	}
+	assert.Nil(t, auditData.PodSecurity)
+}
+
+func TestPodSecurityControlDetails(t *testing.T) {
+	provider, err := kube.CreateResourceProviderFromYaml(podSecurityTestResources)
+	assert.NoError(t, err)
+	var pod kube.GenericResource
+	for _, resource := range provider.Resources["Pod"] {
+		pod = resource
+	}
+	test := schemaTestCase{Resource: pod}
+
+	passes, issues, err := validatorMapper["pssCapabilitiesRestricted"](test)
+	assert.NoError(t, err)
+	assert.False(t, passes)
+	messages := []string{}
+	for _, issue := range issues {
+		messages = append(messages, issue.Message)
+	}
+	assert.Equal(t, []string{
+		`container "shell" must set securityContext.capabilities.drop=["ALL"]`,
+		`container "shell" adds capabilities NET_ADMIN`,
+	}, messages)
+
+	passes, _, err = validatorMapper["pssPrivilegedContainers"](test)
+	assert.NoError(t, err)
+	assert.True(t, passes)
+}
@@ -0,0 +1,395 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/qri-io/jsonschema"
+	"github.com/thoas/go-funk"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// podSecurityControl returns a description of every violation of a Pod Security Standards control
+type podSecurityControl func(podSpec *corev1.PodSpec, annotations map[string]string) []string
+
+const appArmorAnnotationPrefix = "container.apparmor.security.beta.kubernetes.io/"
+
+var (
+	baselineCapabilities = []string{
+		"AUDIT_WRITE", "CHOWN", "DAC_OVERRIDE", "FOWNER", "FSETID", "KILL", "MKNOD",
+		"NET_BIND_SERVICE", "SETFCAP", "SETGID", "SETPCAP", "SETUID", "SYS_CHROOT",
+	}
+	seLinuxTypes = []string{"", "container_t", "container_init_t", "container_kvm_t", "container_engine_t"}
+	safeSysctls  = []string{
+		"kernel.shm_rmid_forced",
+		"net.ipv4.ip_local_port_range",
+		"net.ipv4.ip_local_reserved_ports",
+		"net.ipv4.ip_unprivileged_port_start",
+		"net.ipv4.ping_group_range",
+		"net.ipv4.tcp_fin_timeout",
+		"net.ipv4.tcp_keepalive_intvl",
+		"net.ipv4.tcp_keepalive_probes",
+		"net.ipv4.tcp_keepalive_time",
+		"net.ipv4.tcp_syncookies",
+	}
+)
+
+func init() {
+	controls := map[string]podSecurityControl{
+		"pssHostProcess":            checkHostProcess,
+		"pssHostNamespaces":         checkHostNamespaces,
+		"pssPrivilegedContainers":   checkPrivilegedContainers,
+		"pssCapabilities":           checkBaselineCapabilities,
+		"pssHostPathVolumes":        checkHostPathVolumes,
+		"pssHostPorts":              checkHostPorts,
+		"pssAppArmor":               checkAppArmor,
+		"pssSELinux":                checkSELinux,
+		"pssProcMountType":          checkProcMountType,
+		"pssSeccomp":                checkBaselineSeccomp,
+		"pssSysctls":                checkSysctls,
+		"pssVolumeTypes":            checkVolumeTypes,
+		"pssPrivilegeEscalation":    checkPrivilegeEscalation,
+		"pssRunAsNonRoot":           checkRunAsNonRoot,
+		"pssRunAsUser":              checkRunAsUser,
+		"pssSeccompRestricted":      checkRestrictedSeccomp,
+		"pssCapabilitiesRestricted": checkRestrictedCapabilities,
+	}
+	for checkID, control := range controls {
+		registerCustomChecks(checkID, validatePodSecurityControl(control))
+	}
+}
+
+func validatePodSecurityControl(control podSecurityControl) validatorFunction {
+	return func(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+		if test.Resource.PodSpec == nil {
+			return true, nil, nil
+		}
+		var annotations map[string]string
+		if podTemplate, ok := test.Resource.PodTemplate.(map[string]interface{}); ok {
+			annotations, _, _ = unstructured.NestedStringMap(podTemplate, "metadata", "annotations")
+		}
+		violations := control(test.Resource.PodSpec, annotations)
+		issues := make([]jsonschema.ValError, len(violations))
+		for idx, violation := range violations {
+			issues[idx] = jsonschema.ValError{Message: violation}
+		}
+		return len(issues) == 0, issues, nil
+	}
+}
+
+// getAllContainers returns the init, regular and ephemeral containers of a pod spec
+func getAllContainers(podSpec *corev1.PodSpec) []corev1.Container {
+	containers := []corev1.Container{}
+	containers = append(containers, podSpec.InitContainers...)
+	containers = append(containers, podSpec.Containers...)
+	for _, ephemeralContainer := range podSpec.EphemeralContainers {
+		containers = append(containers, corev1.Container(ephemeralContainer.EphemeralContainerCommon))
+	}
+	return containers
+}
+
+func checkHostProcess(podSpec *corev1.PodSpec, _ map[string]string) []string {
+	violations := []string{}
+	if sc := podSpec.SecurityContext; sc != nil && sc.WindowsOptions != nil && isTrue(sc.WindowsOptions.HostProcess) {
+		violations = append(violations, "pod sets securityContext.windowsOptions.hostProcess=true")
+	}
+	for _, container := range getAllContainers(podSpec) {
+		if sc := container.SecurityContext; sc != nil && sc.WindowsOptions != nil && isTrue(sc.WindowsOptions.HostProcess) {
+			violations = append(violations, fmt.Sprintf("container %q sets securityContext.windowsOptions.hostProcess=true", container.Name))
+		}
+	}
+	return violations
+}
+
+func checkHostNamespaces(podSpec *corev1.PodSpec, _ map[string]string) []string {
+	violations := []string{}
+	if podSpec.HostNetwork {
+		violations = append(violations, "pod sets hostNetwork=true")
+	}
+	if podSpec.HostPID {
+		violations = append(violations, "pod sets hostPID=true")
+	}
+	if podSpec.HostIPC {
+		violations = append(violations, "pod sets hostIPC=true")
+	}
+	return violations
+}
+
+func checkPrivilegedContainers(podSpec *corev1.PodSpec, _ map[string]string) []string {
+	violations := []string{}
+	for _, container := range getAllContainers(podSpec) {
+		if container.SecurityContext != nil && isTrue(container.SecurityContext.Privileged) {
+			violations = append(violations, fmt.Sprintf("container %q sets securityContext.privileged=true", container.Name))
+		}
+	}
+	return violations
+}
+
+func checkBaselineCapabilities(podSpec *corev1.PodSpec, _ map[string]string) []string {
+	violations := []string{}
+	for _, container := range getAllContainers(podSpec) {
+		disallowed := []string{}
+		for _, capability := range getAddedCapabilities(container) {
+			if !funk.ContainsString(baselineCapabilities, capability) {
+				disallowed = append(disallowed, capability)
+			}
+		}
+		if len(disallowed) > 0 {
+			violations = append(violations, fmt.Sprintf("container %q adds capabilities %s", container.Name, strings.Join(disallowed, ", ")))
+		}
+	}
+	return violations
+}
+
+func checkHostPathVolumes(podSpec *corev1.PodSpec, _ map[string]string) []string {
+	violations := []string{}
+	for _, volume := range podSpec.Volumes {
+		if volume.HostPath != nil {
+			violations = append(violations, fmt.Sprintf("volume %q uses hostPath %s", volume.Name, volume.HostPath.Path))
+		}
+	}
+	return violations
+}
+
+func checkHostPorts(podSpec *corev1.PodSpec, _ map[string]string) []string {
+	violations := []string{}
+	for _, container := range getAllContainers(podSpec) {
+		for _, port := range container.Ports {
+			if port.HostPort != 0 {
+				violations = append(violations, fmt.Sprintf("container %q uses hostPort %d", container.Name, port.HostPort))
+			}
+		}
+	}
+	return violations
+}
+
+func checkAppArmor(podSpec *corev1.PodSpec, annotations map[string]string) []string {
+	violations := []string{}
+	keys := []string{}
+	for key := range annotations {
+		if strings.HasPrefix(key, appArmorAnnotationPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := annotations[key]
+		if value != corev1.DeprecatedAppArmorBetaProfileRuntimeDefault && !strings.HasPrefix(value, corev1.DeprecatedAppArmorBetaProfileNamePrefix) {
+			violations = append(violations, fmt.Sprintf("annotation %s=%s is not allowed", key, value))
+		}
+	}
+	if sc := podSpec.SecurityContext; sc != nil && sc.AppArmorProfile != nil && sc.AppArmorProfile.Type == corev1.AppArmorProfileTypeUnconfined {
+		violations = append(violations, "pod sets securityContext.appArmorProfile.type=Unconfined")
+	}
+	for _, container := range getAllContainers(podSpec) {
+		if sc := container.SecurityContext; sc != nil && sc.AppArmorProfile != nil && sc.AppArmorProfile.Type == corev1.AppArmorProfileTypeUnconfined {
+			violations = append(violations, fmt.Sprintf("container %q sets securityContext.appArmorProfile.type=Unconfined", container.Name))
+		}
+	}
+	return violations
+}
+
+func checkSELinux(podSpec *corev1.PodSpec, _ map[string]string) []string {
+	violations := []string{}
+	check := func(owner string, options *corev1.SELinuxOptions) {
+		if options == nil {
+			return
+		}
+		if !funk.ContainsString(seLinuxTypes, options.Type) {
+			violations = append(violations, fmt.Sprintf("%s sets securityContext.seLinuxOptions.type=%s", owner, options.Type))
+		}
+		if options.User != "" {
+			violations = append(violations, fmt.Sprintf("%s sets securityContext.seLinuxOptions.user", owner))
+		}
+		if options.Role != "" {
+			violations = append(violations, fmt.Sprintf("%s sets securityContext.seLinuxOptions.role", owner))
+		}
+	}
+	if podSpec.SecurityContext != nil {
+		check("pod", podSpec.SecurityContext.SELinuxOptions)
+	}
+	for _, container := range getAllContainers(podSpec) {
+		if container.SecurityContext != nil {
+			check(fmt.Sprintf("container %q", container.Name), container.SecurityContext.SELinuxOptions)
+		}
+	}
+	return violations
+}
+
+func checkProcMountType(podSpec *corev1.PodSpec, _ map[string]string) []string {
+	violations := []string{}
+	for _, container := range getAllContainers(podSpec) {
+		if sc := container.SecurityContext; sc != nil && sc.ProcMount != nil && *sc.ProcMount != corev1.DefaultProcMount {
+			violations = append(violations, fmt.Sprintf("container %q sets securityContext.procMount=%s", container.Name, *sc.ProcMount))
+		}
+	}
+	return violations
+}
+
+func checkBaselineSeccomp(podSpec *corev1.PodSpec, _ map[string]string) []string {
+	violations := []string{}
+	if sc := podSpec.SecurityContext; sc != nil && sc.SeccompProfile != nil && sc.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+		violations = append(violations, "pod sets securityContext.seccompProfile.type=Unconfined")
+	}
+	for _, container := range getAllContainers(podSpec) {
+		if sc := container.SecurityContext; sc != nil && sc.SeccompProfile != nil && sc.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+			violations = append(violations, fmt.Sprintf("container %q sets securityContext.seccompProfile.type=Unconfined", container.Name))
+		}
+	}
+	return violations
+}
+
+func checkSysctls(podSpec *corev1.PodSpec, _ map[string]string) []string {
+	violations := []string{}
+	if podSpec.SecurityContext == nil {
+		return violations
+	}
+	for _, sysctl := range podSpec.SecurityContext.Sysctls {
+		if !funk.ContainsString(safeSysctls, sysctl.Name) {
+			violations = append(violations, fmt.Sprintf("pod sets sysctl %s", sysctl.Name))
+		}
+	}
+	return violations
+}
+
+func checkVolumeTypes(podSpec *corev1.PodSpec, _ map[string]string) []string {
+	violations := []string{}
+	for _, volume := range podSpec.Volumes {
+		source := volume.VolumeSource
+		switch {
+		case source.ConfigMap != nil, source.CSI != nil, source.DownwardAPI != nil, source.EmptyDir != nil,
+			source.Ephemeral != nil, source.PersistentVolumeClaim != nil, source.Projected != nil, source.Secret != nil:
+			continue
+		}
+		violations = append(violations, fmt.Sprintf("volume %q uses a restricted volume type", volume.Name))
+	}
+	return violations
+}
+
+func checkPrivilegeEscalation(podSpec *corev1.PodSpec, _ map[string]string) []string {
+	violations := []string{}
+	for _, container := range getAllContainers(podSpec) {
+		if sc := container.SecurityContext; sc == nil || sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation {
+			violations = append(violations, fmt.Sprintf("container %q must set securityContext.allowPrivilegeEscalation=false", container.Name))
+		}
+	}
+	return violations
+}
+
+func checkRunAsNonRoot(podSpec *corev1.PodSpec, _ map[string]string) []string {
+	violations := []string{}
+	podRunAsNonRoot := false
+	if sc := podSpec.SecurityContext; sc != nil && sc.RunAsNonRoot != nil {
+		if !*sc.RunAsNonRoot {
+			violations = append(violations, "pod sets securityContext.runAsNonRoot=false")
+		}
+		podRunAsNonRoot = *sc.RunAsNonRoot
+	}
+	for _, container := range getAllContainers(podSpec) {
+		sc := container.SecurityContext
+		if sc != nil && sc.RunAsNonRoot != nil {
+			if !*sc.RunAsNonRoot {
+				violations = append(violations, fmt.Sprintf("container %q sets securityContext.runAsNonRoot=false", container.Name))
+			}
+		} else if !podRunAsNonRoot {
+			violations = append(violations, fmt.Sprintf("pod or container %q must set securityContext.runAsNonRoot=true", container.Name))
+		}
+	}
+	return violations
+}
+
+func checkRunAsUser(podSpec *corev1.PodSpec, _ map[string]string) []string {
+	violations := []string{}
+	if sc := podSpec.SecurityContext; sc != nil && sc.RunAsUser != nil && *sc.RunAsUser == 0 {
+		violations = append(violations, "pod sets securityContext.runAsUser=0")
+	}
+	for _, container := range getAllContainers(podSpec) {
+		if sc := container.SecurityContext; sc != nil && sc.RunAsUser != nil && *sc.RunAsUser == 0 {
+			violations = append(violations, fmt.Sprintf("container %q sets securityContext.runAsUser=0", container.Name))
+		}
+	}
+	return violations
+}
+
+func checkRestrictedSeccomp(podSpec *corev1.PodSpec, _ map[string]string) []string {
+	violations := []string{}
+	isAllowed := func(profile *corev1.SeccompProfile) bool {
+		return profile.Type == corev1.SeccompProfileTypeRuntimeDefault || profile.Type == corev1.SeccompProfileTypeLocalhost
+	}
+	podSeccomp := false
+	if sc := podSpec.SecurityContext; sc != nil && sc.SeccompProfile != nil {
+		if !isAllowed(sc.SeccompProfile) {
+			violations = append(violations, fmt.Sprintf("pod sets securityContext.seccompProfile.type=%s", sc.SeccompProfile.Type))
+		}
+		podSeccomp = true
+	}
+	for _, container := range getAllContainers(podSpec) {
+		sc := container.SecurityContext
+		if sc != nil && sc.SeccompProfile != nil {
+			if !isAllowed(sc.SeccompProfile) {
+				violations = append(violations, fmt.Sprintf("container %q sets securityContext.seccompProfile.type=%s", container.Name, sc.SeccompProfile.Type))
+			}
+		} else if !podSeccomp {
+			violations = append(violations, fmt.Sprintf("pod or container %q must set securityContext.seccompProfile.type to RuntimeDefault or Localhost", container.Name))
+		}
+	}
+	return violations
+}
+
+func checkRestrictedCapabilities(podSpec *corev1.PodSpec, _ map[string]string) []string {
+	violations := []string{}
+	for _, container := range getAllContainers(podSpec) {
+		dropsAll := false
+		if sc := container.SecurityContext; sc != nil && sc.Capabilities != nil {
+			for _, capability := range sc.Capabilities.Drop {
+				if string(capability) == "ALL" {
+					dropsAll = true
+				}
+			}
+		}
+		if !dropsAll {
+			violations = append(violations, fmt.Sprintf("container %q must set securityContext.capabilities.drop=[\"ALL\"]", container.Name))
+		}
+		disallowed := []string{}
+		for _, capability := range getAddedCapabilities(container) {
+			if capability != "NET_BIND_SERVICE" {
+				disallowed = append(disallowed, capability)
+			}
+		}
+		if len(disallowed) > 0 {
+			violations = append(violations, fmt.Sprintf("container %q adds capabilities %s", container.Name, strings.Join(disallowed, ", ")))
+		}
+	}
+	return violations
+}
+
+func getAddedCapabilities(container corev1.Container) []string {
+	capabilities := []string{}
+	if container.SecurityContext == nil || container.SecurityContext.Capabilities == nil {
+		return capabilities
+	}
+	for _, capability := range container.SecurityContext.Capabilities.Add {
+		capabilities = append(capabilities, string(capability))
+	}
+	return capabilities
+}
+
+func isTrue(value *bool) bool {
+	return value != nil && *value
+}
@@ -0,0 +1,514 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/fairwindsops/polaris/pkg/config"
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+const (
+	roleKind               = "rbac.authorization.k8s.io/Role"
+	clusterRoleKind        = "rbac.authorization.k8s.io/ClusterRole"
+	roleBindingKind        = "rbac.authorization.k8s.io/RoleBinding"
+	clusterRoleBindingKind = "rbac.authorization.k8s.io/ClusterRoleBinding"
+	// bootstrapLabel marks the default roles and bindings that Kubernetes creates and keeps up to date,
+	// with the value rbac-defaults
+	bootstrapLabel      = "kubernetes.io/bootstrapping"
+	bootstrapLabelValue = "rbac-defaults"
+	// ServiceAccounts are authenticated as system:serviceaccount:<namespace>:<name>, and belong to the
+	// system:serviceaccounts, system:serviceaccounts:<namespace> and system:authenticated groups
+	serviceAccountUserPrefix = "system:serviceaccount:"
+	serviceAccountsGroup     = "system:serviceaccounts"
+	authenticatedGroup       = "system:authenticated"
+)
+
+// RBACEscalationType is a way for a subject to gain more privileges than it was granted
+type RBACEscalationType string
+
+const (
+	// RBACEscalationWildcard is full access to every resource
+	RBACEscalationWildcard RBACEscalationType = "wildcard"
+	// RBACEscalationBind allows binding roles with more permissions than the subject has
+	RBACEscalationBind RBACEscalationType = "bind"
+	// RBACEscalationEscalate allows adding permissions the subject doesn't have to roles
+	RBACEscalationEscalate RBACEscalationType = "escalate"
+	// RBACEscalationImpersonate allows acting as other users, groups or ServiceAccounts
+	RBACEscalationImpersonate RBACEscalationType = "impersonate"
+	// RBACEscalationSecrets allows reading secrets in kube-system, which include powerful tokens
+	RBACEscalationSecrets RBACEscalationType = "secrets"
+	// RBACEscalationNodesProxy gives access to the kubelet API, and to every pod on the nodes
+	RBACEscalationNodesProxy RBACEscalationType = "nodesProxy"
+	// RBACEscalationCreatePods allows running pods as a ServiceAccount that can escalate privileges
+	RBACEscalationCreatePods RBACEscalationType = "createPods"
+)
+
+// RBACSubject is a user, group or ServiceAccount, along with the permissions granted by its bindings
+type RBACSubject struct {
+	Kind      string
+	Namespace string
+	Name      string
+	// Permissions are the rules granted to the subject, with aggregated ClusterRoles resolved
+	Permissions []RBACPermission
+	// Escalations are the ways the subject can gain more privileges
+	Escalations []RBACEscalation
+	// Workloads are the workloads running as the subject, if it's a ServiceAccount
+	Workloads []string
+}
+
+// RBACPermission is a rule granted to a subject by a binding
+type RBACPermission struct {
+	// Namespace is empty for rules granted cluster-wide by a ClusterRoleBinding
+	Namespace string
+	Binding   string
+	Role      string
+	Rule      rbacv1.PolicyRule
+}
+
+// RBACEscalation is an escalation path available to a subject because of a binding
+type RBACEscalation struct {
+	Type    RBACEscalationType
+	Message string
+	Binding string
+}
+
+func (subject RBACSubject) String() string {
+	if subject.Namespace == "" {
+		return subject.Kind + " " + subject.Name
+	}
+	return fmt.Sprintf("%s %s/%s", subject.Kind, subject.Namespace, subject.Name)
+}
+
+func (subject RBACSubject) isEscalating() bool {
+	return len(subject.Escalations) > 0
+}
+
+// rbacGrant is a role granted to subjects by a binding
+type rbacGrant struct {
+	namespace string
+	binding   string
+	role      string
+	rules     []rbacv1.PolicyRule
+	subjects  []rbacv1.Subject
+}
+
+func getRBACResourceName(kind, namespace, name string) string {
+	if namespace == "" {
+		return kind + " " + name
+	}
+	return fmt.Sprintf("%s %s/%s", kind, namespace, name)
+}
+
+// isBootstrapBinding returns true if the binding is one of the defaults created by Kubernetes
+func isBootstrapBinding(meta metav1.ObjectMeta) bool {
+	return meta.Labels[bootstrapLabel] == bootstrapLabelValue
+}
+
+// fromUnstructured converts a resource to a typed object, logging a warning if it fails
+func fromUnstructured(generic kube.GenericResource, obj interface{}) bool {
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(generic.Resource.Object, obj)
+	if err != nil {
+		logrus.Warnf("error converting unstructured to %s: %v", generic.Kind, err)
+		return false
+	}
+	return true
+}
+
+// getClusterRoleRules resolves the rules of each ClusterRole, including the rules of the ClusterRoles it aggregates
+func getClusterRoleRules(clusterRoles []rbacv1.ClusterRole) map[string][]rbacv1.PolicyRule {
+	byName := map[string]rbacv1.ClusterRole{}
+	for _, clusterRole := range clusterRoles {
+		byName[clusterRole.Name] = clusterRole
+	}
+	var resolve func(name string, visited map[string]bool) []rbacv1.PolicyRule
+	resolve = func(name string, visited map[string]bool) []rbacv1.PolicyRule {
+		visited[name] = true
+		clusterRole := byName[name]
+		rules := append([]rbacv1.PolicyRule{}, clusterRole.Rules...)
+		if clusterRole.AggregationRule == nil {
+			return rules
+		}
+		for _, labelSelector := range clusterRole.AggregationRule.ClusterRoleSelectors {
+			selector, err := metav1.LabelSelectorAsSelector(&labelSelector)
+			if err != nil {
+				logrus.Warnf("invalid aggregation rule in ClusterRole %s: %v", name, err)
+				continue
+			}
+			for _, aggregated := range clusterRoles {
+				if visited[aggregated.Name] || !selector.Matches(labels.Set(aggregated.Labels)) {
+					continue
+				}
+				rules = append(rules, resolve(aggregated.Name, visited)...)
+			}
+		}
+		return rules
+	}
+	rules := map[string][]rbacv1.PolicyRule{}
+	for name := range byName {
+		rules[name] = resolve(name, map[string]bool{})
+	}
+	return rules
+}
+
+func getRBACGrants(provider *kube.ResourceProvider) []rbacGrant {
+	clusterRoles := []rbacv1.ClusterRole{}
+	for _, generic := range provider.Resources[clusterRoleKind] {
+		clusterRole := rbacv1.ClusterRole{}
+		if fromUnstructured(generic, &clusterRole) {
+			clusterRoles = append(clusterRoles, clusterRole)
+		}
+	}
+	clusterRoleRules := getClusterRoleRules(clusterRoles)
+	roleRules := map[string][]rbacv1.PolicyRule{}
+	for _, generic := range provider.Resources[roleKind] {
+		role := rbacv1.Role{}
+		if fromUnstructured(generic, &role) {
+			roleRules[role.Namespace+"/"+role.Name] = role.Rules
+		}
+	}
+
+	grants := []rbacGrant{}
+	for _, generic := range provider.Resources[clusterRoleBindingKind] {
+		binding := rbacv1.ClusterRoleBinding{}
+		if !fromUnstructured(generic, &binding) || isBootstrapBinding(binding.ObjectMeta) {
+			continue
+		}
+		rules, ok := clusterRoleRules[binding.RoleRef.Name]
+		if !ok {
+			continue
+		}
+		grants = append(grants, rbacGrant{
+			binding:  getRBACResourceName("ClusterRoleBinding", "", binding.Name),
+			role:     getRBACResourceName("ClusterRole", "", binding.RoleRef.Name),
+			rules:    rules,
+			subjects: binding.Subjects,
+		})
+	}
+	for _, generic := range provider.Resources[roleBindingKind] {
+		binding := rbacv1.RoleBinding{}
+		if !fromUnstructured(generic, &binding) || isBootstrapBinding(binding.ObjectMeta) {
+			continue
+		}
+		grant := rbacGrant{
+			namespace: binding.Namespace,
+			binding:   getRBACResourceName("RoleBinding", binding.Namespace, binding.Name),
+			subjects:  binding.Subjects,
+		}
+		var ok bool
+		if binding.RoleRef.Kind == "ClusterRole" {
+			grant.role = getRBACResourceName("ClusterRole", "", binding.RoleRef.Name)
+			grant.rules, ok = clusterRoleRules[binding.RoleRef.Name]
+		} else {
+			grant.role = getRBACResourceName("Role", binding.Namespace, binding.RoleRef.Name)
+			grant.rules, ok = roleRules[binding.Namespace+"/"+binding.RoleRef.Name]
+		}
+		if ok {
+			grants = append(grants, grant)
+		}
+	}
+	return grants
+}
+
+// matchesRuleValue returns true if a list of verbs, API groups or resources of a rule includes the value
+func matchesRuleValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == rbacv1.VerbAll || v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesRuleResource returns true if the resources of a rule include the resource, which may have a subresource
+func matchesRuleResource(resources []string, resource string) bool {
+	if matchesRuleValue(resources, resource) {
+		return true
+	}
+	parts := strings.SplitN(resource, "/", 2)
+	if len(parts) < 2 {
+		return false
+	}
+	return matchesRuleValue(resources, parts[0]+"/*") || matchesRuleValue(resources, "*/"+parts[1])
+}
+
+func ruleAllows(rule rbacv1.PolicyRule, verb, apiGroup, resource string) bool {
+	return matchesRuleValue(rule.Verbs, verb) && matchesRuleValue(rule.APIGroups, apiGroup) && matchesRuleResource(rule.Resources, resource)
+}
+
+func rulesAllow(rules []rbacv1.PolicyRule, verbs []string, apiGroup string, resources ...string) bool {
+	for _, rule := range rules {
+		for _, verb := range verbs {
+			for _, resource := range resources {
+				if ruleAllows(rule, verb, apiGroup, resource) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func getRBACScope(namespace string) string {
+	if namespace == "" {
+		return "cluster-wide"
+	}
+	return "in namespace " + namespace
+}
+
+func (grant rbacGrant) scope() string {
+	return getRBACScope(grant.namespace)
+}
+
+func (permission RBACPermission) scope() string {
+	return getRBACScope(permission.Namespace)
+}
+
+func (grant rbacGrant) coversNamespace(namespace string) bool {
+	return grant.namespace == "" || grant.namespace == namespace
+}
+
+// getEscalations returns the escalation paths a grant opens by itself
+func (grant rbacGrant) getEscalations() []RBACEscalation {
+	escalations := []RBACEscalation{}
+	add := func(escalationType RBACEscalationType, message string) {
+		escalations = append(escalations, RBACEscalation{Type: escalationType, Message: message, Binding: grant.binding})
+	}
+	if rulesAllow(grant.rules, []string{"*"}, "*", "*") {
+		add(RBACEscalationWildcard, fmt.Sprintf("has full access to all resources %s", grant.scope()))
+		return escalations
+	}
+	if rulesAllow(grant.rules, []string{"bind"}, rbacv1.GroupName, "roles", "clusterroles") {
+		add(RBACEscalationBind, fmt.Sprintf("can bind roles %s", grant.scope()))
+	}
+	if rulesAllow(grant.rules, []string{"escalate"}, rbacv1.GroupName, "roles", "clusterroles") {
+		add(RBACEscalationEscalate, fmt.Sprintf("can escalate roles %s", grant.scope()))
+	}
+	if rulesAllow(grant.rules, []string{"impersonate"}, "", "users", "groups", "serviceaccounts") {
+		add(RBACEscalationImpersonate, fmt.Sprintf("can impersonate users, groups or ServiceAccounts %s", grant.scope()))
+	}
+	if (grant.namespace == "" || grant.namespace == "kube-system") && rulesAllow(grant.rules, []string{"get", "list", "watch"}, "", "secrets") {
+		add(RBACEscalationSecrets, "can read secrets in kube-system")
+	}
+	if rulesAllow(grant.rules, []string{"get", "create"}, "", "nodes/proxy") {
+		add(RBACEscalationNodesProxy, "can access nodes/proxy, which gives access to the kubelet API")
+	}
+	return escalations
+}
+
+func getSubjectKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// getServiceAccountIdentities returns the keys of the user and groups a ServiceAccount is authenticated as
+func getServiceAccountIdentities(namespace, name string) []string {
+	return []string{
+		getSubjectKey(rbacv1.UserKind, "", serviceAccountUserPrefix+namespace+":"+name),
+		getSubjectKey(rbacv1.GroupKind, "", serviceAccountsGroup),
+		getSubjectKey(rbacv1.GroupKind, "", serviceAccountsGroup+":"+namespace),
+		getSubjectKey(rbacv1.GroupKind, "", authenticatedGroup),
+	}
+}
+
+// getServiceAccounts returns the namespace and name of the ServiceAccounts that are bound, defined, or used by
+// workloads, sorted
+func getServiceAccounts(provider *kube.ResourceProvider, subjects map[string]*RBACSubject) [][2]string {
+	found := map[[2]string]bool{}
+	for _, subject := range subjects {
+		if subject.Kind == rbacv1.ServiceAccountKind {
+			found[[2]string{subject.Namespace, subject.Name}] = true
+		}
+	}
+	for _, serviceAccount := range provider.Resources["ServiceAccount"] {
+		found[[2]string{serviceAccount.ObjectMeta.GetNamespace(), serviceAccount.ObjectMeta.GetName()}] = true
+	}
+	for _, resources := range provider.Resources {
+		for _, resource := range resources {
+			if resource.PodSpec != nil {
+				found[[2]string{resource.ObjectMeta.GetNamespace(), getServiceAccountName(resource)}] = true
+			}
+		}
+	}
+	serviceAccounts := make([][2]string, 0, len(found))
+	for serviceAccount := range found {
+		serviceAccounts = append(serviceAccounts, serviceAccount)
+	}
+	sort.Slice(serviceAccounts, func(i, j int) bool {
+		if serviceAccounts[i][0] != serviceAccounts[j][0] {
+			return serviceAccounts[i][0] < serviceAccounts[j][0]
+		}
+		return serviceAccounts[i][1] < serviceAccounts[j][1]
+	})
+	return serviceAccounts
+}
+
+// analyzeRBAC resolves the bindings of a ResourceProvider into the effective permissions and escalation
+// paths of each subject. The default bindings of Kubernetes are skipped. ServiceAccounts also get the
+// roles bound to their username and to their groups, like system:serviceaccounts.
+func analyzeRBAC(provider *kube.ResourceProvider) []RBACSubject {
+	grants := getRBACGrants(provider)
+	subjects := map[string]*RBACSubject{}
+	subjectGrants := map[string][]rbacGrant{}
+	addGrant := func(kind, namespace, name string, grant rbacGrant) {
+		key := getSubjectKey(kind, namespace, name)
+		for _, existing := range subjectGrants[key] {
+			if existing.binding == grant.binding {
+				return
+			}
+		}
+		subject, ok := subjects[key]
+		if !ok {
+			subject = &RBACSubject{
+				Kind:        kind,
+				Namespace:   namespace,
+				Name:        name,
+				Permissions: []RBACPermission{},
+				Escalations: []RBACEscalation{},
+				Workloads:   []string{},
+			}
+			subjects[key] = subject
+		}
+		for _, rule := range grant.rules {
+			subject.Permissions = append(subject.Permissions, RBACPermission{
+				Namespace: grant.namespace,
+				Binding:   grant.binding,
+				Role:      grant.role,
+				Rule:      rule,
+			})
+		}
+		subject.Escalations = append(subject.Escalations, grant.getEscalations()...)
+		subjectGrants[key] = append(subjectGrants[key], grant)
+	}
+	for _, grant := range grants {
+		for _, s := range grant.subjects {
+			namespace := ""
+			if s.Kind == rbacv1.ServiceAccountKind {
+				namespace = s.Namespace
+				if namespace == "" {
+					namespace = grant.namespace
+				}
+			}
+			addGrant(s.Kind, namespace, s.Name, grant)
+		}
+	}
+	for _, serviceAccount := range getServiceAccounts(provider, subjects) {
+		namespace, name := serviceAccount[0], serviceAccount[1]
+		for _, identity := range getServiceAccountIdentities(namespace, name) {
+			for _, grant := range subjectGrants[identity] {
+				addGrant(rbacv1.ServiceAccountKind, namespace, name, grant)
+			}
+		}
+	}
+
+	// Creating pods allows running them as any ServiceAccount in the namespace
+	escalatingServiceAccounts := []RBACSubject{}
+	for _, subject := range subjects {
+		if subject.Kind == rbacv1.ServiceAccountKind && subject.isEscalating() {
+			escalatingServiceAccounts = append(escalatingServiceAccounts, *subject)
+		}
+	}
+	sortRBACSubjects(escalatingServiceAccounts)
+	for key, subject := range subjects {
+		for _, grant := range subjectGrants[key] {
+			if !rulesAllow(grant.rules, []string{"create"}, "", "pods") {
+				continue
+			}
+			for _, serviceAccount := range escalatingServiceAccounts {
+				if serviceAccount.Namespace == subject.Namespace && serviceAccount.Name == subject.Name || !grant.coversNamespace(serviceAccount.Namespace) {
+					continue
+				}
+				subject.Escalations = append(subject.Escalations, RBACEscalation{
+					Type:    RBACEscalationCreatePods,
+					Message: fmt.Sprintf("can create pods %s and run them as %s", grant.scope(), serviceAccount.String()),
+					Binding: grant.binding,
+				})
+			}
+		}
+	}
+
+	for _, resources := range provider.Resources {
+		for _, resource := range resources {
+			if resource.PodSpec == nil {
+				continue
+			}
+			serviceAccountName := getServiceAccountName(resource)
+			for _, subject := range subjects {
+				if subject.Kind == rbacv1.ServiceAccountKind && subject.Name == serviceAccountName && subject.Namespace == resource.ObjectMeta.GetNamespace() {
+					subject.Workloads = append(subject.Workloads, getRBACResourceName(resource.Kind, resource.ObjectMeta.GetNamespace(), resource.ObjectMeta.GetName()))
+				}
+			}
+		}
+	}
+
+	report := []RBACSubject{}
+	for _, subject := range subjects {
+		sort.Strings(subject.Workloads)
+		report = append(report, *subject)
+	}
+	sortRBACSubjects(report)
+	return report
+}
+
+// getRBACSubjects returns the RBAC analysis of the audited resources, running it only once per audit
+func (audit *auditContext) getRBACSubjects() []RBACSubject {
+	if audit.getProvider() == nil {
+		return nil
+	}
+	audit.rbacSubjectsOnce.Do(func() {
+		audit.rbacSubjects = analyzeRBAC(audit.provider)
+	})
+	return audit.rbacSubjects
+}
+
+// getServiceAccountSubject returns the RBAC analysis of a ServiceAccount, or nil if it isn't bound to any role
+func (audit *auditContext) getServiceAccountSubject(namespace, name string) *RBACSubject {
+	for _, subject := range audit.getRBACSubjects() {
+		if subject.Kind == rbacv1.ServiceAccountKind && subject.Name == name && subject.Namespace == namespace {
+			return &subject
+		}
+	}
+	return nil
+}
+
+func sortRBACSubjects(subjects []RBACSubject) {
+	sort.Slice(subjects, func(i, j int) bool {
+		if subjects[i].Kind != subjects[j].Kind {
+			return subjects[i].Kind < subjects[j].Kind
+		}
+		if subjects[i].Namespace != subjects[j].Namespace {
+			return subjects[i].Namespace < subjects[j].Namespace
+		}
+		return subjects[i].Name < subjects[j].Name
+	})
+}
+
+// getRBACReport returns the effective permissions of every subject, if one of the RBAC escalation checks is enabled
+func getRBACReport(conf *config.Configuration, audit *auditContext) []RBACSubject {
+	_, clusterRoleBindingCheck := conf.Checks["clusterrolebindingEscalation"]
+	_, roleBindingCheck := conf.Checks["rolebindingEscalation"]
+	if !clusterRoleBindingCheck && !roleBindingCheck {
+		return nil
+	}
+	return audit.getRBACSubjects()
+}
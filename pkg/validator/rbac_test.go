@@ -0,0 +1,449 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	conf "github.com/fairwindsops/polaris/pkg/config"
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+const rbacTestResources = `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: operator
+aggregationRule:
+  clusterRoleSelectors:
+    - matchLabels:
+        rbac.example.com/aggregate-to-operator: "true"
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: operator-rbac
+  labels:
+    rbac.example.com/aggregate-to-operator: "true"
+rules:
+  - apiGroups: ["rbac.authorization.k8s.io"]
+    resources: ["clusterroles"]
+    verbs: ["bind", "escalate"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: operator
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: operator
+subjects:
+  - kind: ServiceAccount
+    name: operator
+    namespace: apps
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: deployer
+  namespace: apps
+rules:
+  - apiGroups: [""]
+    resources: ["pods"]
+    verbs: ["create", "delete"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: deployer
+  namespace: apps
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: deployer
+subjects:
+  - kind: Group
+    name: developers
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: system:operator
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: operator
+subjects:
+  - kind: Group
+    name: admins
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: system:controller:operator
+  labels:
+    kubernetes.io/bootstrapping: rbac-defaults
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: operator
+subjects:
+  - kind: User
+    name: system:kube-controller-manager
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: operator
+  namespace: apps
+spec:
+  selector:
+    matchLabels:
+      app: operator
+  template:
+    metadata:
+      labels:
+        app: operator
+    spec:
+      serviceAccountName: operator
+      containers:
+        - name: operator
+          image: operator:1.0
+`
+
+func TestAnalyzeRBAC(t *testing.T) {
+	provider, err := kube.CreateResourceProviderFromYaml(rbacTestResources)
+	assert.NoError(t, err)
+	subjects := analyzeRBAC(provider)
+	assert.Len(t, subjects, 3)
+
+	// Bindings named like the defaults are analyzed, only the ones labeled as defaults are skipped
+	admins := subjects[0]
+	assert.Equal(t, "Group admins", admins.String())
+	assert.Equal(t, []RBACEscalation{
+		{Type: RBACEscalationBind, Message: "can bind roles cluster-wide", Binding: "ClusterRoleBinding system:operator"},
+		{Type: RBACEscalationEscalate, Message: "can escalate roles cluster-wide", Binding: "ClusterRoleBinding system:operator"},
+	}, admins.Escalations)
+
+	developers := subjects[1]
+	assert.Equal(t, "Group developers", developers.String())
+	assert.Len(t, developers.Permissions, 1)
+	assert.Equal(t, "apps", developers.Permissions[0].Namespace)
+	assert.Equal(t, "Role apps/deployer", developers.Permissions[0].Role)
+	assert.Equal(t, []RBACEscalation{
+		{Type: RBACEscalationCreatePods, Message: "can create pods in namespace apps and run them as ServiceAccount apps/operator", Binding: "RoleBinding apps/deployer"},
+	}, developers.Escalations)
+
+	operator := subjects[2]
+	assert.Equal(t, "ServiceAccount apps/operator", operator.String())
+	assert.Equal(t, "ClusterRole operator", operator.Permissions[0].Role)
+	assert.Equal(t, []string{"bind", "escalate"}, operator.Permissions[0].Rule.Verbs)
+	assert.Equal(t, []RBACEscalation{
+		{Type: RBACEscalationBind, Message: "can bind roles cluster-wide", Binding: "ClusterRoleBinding operator"},
+		{Type: RBACEscalationEscalate, Message: "can escalate roles cluster-wide", Binding: "ClusterRoleBinding operator"},
+	}, operator.Escalations)
+	assert.Equal(t, []string{"Deployment apps/operator"}, operator.Workloads)
+}
+
+func TestAnalyzeRBACDeprecatedServiceAccount(t *testing.T) {
+	resources := strings.Replace(rbacTestResources, "serviceAccountName: operator", "serviceAccount: operator", 1)
+	provider, err := kube.CreateResourceProviderFromYaml(resources)
+	assert.NoError(t, err)
+	operator := newAuditContext(provider).getServiceAccountSubject("apps", "operator")
+	assert.NotNil(t, operator)
+	assert.Equal(t, []string{"Deployment apps/operator"}, operator.Workloads)
+}
+
+func TestRBACEscalationChecks(t *testing.T) {
+	c := conf.Configuration{
+		Checks: map[string]conf.Severity{
+			"clusterrolebindingEscalation": conf.SeverityWarning,
+			"rolebindingEscalation":        conf.SeverityWarning,
+		},
+	}
+	provider, err := kube.CreateResourceProviderFromYaml(rbacTestResources)
+	assert.NoError(t, err)
+	auditData, err := RunAudit(c, provider)
+	assert.NoError(t, err)
+	assert.Len(t, auditData.RBAC, 3)
+
+	details := map[string][]string{}
+	for _, result := range auditData.Results {
+		for _, msg := range result.Results {
+			details[result.Kind+"/"+result.Name] = msg.Details
+		}
+	}
+	assert.Equal(t, []string{
+		"ServiceAccount apps/operator can bind roles cluster-wide",
+		"ServiceAccount apps/operator can escalate roles cluster-wide",
+	}, details["ClusterRoleBinding/operator"])
+	assert.Equal(t, []string{
+		"Group admins can bind roles cluster-wide",
+		"Group admins can escalate roles cluster-wide",
+	}, details["ClusterRoleBinding/system:operator"])
+	assert.Nil(t, details["ClusterRoleBinding/system:controller:operator"])
+	assert.Equal(t, []string{
+		"Group developers can create pods in namespace apps and run them as ServiceAccount apps/operator",
+	}, details["RoleBinding/deployer"])
+}
+
+func TestRBACServiceAccountGroups(t *testing.T) {
+	provider, err := kube.CreateResourceProviderFromYaml(`
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: cluster-admin
+  labels:
+    kubernetes.io/bootstrapping: rbac-defaults
+rules:
+  - apiGroups: ["*"]
+    resources: ["*"]
+    verbs: ["*"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: cluster-admin
+  labels:
+    kubernetes.io/bootstrapping: rbac-defaults
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: cluster-admin
+subjects:
+  - kind: Group
+    name: system:masters
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: all-serviceaccounts-admin
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: cluster-admin
+subjects:
+  - kind: Group
+    name: system:serviceaccounts
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: secret-reader
+  namespace: apps
+rules:
+  - apiGroups: [""]
+    resources: ["secrets"]
+    verbs: ["get"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: apps-secrets
+  namespace: apps
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: secret-reader
+subjects:
+  - kind: Group
+    name: system:serviceaccounts:apps
+  - kind: User
+    name: system:serviceaccount:apps:web
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: worker
+  namespace: batch
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: apps
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      serviceAccountName: web
+      containers:
+        - name: web
+          image: nginx:1.25
+`)
+	assert.NoError(t, err)
+	subjects := map[string]RBACSubject{}
+	for _, subject := range analyzeRBAC(provider) {
+		subjects[subject.String()] = subject
+	}
+	assert.NotContains(t, subjects, "Group system:masters", "default bindings are skipped")
+	assert.Contains(t, subjects, "Group system:serviceaccounts")
+
+	web := subjects["ServiceAccount apps/web"]
+	assert.Equal(t, RBACEscalation{
+		Type:    RBACEscalationWildcard,
+		Message: "has full access to all resources cluster-wide",
+		Binding: "ClusterRoleBinding all-serviceaccounts-admin",
+	}, web.Escalations[0])
+	// The username and the namespace group are granted the same binding, which is only counted once
+	bindings := []string{}
+	for _, permission := range web.Permissions {
+		bindings = append(bindings, permission.Binding)
+	}
+	assert.Equal(t, []string{"RoleBinding apps/apps-secrets", "ClusterRoleBinding all-serviceaccounts-admin"}, bindings)
+	assert.Equal(t, []string{"Deployment apps/web"}, web.Workloads)
+
+	worker := subjects["ServiceAccount batch/worker"]
+	assert.Len(t, worker.Permissions, 1)
+	assert.Equal(t, RBACEscalationWildcard, worker.Escalations[0].Type)
+}
+
+func TestMatchesRuleResource(t *testing.T) {
+	assert.True(t, matchesRuleResource([]string{"*"}, "nodes/proxy"))
+	assert.True(t, matchesRuleResource([]string{"nodes/*"}, "nodes/proxy"))
+	assert.True(t, matchesRuleResource([]string{"*/proxy"}, "nodes/proxy"))
+	assert.False(t, matchesRuleResource([]string{"nodes"}, "nodes/proxy"))
+	assert.False(t, matchesRuleResource([]string{"nodes/*"}, "nodes"))
+}
+
+func TestWorkloadServiceAccount(t *testing.T) {
+	c := conf.Configuration{
+		Checks: map[string]conf.Severity{
+			"serviceAccountTokenEscalation":   conf.SeverityDanger,
+			"serviceAccountTokenReadsSecrets": conf.SeverityWarning,
+			"clusterrolebindingEscalation":    conf.SeverityDanger,
+		},
+	}
+	provider, err := kube.CreateResourceProviderFromYaml(rbacTestResources)
+	assert.NoError(t, err)
+	auditData, err := RunAudit(c, provider)
+	assert.NoError(t, err)
+
+	var deployment Result
+	for _, result := range auditData.Results {
+		if result.Kind == "Deployment" {
+			deployment = result
+		}
+	}
+	assert.NotNil(t, deployment.ServiceAccount)
+	assert.Equal(t, "ServiceAccount apps/operator", deployment.ServiceAccount.String())
+	assert.Len(t, deployment.ServiceAccount.Permissions, 1)
+	assert.Nil(t, deployment.ServiceAccount.Workloads)
+	assert.True(t, deployment.Results["serviceAccountTokenReadsSecrets"].Success)
+	assert.False(t, deployment.Results["serviceAccountTokenEscalation"].Success)
+	assert.Equal(t, []string{
+		"ServiceAccount apps/operator can bind roles cluster-wide (ClusterRoleBinding operator)",
+		"ServiceAccount apps/operator can escalate roles cluster-wide (ClusterRoleBinding operator)",
+	}, deployment.Results["serviceAccountTokenEscalation"].Details)
+
+	// The analysis is shared between workloads and the report, so it must not be modified
+	assert.Len(t, auditData.RBAC, 3)
+	assert.Equal(t, "ServiceAccount apps/operator", auditData.RBAC[2].String())
+	assert.Equal(t, []string{"Deployment apps/operator"}, auditData.RBAC[2].Workloads)
+}
+
+func TestRBACNamespaces(t *testing.T) {
+	provider, err := kube.CreateResourceProviderFromYaml(`
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: admin-all
+rules:
+  - apiGroups: ["*"]
+    resources: ["*"]
+    verbs: ["*"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: ci-admin
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: admin-all
+subjects:
+  - kind: ServiceAccount
+    name: default
+    namespace: ci
+  - kind: ServiceAccount
+    name: builder
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: pod-creator
+  namespace: apps
+rules:
+  - apiGroups: [""]
+    resources: ["pods"]
+    verbs: ["create"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: deployer
+  namespace: apps
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: pod-creator
+subjects:
+  - kind: User
+    name: deployer
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.25
+`)
+	assert.NoError(t, err)
+	subjects := analyzeRBAC(provider)
+	assert.Len(t, subjects, 3)
+	for _, subject := range subjects {
+		if subject.Kind == "User" {
+			// Pods created in apps can't run as a ServiceAccount without a namespace
+			assert.Empty(t, subject.Escalations)
+		} else {
+			// The Deployment has no namespace, so it isn't known to run in ci
+			assert.Empty(t, subject.Workloads)
+		}
+	}
+	assert.Nil(t, newAuditContext(provider).getServiceAccountSubject("", "default"))
+
+	audit := newAuditContext(provider)
+	assert.Equal(t, audit.getRBACSubjects(), audit.getRBACSubjects())
+	assert.Nil(t, (*auditContext)(nil).getRBACSubjects())
+}
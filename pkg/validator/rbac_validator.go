@@ -0,0 +1,48 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+
+	"github.com/qri-io/jsonschema"
+)
+
+func init() {
+	registerCustomChecks("clusterrolebindingEscalation", validateBindingEscalations)
+	registerCustomChecks("rolebindingEscalation", validateBindingEscalations)
+}
+
+// validateBindingEscalations fails if the binding gives one of its subjects a way to escalate privileges
+func validateBindingEscalations(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	if test.Audit.getProvider() == nil {
+		return true, nil, nil
+	}
+	binding := getRBACResourceName(test.Resource.Kind, test.Resource.ObjectMeta.GetNamespace(), test.Resource.ObjectMeta.GetName())
+	issues := []jsonschema.ValError{}
+	for _, subject := range test.Audit.getRBACSubjects() {
+		for _, escalation := range subject.Escalations {
+			if escalation.Binding != binding {
+				continue
+			}
+			issues = append(issues, jsonschema.ValError{
+				PropertyPath: "subjects",
+				InvalidValue: subject.Name,
+				Message:      fmt.Sprintf("%s %s", subject.String(), escalation.Message),
+			})
+		}
+	}
+	return len(issues) == 0, issues, nil
+}
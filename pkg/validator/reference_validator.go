@@ -0,0 +1,368 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+
+	"github.com/qri-io/jsonschema"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+// podReference is a reference from a pod spec to another resource
+type podReference struct {
+	name     string
+	optional bool
+	// source describes where the reference is made, e.g. `envFrom of container "app"`
+	source string
+}
+
+// podReferenceFunc returns the references of a pod spec to resources of a single kind
+type podReferenceFunc func(podSpec *corev1.PodSpec) []podReference
+
+func init() {
+	registerCustomChecks("danglingConfigMapReference", validatePodReferences("ConfigMap", getConfigMapReferences))
+	registerCustomChecks("danglingSecretReference", validatePodReferences("Secret", getSecretReferences))
+	registerCustomChecks("danglingServiceAccountReference", validatePodReferences("ServiceAccount", getServiceAccountReferences))
+	registerCustomChecks("danglingPersistentVolumeClaimReference", validatePodReferences("PersistentVolumeClaim", getPersistentVolumeClaimReferences))
+	registerCustomChecks("danglingIngressBackend", danglingIngressBackend)
+	registerCustomChecks("danglingHPAScaleTargetRef", danglingHPAScaleTargetRef)
+	registerCustomChecks("serviceSelectorMatchesNothing", serviceSelectorMatchesNothing)
+	registerCustomApplicability("danglingConfigMapReference", hasPodReferences(getConfigMapReferences))
+	registerCustomApplicability("danglingSecretReference", hasPodReferences(getSecretReferences))
+	registerCustomApplicability("danglingServiceAccountReference", hasPodReferences(getServiceAccountReferences))
+	registerCustomApplicability("danglingPersistentVolumeClaimReference", hasPodReferences(getPersistentVolumeClaimReferences))
+	registerCustomApplicability("danglingIngressBackend", func(test schemaTestCase) bool {
+		return canVerifyReferences(test.ResourceProvider) && len(getIngressBackends(test.Resource.Resource.Object)) > 0
+	})
+	registerCustomApplicability("danglingHPAScaleTargetRef", func(test schemaTestCase) bool {
+		return canVerifyReferences(test.ResourceProvider)
+	})
+	registerCustomApplicability("serviceSelectorMatchesNothing", func(test schemaTestCase) bool {
+		return canVerifyReferences(test.ResourceProvider)
+	})
+}
+
+// canVerifyReferences returns true when every referenced resource would be part of the audit. In-cluster
+// audits only load the kinds that checks target, so references can only be verified when auditing manifests.
+func canVerifyReferences(provider *kube.ResourceProvider) bool {
+	return provider != nil && (provider.SourceType == "Path" || provider.SourceType == "Content")
+}
+
+// sameNamespace treats resources without a namespace, which is common in manifests, as being in any namespace
+func sameNamespace(a, b string) bool {
+	return a == b || a == "" || b == ""
+}
+
+func findResource(resources []kube.GenericResource, namespace, name string) *kube.GenericResource {
+	for idx, resource := range resources {
+		if resource.ObjectMeta.GetName() == name && sameNamespace(resource.ObjectMeta.GetNamespace(), namespace) {
+			return &resources[idx]
+		}
+	}
+	return nil
+}
+
+// hasPodReferences returns true if a workload has required references that can be verified
+func hasPodReferences(getReferences podReferenceFunc) applicabilityFunction {
+	return func(test schemaTestCase) bool {
+		if !canVerifyReferences(test.ResourceProvider) || test.Resource.PodSpec == nil {
+			return false
+		}
+		for _, reference := range getReferences(test.Resource.PodSpec) {
+			if !reference.optional {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func validatePodReferences(kind string, getReferences podReferenceFunc) validatorFunction {
+	return func(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+		if !canVerifyReferences(test.ResourceProvider) || test.Resource.PodSpec == nil {
+			return true, nil, nil
+		}
+		namespace := test.Resource.ObjectMeta.GetNamespace()
+		issues := []jsonschema.ValError{}
+		for _, reference := range getReferences(test.Resource.PodSpec) {
+			if reference.optional || findResource(test.ResourceProvider.Resources[kind], namespace, reference.name) != nil {
+				continue
+			}
+			issues = append(issues, jsonschema.ValError{
+				InvalidValue: reference.name,
+				Message:      fmt.Sprintf("%s %q referenced by %s does not exist", kind, reference.name, reference.source),
+			})
+		}
+		return len(issues) == 0, issues, nil
+	}
+}
+
+func getConfigMapReferences(podSpec *corev1.PodSpec) []podReference {
+	references := []podReference{}
+	for _, container := range getAllContainers(podSpec) {
+		for _, envFrom := range container.EnvFrom {
+			if ref := envFrom.ConfigMapRef; ref != nil {
+				references = append(references, podReference{name: ref.Name, optional: isTrue(ref.Optional), source: fmt.Sprintf("envFrom of container %q", container.Name)})
+			}
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.ConfigMapKeyRef != nil {
+				ref := env.ValueFrom.ConfigMapKeyRef
+				references = append(references, podReference{name: ref.Name, optional: isTrue(ref.Optional), source: fmt.Sprintf("env %s of container %q", env.Name, container.Name)})
+			}
+		}
+	}
+	for _, volume := range podSpec.Volumes {
+		if ref := volume.ConfigMap; ref != nil {
+			references = append(references, podReference{name: ref.Name, optional: isTrue(ref.Optional), source: fmt.Sprintf("volume %q", volume.Name)})
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if ref := source.ConfigMap; ref != nil {
+					references = append(references, podReference{name: ref.Name, optional: isTrue(ref.Optional), source: fmt.Sprintf("volume %q", volume.Name)})
+				}
+			}
+		}
+	}
+	return references
+}
+
+func getSecretReferences(podSpec *corev1.PodSpec) []podReference {
+	references := []podReference{}
+	for _, container := range getAllContainers(podSpec) {
+		for _, envFrom := range container.EnvFrom {
+			if ref := envFrom.SecretRef; ref != nil {
+				references = append(references, podReference{name: ref.Name, optional: isTrue(ref.Optional), source: fmt.Sprintf("envFrom of container %q", container.Name)})
+			}
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+				ref := env.ValueFrom.SecretKeyRef
+				references = append(references, podReference{name: ref.Name, optional: isTrue(ref.Optional), source: fmt.Sprintf("env %s of container %q", env.Name, container.Name)})
+			}
+		}
+	}
+	for _, volume := range podSpec.Volumes {
+		if ref := volume.Secret; ref != nil {
+			references = append(references, podReference{name: ref.SecretName, optional: isTrue(ref.Optional), source: fmt.Sprintf("volume %q", volume.Name)})
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if ref := source.Secret; ref != nil {
+					references = append(references, podReference{name: ref.Name, optional: isTrue(ref.Optional), source: fmt.Sprintf("volume %q", volume.Name)})
+				}
+			}
+		}
+	}
+	for _, ref := range podSpec.ImagePullSecrets {
+		references = append(references, podReference{name: ref.Name, source: "imagePullSecrets"})
+	}
+	return references
+}
+
+func getServiceAccountReferences(podSpec *corev1.PodSpec) []podReference {
+	name := podSpec.ServiceAccountName
+	if name == "" {
+		name = podSpec.DeprecatedServiceAccount
+	}
+	// Every namespace has a default ServiceAccount
+	if name == "" || name == "default" {
+		return nil
+	}
+	return []podReference{{name: name, source: "serviceAccountName"}}
+}
+
+func getPersistentVolumeClaimReferences(podSpec *corev1.PodSpec) []podReference {
+	references := []podReference{}
+	for _, volume := range podSpec.Volumes {
+		if ref := volume.PersistentVolumeClaim; ref != nil {
+			references = append(references, podReference{name: ref.ClaimName, source: fmt.Sprintf("volume %q", volume.Name)})
+		}
+	}
+	return references
+}
+
+// ingressBackend is a Service an Ingress routes traffic to. Port is a number or a name, or nil if it isn't set.
+type ingressBackend struct {
+	Service string
+	Port    interface{}
+}
+
+// getIngressBackends returns the Services an Ingress routes traffic to. Resource backends aren't Services, and are
+// left out.
+func getIngressBackends(obj map[string]interface{}) []ingressBackend {
+	backends := []interface{}{}
+	if backend, found, _ := unstructured.NestedFieldNoCopy(obj, "spec", "defaultBackend"); found {
+		backends = append(backends, backend)
+	}
+	// networking.k8s.io/v1beta1 and extensions/v1beta1 Ingresses use `backend`
+	if backend, found, _ := unstructured.NestedFieldNoCopy(obj, "spec", "backend"); found {
+		backends = append(backends, backend)
+	}
+	rules, _ := getNestedSlice(obj, "spec", "rules")
+	for _, rule := range rules {
+		ruleMap, ok := rule.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		paths, _ := getNestedSlice(ruleMap, "http", "paths")
+		for _, path := range paths {
+			if pathMap, ok := path.(map[string]interface{}); ok && pathMap["backend"] != nil {
+				backends = append(backends, pathMap["backend"])
+			}
+		}
+	}
+
+	serviceBackends := []ingressBackend{}
+	for _, backend := range backends {
+		backendMap, ok := backend.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(backendMap, "service", "name")
+		port, _, _ := unstructured.NestedFieldNoCopy(backendMap, "service", "port", "number")
+		if port == nil {
+			port, _, _ = unstructured.NestedFieldNoCopy(backendMap, "service", "port", "name")
+		}
+		if name == "" {
+			name, _, _ = unstructured.NestedString(backendMap, "serviceName")
+			port, _, _ = unstructured.NestedFieldNoCopy(backendMap, "servicePort")
+		}
+		if name != "" {
+			serviceBackends = append(serviceBackends, ingressBackend{Service: name, Port: port})
+		}
+	}
+	return serviceBackends
+}
+
+func danglingIngressBackend(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	if !canVerifyReferences(test.ResourceProvider) {
+		return true, nil, nil
+	}
+	namespace := test.Resource.ObjectMeta.GetNamespace()
+	issues := []jsonschema.ValError{}
+	for _, backend := range getIngressBackends(test.Resource.Resource.Object) {
+		service := findResource(test.ResourceProvider.Resources["Service"], namespace, backend.Service)
+		if service == nil {
+			issues = append(issues, jsonschema.ValError{
+				InvalidValue: backend.Service,
+				Message:      fmt.Sprintf("Service %q does not exist", backend.Service),
+			})
+		} else if backend.Port != nil && !hasServicePort(*service, backend.Port) {
+			issues = append(issues, jsonschema.ValError{
+				InvalidValue: backend.Port,
+				Message:      fmt.Sprintf("Service %q has no port %v", backend.Service, backend.Port),
+			})
+		}
+	}
+	return len(issues) == 0, issues, nil
+}
+
+// hasServicePort returns true if the Service exposes a port with the given number or name
+func hasServicePort(service kube.GenericResource, port interface{}) bool {
+	ports, _ := getNestedSlice(service.Resource.Object, "spec", "ports")
+	for _, p := range ports {
+		portMap, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if fmt.Sprint(portMap["port"]) == fmt.Sprint(port) || portMap["name"] == port {
+			return true
+		}
+	}
+	return false
+}
+
+func danglingHPAScaleTargetRef(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	if !canVerifyReferences(test.ResourceProvider) {
+		return true, nil, nil
+	}
+	ref, found, _ := unstructured.NestedStringMap(test.Resource.Resource.Object, "spec", "scaleTargetRef")
+	if !found || ref["kind"] == "" || ref["name"] == "" {
+		return true, nil, nil
+	}
+	groupKind := getScaleTargetGroupKind(ref["apiVersion"], ref["kind"])
+	if findResource(test.ResourceProvider.Resources[groupKind], test.Resource.ObjectMeta.GetNamespace(), ref["name"]) != nil {
+		return true, nil, nil
+	}
+	return false, []jsonschema.ValError{
+		{
+			PropertyPath: "spec.scaleTargetRef",
+			InvalidValue: ref["name"],
+			Message:      fmt.Sprintf("%s %q does not exist", ref["kind"], ref["name"]),
+		},
+	}, nil
+}
+
+func serviceSelectorMatchesNothing(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	if !canVerifyReferences(test.ResourceProvider) {
+		return true, nil, nil
+	}
+	// Services without a selector have their endpoints managed separately
+	selector, _, _ := unstructured.NestedStringMap(test.Resource.Resource.Object, "spec", "selector")
+	if len(selector) == 0 {
+		return true, nil, nil
+	}
+	namespace := test.Resource.ObjectMeta.GetNamespace()
+	labelSelector := labels.SelectorFromSet(selector)
+	for _, resources := range test.ResourceProvider.Resources {
+		for _, resource := range resources {
+			if resource.PodSpec == nil || !sameNamespace(resource.ObjectMeta.GetNamespace(), namespace) {
+				continue
+			}
+			if labelSelector.Matches(labels.Set(getPodLabels(resource))) {
+				return true, nil, nil
+			}
+		}
+	}
+	return false, []jsonschema.ValError{
+		{
+			PropertyPath: "spec.selector",
+			InvalidValue: selector,
+			Message:      fmt.Sprintf("selector %s matches no workload", labelSelector.String()),
+		},
+	}, nil
+}
+
+// getPodLabels returns the labels of the pods created by a workload
+func getPodLabels(resource kube.GenericResource) map[string]string {
+	podTemplate, ok := resource.PodTemplate.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	podLabels, _, err := unstructured.NestedStringMap(podTemplate, "metadata", "labels")
+	if err != nil {
+		logrus.Debugf("could not read pod labels of %s %s: %v", resource.Kind, resource.ObjectMeta.GetName(), err)
+		return nil
+	}
+	return podLabels
+}
+
+// getNestedSlice is like unstructured.NestedSlice, but doesn't deep copy the slice, which fails
+// for resources parsed from YAML because they can contain int values
+func getNestedSlice(obj map[string]interface{}, fields ...string) ([]interface{}, bool) {
+	val, found, err := unstructured.NestedFieldNoCopy(obj, fields...)
+	if !found || err != nil {
+		return nil, false
+	}
+	slice, ok := val.([]interface{})
+	return slice, ok
+}
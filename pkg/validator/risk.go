@@ -0,0 +1,232 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/fairwindsops/polaris/pkg/config"
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+// CriticalNamespaceLabel marks a Namespace as critical when set to "true", in addition to the criticalNamespaces config
+const CriticalNamespaceLabel = "polaris.fairwinds.com/critical"
+
+// RiskiestWorkloadsCount is the number of workloads listed in the riskiest workloads sections
+const RiskiestWorkloadsCount = 10
+
+// RiskFactorType is context that makes the findings of a resource more urgent to fix
+type RiskFactorType string
+
+const (
+	// RiskFactorExposed is a workload receiving traffic from outside the cluster, through a LoadBalancer or
+	// NodePort Service, or an Ingress
+	RiskFactorExposed RiskFactorType = "exposed"
+	// RiskFactorRBAC is a workload whose ServiceAccount can escalate its privileges
+	RiskFactorRBAC RiskFactorType = "rbac"
+	// RiskFactorPrivileged is a workload running privileged containers
+	RiskFactorPrivileged RiskFactorType = "privileged"
+	// RiskFactorCriticalNamespace is a resource in a namespace marked critical
+	RiskFactorCriticalNamespace RiskFactorType = "criticalNamespace"
+)
+
+// riskSeverityScores are the risk of each failing check, before the risk factors are applied
+var riskSeverityScores = map[config.Severity]float64{
+	config.SeverityDanger:  10,
+	config.SeverityWarning: 3,
+}
+
+// riskFactorWeights multiply the risk of a resource's failing checks
+var riskFactorWeights = map[RiskFactorType]float64{
+	RiskFactorExposed:           2,
+	RiskFactorRBAC:              2,
+	RiskFactorPrivileged:        2,
+	RiskFactorCriticalNamespace: 1.5,
+}
+
+// RiskFactor is context that raised the risk of a resource
+type RiskFactor struct {
+	Type    RiskFactorType
+	Message string
+}
+
+func (res ResultSet) getSeverityRisk() float64 {
+	risk := 0.0
+	for _, msg := range res {
+		if !msg.Success {
+			risk += riskSeverityScores[msg.Severity]
+		}
+	}
+	return risk
+}
+
+// getSeverityRisk adds up the risk of every failing check of the resource, its pods and containers
+func (res Result) getSeverityRisk() float64 {
+	risk := res.Results.getSeverityRisk()
+	if res.PodResult != nil {
+		risk += res.PodResult.Results.getSeverityRisk()
+		for _, container := range res.PodResult.ContainerResults {
+			risk += container.Results.getSeverityRisk()
+		}
+	}
+	return risk
+}
+
+// setRisk scores the failing checks of a resource by their severity, multiplied by the weight of each risk factor.
+// Resources without failing checks have no risk, whatever their context.
+func (res *Result) setRisk(conf *config.Configuration, resourceProvider *kube.ResourceProvider, resource kube.GenericResource) {
+	res.RiskFactors = getRiskFactors(conf, resourceProvider, resource, res.ServiceAccount)
+	risk := res.getSeverityRisk()
+	for _, factor := range res.RiskFactors {
+		risk *= riskFactorWeights[factor.Type]
+	}
+	res.Risk = uint(math.Round(risk))
+}
+
+func getRiskFactors(conf *config.Configuration, resourceProvider *kube.ResourceProvider, resource kube.GenericResource, serviceAccount *RBACSubject) []RiskFactor {
+	factors := []RiskFactor{}
+	if resource.PodSpec != nil {
+		if exposures := getExposures(resourceProvider, resource); len(exposures) > 0 {
+			factors = append(factors, RiskFactor{
+				Type:    RiskFactorExposed,
+				Message: "exposed by " + strings.Join(exposures, ", "),
+			})
+		}
+		if serviceAccount != nil && serviceAccount.isEscalating() {
+			factors = append(factors, RiskFactor{
+				Type:    RiskFactorRBAC,
+				Message: fmt.Sprintf("%s has %d RBAC escalation path(s)", serviceAccount.String(), len(serviceAccount.Escalations)),
+			})
+		}
+		privileged := []string{}
+		for _, container := range getAllContainers(resource.PodSpec) {
+			if container.SecurityContext != nil && isTrue(container.SecurityContext.Privileged) {
+				privileged = append(privileged, container.Name)
+			}
+		}
+		if len(privileged) > 0 {
+			factors = append(factors, RiskFactor{
+				Type:    RiskFactorPrivileged,
+				Message: "privileged container(s) " + strings.Join(privileged, ", "),
+			})
+		}
+	}
+	if namespace := resource.ObjectMeta.GetNamespace(); isCriticalNamespace(conf, resourceProvider, namespace) {
+		factors = append(factors, RiskFactor{
+			Type:    RiskFactorCriticalNamespace,
+			Message: fmt.Sprintf("namespace %s is critical", namespace),
+		})
+	}
+	return factors
+}
+
+// isCriticalNamespace returns true if the namespace matches criticalNamespaces, or is labeled critical
+func isCriticalNamespace(conf *config.Configuration, resourceProvider *kube.ResourceProvider, namespace string) bool {
+	if namespace == "" {
+		return false
+	}
+	if conf != nil && matchesAny(conf.CriticalNamespaces, namespace) {
+		return true
+	}
+	if resourceProvider == nil {
+		return false
+	}
+	for _, ns := range resourceProvider.Namespaces {
+		if ns.ObjectMeta.GetName() == namespace {
+			return ns.ObjectMeta.GetLabels()[CriticalNamespaceLabel] == "true"
+		}
+	}
+	return false
+}
+
+// getExposures lists the LoadBalancer and NodePort Services, and the Ingresses, routing traffic to a workload's pods
+func getExposures(resourceProvider *kube.ResourceProvider, resource kube.GenericResource) []string {
+	if resourceProvider == nil {
+		return nil
+	}
+	namespace := resource.ObjectMeta.GetNamespace()
+	podLabels := labels.Set(getPodLabels(resource))
+	// Services and Ingresses only route traffic within their namespace
+	selectingServices := map[string]bool{}
+	exposures := []string{}
+	for _, service := range resourceProvider.Resources["Service"] {
+		if service.ObjectMeta.GetNamespace() != namespace {
+			continue
+		}
+		selector, _, _ := unstructured.NestedStringMap(service.Resource.Object, "spec", "selector")
+		if len(selector) == 0 || !labels.SelectorFromSet(selector).Matches(podLabels) {
+			continue
+		}
+		selectingServices[getNamespacedName(namespace, service.ObjectMeta.GetName())] = true
+		serviceType, _, _ := unstructured.NestedString(service.Resource.Object, "spec", "type")
+		if serviceType == "LoadBalancer" || serviceType == "NodePort" {
+			exposures = append(exposures, fmt.Sprintf("%s Service %s", serviceType, service.ObjectMeta.GetName()))
+		}
+	}
+	if len(selectingServices) == 0 {
+		return exposures
+	}
+	for _, ingress := range resourceProvider.Resources[ingressKind] {
+		if ingress.ObjectMeta.GetNamespace() != namespace {
+			continue
+		}
+		for _, backend := range getIngressBackends(ingress.Resource.Object) {
+			if selectingServices[getNamespacedName(namespace, backend.Service)] {
+				exposures = append(exposures, "Ingress "+ingress.ObjectMeta.GetName())
+				break
+			}
+		}
+	}
+	return exposures
+}
+
+// SortResultsByRisk orders the results from the riskiest to the least risky. Results with the same risk keep their order.
+func (res AuditData) SortResultsByRisk() AuditData {
+	resCopy := res
+	resCopy.Results = append([]Result{}, res.Results...)
+	sort.SliceStable(resCopy.Results, func(i, j int) bool {
+		return resCopy.Results[i].Risk > resCopy.Results[j].Risk
+	})
+	return resCopy
+}
+
+// GetRiskiestWorkloads returns up to count workloads with failing checks, from the riskiest
+func (res AuditData) GetRiskiestWorkloads(count int) []Result {
+	workloads := []Result{}
+	for _, result := range res.SortResultsByRisk().Results {
+		if len(workloads) == count {
+			break
+		}
+		if result.PodResult != nil && result.Risk > 0 {
+			workloads = append(workloads, result)
+		}
+	}
+	return workloads
+}
+
+// GetRiskFactorTypes returns the types of the risk factors of a result, e.g. for labels in the dashboard
+func (res Result) GetRiskFactorTypes() []string {
+	types := []string{}
+	for _, factor := range res.RiskFactors {
+		types = append(types, string(factor.Type))
+	}
+	return types
+}
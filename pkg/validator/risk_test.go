@@ -0,0 +1,279 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	conf "github.com/fairwindsops/polaris/pkg/config"
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+const riskTestResources = `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: payments
+  labels:
+    polaris.fairwinds.com/critical: "true"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.27
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: shop
+spec:
+  selector:
+    app: web
+  ports:
+    - port: 80
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: web
+  namespace: shop
+spec:
+  rules:
+    - host: shop.example.com
+      http:
+        paths:
+          - path: /
+            pathType: Prefix
+            backend:
+              service:
+                name: web
+                port:
+                  number: 80
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: agent
+  namespace: payments
+spec:
+  selector:
+    matchLabels:
+      app: agent
+  template:
+    metadata:
+      labels:
+        app: agent
+    spec:
+      containers:
+        - name: agent
+          image: agent:1.0
+          securityContext:
+            privileged: true
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: worker
+  namespace: shop
+spec:
+  selector:
+    matchLabels:
+      app: worker
+  template:
+    metadata:
+      labels:
+        app: worker
+    spec:
+      containers:
+        - name: worker
+          image: worker:1.0
+`
+
+func TestRisk(t *testing.T) {
+	c := conf.Configuration{
+		Checks: map[string]conf.Severity{
+			"privilegeEscalationAllowed": conf.SeverityDanger,
+			"cpuRequestsMissing":         conf.SeverityWarning,
+		},
+	}
+	provider, err := kube.CreateResourceProviderFromYaml(riskTestResources)
+	assert.NoError(t, err)
+	auditData, err := RunAudit(c, provider)
+	assert.NoError(t, err)
+
+	risks := map[string]uint{}
+	factors := map[string][]string{}
+	for _, result := range auditData.Results {
+		if result.Kind == "Deployment" {
+			risks[result.Name] = result.Risk
+			factors[result.Name] = result.GetRiskFactorTypes()
+		}
+	}
+	// every container fails both checks: 10 + 3
+	assert.Equal(t, uint(13*2), risks["web"])
+	assert.Equal(t, []string{"exposed"}, factors["web"])
+	assert.Equal(t, uint(13*2*1.5), risks["agent"])
+	assert.Equal(t, []string{"privileged", "criticalNamespace"}, factors["agent"])
+	assert.Equal(t, uint(13), risks["worker"])
+	assert.Empty(t, factors["worker"])
+
+	riskiest := auditData.GetRiskiestWorkloads(2)
+	if assert.Len(t, riskiest, 2) {
+		assert.Equal(t, "agent", riskiest[0].Name)
+		assert.Equal(t, "web", riskiest[1].Name)
+		assert.Equal(t, "exposed by Ingress web", riskiest[1].RiskFactors[0].Message)
+	}
+	assert.Equal(t, "agent", auditData.SortResultsByRisk().Results[0].Name)
+	// The riskiest workloads are listed whatever the order of the results
+	assert.Contains(t, auditData.GetPrettyOutput(false), "    Top 3 riskiest workloads:\n      39: Deployment payments/agent (privileged, criticalNamespace)\n")
+
+	c.CriticalNamespaces = []string{"sh*"}
+	auditData, err = RunAudit(c, provider)
+	assert.NoError(t, err)
+	for _, result := range auditData.Results {
+		if result.Name == "worker" {
+			assert.Equal(t, uint(20), result.Risk)
+		}
+	}
+}
+
+func TestRiskServiceAccountGroups(t *testing.T) {
+	c := conf.Configuration{
+		Checks: map[string]conf.Severity{
+			"cpuRequestsMissing": conf.SeverityWarning,
+		},
+	}
+	provider, err := kube.CreateResourceProviderFromYaml(`
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: cluster-admin
+  labels:
+    kubernetes.io/bootstrapping: rbac-defaults
+rules:
+  - apiGroups: ["*"]
+    resources: ["*"]
+    verbs: ["*"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: serviceaccounts-admin
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: cluster-admin
+subjects:
+  - kind: Group
+    name: system:serviceaccounts
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: worker
+  namespace: shop
+spec:
+  selector:
+    matchLabels:
+      app: worker
+  template:
+    metadata:
+      labels:
+        app: worker
+    spec:
+      containers:
+        - name: worker
+          image: worker:1.0
+`)
+	assert.NoError(t, err)
+	auditData, err := RunAudit(c, provider)
+	assert.NoError(t, err)
+	riskiest := auditData.GetRiskiestWorkloads(1)
+	if assert.Len(t, riskiest, 1) {
+		// The default ServiceAccount of shop gets the role bound to every ServiceAccount
+		assert.Equal(t, []string{"rbac"}, riskiest[0].GetRiskFactorTypes())
+		assert.Equal(t, uint(3*2), riskiest[0].Risk)
+	}
+}
+
+func TestGetExposuresNamespaces(t *testing.T) {
+	provider, err := kube.CreateResourceProviderFromYaml(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.27
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  type: LoadBalancer
+  selector:
+    app: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: shop
+spec:
+  selector:
+    app: web
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: web
+spec:
+  defaultBackend:
+    service:
+      name: web
+      port:
+        number: 80
+`)
+	assert.NoError(t, err)
+	// Neither the Service nor the Ingress without a namespace route traffic to shop
+	assert.Empty(t, getExposures(provider, provider.Resources["apps/Deployment"][0]))
+}
@@ -0,0 +1,445 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/qri-io/jsonschema"
+	"github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/fairwindsops/polaris/pkg/config"
+)
+
+const (
+	// defaultTerminationGracePeriodSeconds is used by Kubernetes when terminationGracePeriodSeconds isn't set
+	defaultTerminationGracePeriodSeconds = 30
+	// shutdownSeconds is the time left to the application to shut down after its preStop hook, in mutations
+	shutdownSeconds = 30
+	// startupProbeFailureThreshold and startupProbePeriodSeconds give containers 5 minutes to start, in mutations
+	startupProbeFailureThreshold = 30
+	startupProbePeriodSeconds    = 10
+)
+
+// preStopSleep matches sleep commands in preStop hooks, e.g. `sleep 15` or `/bin/sleep 15s`
+var preStopSleep = regexp.MustCompile(`(?:^|[\s;&|/])sleep\s+([0-9]+)s?(?:$|[\s;&|])`)
+
+func init() {
+	registerCustomChecks("probesIdentical", probesIdentical)
+	registerCustomChecks("livenessProbeStartupMissing", livenessProbeStartupMissing)
+	registerCustomChecks("terminationGracePeriodTooShort", terminationGracePeriodTooShort)
+	registerCustomChecks("rollingUpdateMaxUnavailableAll", rollingUpdateMaxUnavailableAll)
+	registerCustomChecks("singleReplicaPDBBlocksEviction", singleReplicaPDBBlocksEviction)
+	registerCustomChecks("statefulSetUpdateStrategyRisky", statefulSetUpdateStrategyRisky)
+	registerCustomMutations("livenessProbeStartupMissing", livenessProbeStartupMutations)
+	registerCustomMutations("terminationGracePeriodTooShort", terminationGracePeriodMutations)
+	registerCustomMutations("rollingUpdateMaxUnavailableAll", rollingUpdateMaxUnavailableMutations)
+	registerCustomMutations("replicasNotSpread", replicasNotSpreadMutations)
+	registerCustomApplicability("probesIdentical", anyContainer(func(container corev1.Container) bool {
+		return container.LivenessProbe != nil && container.ReadinessProbe != nil
+	}))
+	registerCustomApplicability("livenessProbeStartupMissing", anyContainer(func(container corev1.Container) bool {
+		return container.LivenessProbe != nil && isSlowStarter(container)
+	}))
+	registerCustomApplicability("terminationGracePeriodTooShort", anyContainer(func(container corev1.Container) bool {
+		return getPreStopSleepSeconds(container) > 0
+	}))
+	registerCustomApplicability("rollingUpdateMaxUnavailableAll", func(test schemaTestCase) bool {
+		var deployment appsv1.Deployment
+		if !fromUnstructured(test.Resource, &deployment) {
+			return false
+		}
+		_, ok := getMaxUnavailable(deployment)
+		return ok
+	})
+	registerCustomApplicability("singleReplicaPDBBlocksEviction", func(test schemaTestCase) bool {
+		var deployment appsv1.Deployment
+		return test.ResourceProvider != nil && len(test.ResourceProvider.Resources["policy/PodDisruptionBudget"]) > 0 &&
+			fromUnstructured(test.Resource, &deployment) && getDeploymentReplicas(deployment) == 1
+	})
+	// A single replica can't be spread
+	registerCustomApplicability("replicasNotSpread", func(test schemaTestCase) bool {
+		return getWorkloadReplicas(test.ResourceProvider, test.Resource) > 1
+	})
+}
+
+// anyContainer returns an applicabilityFunction for checks that only apply to pods with a matching container
+func anyContainer(matches func(container corev1.Container) bool) applicabilityFunction {
+	return func(test schemaTestCase) bool {
+		if test.Resource.PodSpec == nil {
+			return false
+		}
+		for _, container := range getAllContainers(test.Resource.PodSpec) {
+			if matches(container) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// validateContainers runs a rule against every container, and fails with the messages it returns
+func validateContainers(test schemaTestCase, rule func(container corev1.Container) string) (bool, []jsonschema.ValError, error) {
+	if test.Resource.PodSpec == nil {
+		return true, nil, nil
+	}
+	issues := []jsonschema.ValError{}
+	for _, container := range getAllContainers(test.Resource.PodSpec) {
+		if message := rule(container); message != "" {
+			issues = append(issues, jsonschema.ValError{Message: message})
+		}
+	}
+	return len(issues) == 0, issues, nil
+}
+
+// probesIdentical fails if a container's liveness probe checks the same thing as its readiness probe, so that a
+// container that is too busy to be ready gets restarted too
+func probesIdentical(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	return validateContainers(test, func(container corev1.Container) string {
+		if container.LivenessProbe == nil || container.ReadinessProbe == nil {
+			return ""
+		}
+		if !reflect.DeepEqual(container.LivenessProbe.ProbeHandler, container.ReadinessProbe.ProbeHandler) {
+			return ""
+		}
+		return fmt.Sprintf("container %q uses the same handler for its liveness and readiness probes", container.Name)
+	})
+}
+
+// isSlowStarter returns true if the readiness probe of a container waits for it to start
+func isSlowStarter(container corev1.Container) bool {
+	return container.ReadinessProbe != nil && container.ReadinessProbe.InitialDelaySeconds > 0
+}
+
+// livenessProbeStartupMissing fails if the liveness probe of a container that is slow to start, as shown by the
+// initialDelaySeconds of its readiness probe, can restart it before it has started
+func livenessProbeStartupMissing(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	return validateContainers(test, func(container corev1.Container) string {
+		if container.LivenessProbe == nil || container.StartupProbe != nil || !isSlowStarter(container) {
+			return ""
+		}
+		if container.LivenessProbe.InitialDelaySeconds > 0 {
+			return ""
+		}
+		return fmt.Sprintf("container %q waits %ds to be ready, but its liveness probe starts immediately without a startupProbe",
+			container.Name, container.ReadinessProbe.InitialDelaySeconds)
+	})
+}
+
+// livenessProbeStartupMutations adds a startupProbe using the liveness probe's handler, which holds off the
+// liveness probe until it succeeds
+func livenessProbeStartupMutations(test schemaTestCase) ([]config.Mutation, error) {
+	if test.Resource.PodSpec == nil {
+		return nil, nil
+	}
+	mutations := []config.Mutation{}
+	forEachContainer(test.Resource.PodSpec, func(container *corev1.Container, containerClass config.ContainerClass) {
+		if container.LivenessProbe == nil || container.StartupProbe != nil || !isSlowStarter(*container) ||
+			container.LivenessProbe.InitialDelaySeconds > 0 {
+			return
+		}
+		startupProbe := corev1.Probe{
+			ProbeHandler:     container.LivenessProbe.ProbeHandler,
+			PeriodSeconds:    startupProbePeriodSeconds,
+			FailureThreshold: startupProbeFailureThreshold,
+		}
+		value, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&startupProbe)
+		if err != nil {
+			logrus.Warnf("could not convert startupProbe of container %s: %v", container.Name, err)
+			return
+		}
+		mutations = append(mutations, config.Mutation{
+			Op:      "add",
+			Path:    getContainerJSONSchemaPrefix(test.Resource, container, containerClass) + "/startupProbe",
+			Value:   value,
+			Comment: "Gives the container 5 minutes to start before the liveness probe runs",
+		})
+	})
+	return mutations, nil
+}
+
+// getPreStopSleepSeconds returns how long the preStop hook of a container sleeps, or 0
+func getPreStopSleepSeconds(container corev1.Container) int64 {
+	if container.Lifecycle == nil || container.Lifecycle.PreStop == nil {
+		return 0
+	}
+	preStop := container.Lifecycle.PreStop
+	if preStop.Sleep != nil {
+		return preStop.Sleep.Seconds
+	}
+	if preStop.Exec == nil {
+		return 0
+	}
+	var seconds int64
+	for _, match := range preStopSleep.FindAllStringSubmatch(strings.Join(preStop.Exec.Command, " "), -1) {
+		value, err := strconv.ParseInt(match[1], 10, 64)
+		if err == nil && value > seconds {
+			seconds = value
+		}
+	}
+	return seconds
+}
+
+func getTerminationGracePeriodSeconds(podSpec *corev1.PodSpec) int64 {
+	if podSpec.TerminationGracePeriodSeconds == nil {
+		return defaultTerminationGracePeriodSeconds
+	}
+	return *podSpec.TerminationGracePeriodSeconds
+}
+
+// terminationGracePeriodTooShort fails if a preStop hook sleeps for the whole grace period, so that the
+// container is killed before it can shut down
+func terminationGracePeriodTooShort(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	if test.Resource.PodSpec == nil {
+		return true, nil, nil
+	}
+	gracePeriod := getTerminationGracePeriodSeconds(test.Resource.PodSpec)
+	return validateContainers(test, func(container corev1.Container) string {
+		sleep := getPreStopSleepSeconds(container)
+		if sleep == 0 || sleep < gracePeriod {
+			return ""
+		}
+		return fmt.Sprintf("container %q sleeps %ds in its preStop hook, but terminationGracePeriodSeconds is %d", container.Name, sleep, gracePeriod)
+	})
+}
+
+// terminationGracePeriodMutations leaves time to shut down after the longest preStop sleep
+func terminationGracePeriodMutations(test schemaTestCase) ([]config.Mutation, error) {
+	if test.Resource.PodSpec == nil {
+		return nil, nil
+	}
+	var sleep int64
+	for _, container := range getAllContainers(test.Resource.PodSpec) {
+		if seconds := getPreStopSleepSeconds(container); seconds > sleep {
+			sleep = seconds
+		}
+	}
+	if sleep < getTerminationGracePeriodSeconds(test.Resource.PodSpec) {
+		return nil, nil
+	}
+	return []config.Mutation{{
+		Op:    "add",
+		Path:  getJSONSchemaPrefix(test.Resource) + "/terminationGracePeriodSeconds",
+		Value: sleep + shutdownSeconds,
+	}}, nil
+}
+
+func getDeploymentReplicas(deployment appsv1.Deployment) int {
+	if deployment.Spec.Replicas == nil {
+		return 1
+	}
+	return int(*deployment.Spec.Replicas)
+}
+
+// defaultMaxSurge is used by Kubernetes when a rolling update doesn't set maxSurge, and by mutations
+var defaultMaxSurge = intstr.FromString("25%")
+
+// resolveFenceposts returns how many pods a rolling update can add and take down at once, like the Deployment
+// controller: maxSurge is rounded up, maxUnavailable down, and at least one pod is taken down when neither allows any
+func resolveFenceposts(maxSurge, maxUnavailable *intstr.IntOrString, replicas int) (int, int, error) {
+	if maxSurge == nil {
+		maxSurge = &defaultMaxSurge
+	}
+	surge, err := intstr.GetScaledValueFromIntOrPercent(maxSurge, replicas, true)
+	if err != nil {
+		return 0, 0, err
+	}
+	unavailable, err := intstr.GetScaledValueFromIntOrPercent(maxUnavailable, replicas, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	if surge == 0 && unavailable == 0 {
+		unavailable = 1
+	}
+	return surge, unavailable, nil
+}
+
+// getMaxUnavailable returns how many pods a rolling update can take down at once, or false if it can't tell
+func getMaxUnavailable(deployment appsv1.Deployment) (int, bool) {
+	if deployment.Spec.Strategy.Type == appsv1.RecreateDeploymentStrategyType || deployment.Spec.Strategy.RollingUpdate == nil ||
+		deployment.Spec.Strategy.RollingUpdate.MaxUnavailable == nil {
+		return 0, false
+	}
+	rollingUpdate := deployment.Spec.Strategy.RollingUpdate
+	_, maxUnavailable, err := resolveFenceposts(rollingUpdate.MaxSurge, rollingUpdate.MaxUnavailable, getDeploymentReplicas(deployment))
+	if err != nil {
+		logrus.Warnf("invalid rolling update in Deployment %s: %v", deployment.Name, err)
+		return 0, false
+	}
+	return maxUnavailable, true
+}
+
+// rollingUpdateMaxUnavailableAll fails if a rolling update can take down every pod of a Deployment at once.
+// Deployments with a single replica are left to deploymentMissingReplicas.
+func rollingUpdateMaxUnavailableAll(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	var deployment appsv1.Deployment
+	if !fromUnstructured(test.Resource, &deployment) {
+		return true, nil, nil
+	}
+	replicas := getDeploymentReplicas(deployment)
+	maxUnavailable, ok := getMaxUnavailable(deployment)
+	if !ok || replicas <= 1 || maxUnavailable < replicas {
+		return true, nil, nil
+	}
+	return false, []jsonschema.ValError{{
+		PropertyPath: "spec.strategy.rollingUpdate.maxUnavailable",
+		InvalidValue: deployment.Spec.Strategy.RollingUpdate.MaxUnavailable.String(),
+		Message:      fmt.Sprintf("maxUnavailable %s allows rolling updates to take down all %d replicas", deployment.Spec.Strategy.RollingUpdate.MaxUnavailable.String(), replicas),
+	}}, nil
+}
+
+// statefulSetUpdateStrategyRisky fails if a StatefulSet is updated only when its pods are deleted, or if a rolling
+// update can take down more than one pod at once. Percentages are rounded up, like the StatefulSet controller does.
+func statefulSetUpdateStrategyRisky(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	var statefulSet appsv1.StatefulSet
+	if !fromUnstructured(test.Resource, &statefulSet) {
+		return true, nil, nil
+	}
+	strategy := statefulSet.Spec.UpdateStrategy
+	if strategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+		return false, []jsonschema.ValError{{
+			PropertyPath: "spec.updateStrategy.type",
+			InvalidValue: string(strategy.Type),
+			Message:      "the OnDelete update strategy leaves pods outdated until they are deleted",
+		}}, nil
+	}
+	if strategy.RollingUpdate == nil || strategy.RollingUpdate.MaxUnavailable == nil {
+		return true, nil, nil
+	}
+	replicas := 1
+	if statefulSet.Spec.Replicas != nil {
+		replicas = int(*statefulSet.Spec.Replicas)
+	}
+	maxUnavailable := strategy.RollingUpdate.MaxUnavailable
+	unavailable, err := intstr.GetScaledValueFromIntOrPercent(maxUnavailable, replicas, true)
+	if err != nil {
+		return false, []jsonschema.ValError{{
+			PropertyPath: "spec.updateStrategy.rollingUpdate.maxUnavailable",
+			InvalidValue: maxUnavailable.String(),
+			Message:      fmt.Sprintf("maxUnavailable is invalid: %v", err),
+		}}, nil
+	}
+	if unavailable <= 1 {
+		return true, nil, nil
+	}
+	return false, []jsonschema.ValError{{
+		PropertyPath: "spec.updateStrategy.rollingUpdate.maxUnavailable",
+		InvalidValue: maxUnavailable.String(),
+		Message:      fmt.Sprintf("maxUnavailable %s allows rolling updates to take down %d of %d replicas at once", maxUnavailable.String(), unavailable, replicas),
+	}}, nil
+}
+
+// rollingUpdateMaxUnavailableMutations restores the default maxUnavailable, which always keeps a pod of a
+// Deployment with several replicas available
+func rollingUpdateMaxUnavailableMutations(test schemaTestCase) ([]config.Mutation, error) {
+	var deployment appsv1.Deployment
+	if !fromUnstructured(test.Resource, &deployment) || deployment.Spec.Strategy.RollingUpdate == nil {
+		return nil, nil
+	}
+	return []config.Mutation{{
+		Op:    "replace",
+		Path:  "/spec/strategy/rollingUpdate/maxUnavailable",
+		Value: "25%",
+	}}, nil
+}
+
+// blocksEviction returns true if the PodDisruptionBudget never allows evicting one of the given number of pods
+func blocksEviction(pdb policyv1.PodDisruptionBudget, replicas int) bool {
+	// The disruption controller rounds both minAvailable and maxUnavailable up
+	if pdb.Spec.MinAvailable != nil {
+		minAvailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MinAvailable, replicas, true)
+		return err == nil && minAvailable >= replicas
+	}
+	if pdb.Spec.MaxUnavailable != nil {
+		maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MaxUnavailable, replicas, true)
+		return err == nil && maxUnavailable == 0
+	}
+	return false
+}
+
+// singleReplicaPDBBlocksEviction fails if a Deployment with a single replica has a PodDisruptionBudget that never
+// allows evicting it, which blocks draining its node
+func singleReplicaPDBBlocksEviction(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	if test.ResourceProvider == nil {
+		return true, nil, nil
+	}
+	var deployment appsv1.Deployment
+	if !fromUnstructured(test.Resource, &deployment) || getDeploymentReplicas(deployment) != 1 {
+		return true, nil, nil
+	}
+	if hpa := findHorizontalPodAutoscaler(test.ResourceProvider, test.Resource); hpa != nil && hpa.Spec.MinReplicas != nil && *hpa.Spec.MinReplicas > 1 {
+		return true, nil, nil
+	}
+	podLabels := labels.Set(deployment.Spec.Template.Labels)
+	issues := []jsonschema.ValError{}
+	for _, generic := range test.ResourceProvider.Resources["policy/PodDisruptionBudget"] {
+		var pdb policyv1.PodDisruptionBudget
+		if !fromUnstructured(generic, &pdb) || pdb.Spec.Selector == nil || pdb.Namespace != deployment.Namespace {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || !selector.Matches(podLabels) || !blocksEviction(pdb, 1) {
+			continue
+		}
+		issues = append(issues, jsonschema.ValError{
+			PropertyPath: "spec.replicas",
+			InvalidValue: 1,
+			Message:      fmt.Sprintf("PodDisruptionBudget %q doesn't allow evicting the only replica", pdb.Name),
+		})
+	}
+	return len(issues) == 0, issues, nil
+}
+
+// replicasNotSpreadMutations prefers spreading the pods across nodes, without blocking scheduling
+func replicasNotSpreadMutations(test schemaTestCase) ([]config.Mutation, error) {
+	podLabels := getPodLabels(test.Resource)
+	if test.Resource.PodSpec == nil || len(podLabels) == 0 {
+		return nil, nil
+	}
+	matchLabels := map[string]interface{}{}
+	for key, value := range podLabels {
+		matchLabels[key] = value
+	}
+	constraintsPath := getJSONSchemaPrefix(test.Resource) + "/topologySpreadConstraints"
+	// The check fails when there are no constraints, but the list can be empty
+	return []config.Mutation{{
+		Op:   "remove",
+		Path: constraintsPath,
+	}, {
+		Op:   "add",
+		Path: constraintsPath,
+		Value: []interface{}{
+			map[string]interface{}{
+				"maxSkew":           1,
+				"topologyKey":       corev1.LabelHostname,
+				"whenUnsatisfiable": string(corev1.ScheduleAnyway),
+				"labelSelector": map[string]interface{}{
+					"matchLabels": matchLabels,
+				},
+			},
+		},
+	}}, nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestGetPreStopSleepSeconds(t *testing.T) {
+	testCases := []struct {
+		command  []string
+		expected int64
+	}{
+		{[]string{"sleep", "15"}, 15},
+		{[]string{"/bin/sleep", "20s"}, 20},
+		{[]string{"/bin/sh", "-c", "sleep 5; nginx -s quit"}, 5},
+		{[]string{"/bin/sh", "-c", "sleep 5 && sleep 10"}, 10},
+		{[]string{"/bin/sh", "-c", "nginx -s quit"}, 0},
+		{[]string{"/app/nosleep", "15"}, 0},
+	}
+	for _, tc := range testCases {
+		container := corev1.Container{Lifecycle: &corev1.Lifecycle{PreStop: &corev1.LifecycleHandler{
+			Exec: &corev1.ExecAction{Command: tc.command},
+		}}}
+		assert.Equal(t, tc.expected, getPreStopSleepSeconds(container), tc.command)
+	}
+	container := corev1.Container{Lifecycle: &corev1.Lifecycle{PreStop: &corev1.LifecycleHandler{
+		Sleep: &corev1.SleepAction{Seconds: 25},
+	}}}
+	assert.Equal(t, int64(25), getPreStopSleepSeconds(container))
+	assert.Equal(t, int64(0), getPreStopSleepSeconds(corev1.Container{}))
+}
+
+func TestBlocksEviction(t *testing.T) {
+	pdb := func(minAvailable, maxUnavailable *intstr.IntOrString) policyv1.PodDisruptionBudget {
+		return policyv1.PodDisruptionBudget{Spec: policyv1.PodDisruptionBudgetSpec{MinAvailable: minAvailable, MaxUnavailable: maxUnavailable}}
+	}
+	value := intstr.FromInt32
+	percent := intstr.FromString
+	assert.True(t, blocksEviction(pdb(intOrStringPtr(value(1)), nil), 1))
+	assert.True(t, blocksEviction(pdb(intOrStringPtr(percent("50%")), nil), 1))
+	assert.True(t, blocksEviction(pdb(nil, intOrStringPtr(value(0))), 1))
+	assert.False(t, blocksEviction(pdb(intOrStringPtr(value(0)), nil), 1))
+	assert.False(t, blocksEviction(pdb(nil, intOrStringPtr(percent("10%"))), 1))
+	assert.False(t, blocksEviction(pdb(intOrStringPtr(value(1)), nil), 2))
+	assert.False(t, blocksEviction(pdb(nil, nil), 1))
+}
+
+func TestResolveFenceposts(t *testing.T) {
+	value := intstr.FromInt32
+	percent := intstr.FromString
+	testCases := []struct {
+		maxSurge           *intstr.IntOrString
+		maxUnavailable     intstr.IntOrString
+		replicas           int
+		surge, unavailable int
+	}{
+		{nil, percent("25%"), 4, 1, 1},
+		{nil, percent("25%"), 1, 1, 0},
+		{intOrStringPtr(value(0)), percent("25%"), 1, 0, 1},
+		{intOrStringPtr(percent("0%")), percent("10%"), 3, 0, 1},
+		{intOrStringPtr(value(1)), value(3), 3, 1, 3},
+	}
+	for _, tc := range testCases {
+		surge, unavailable, err := resolveFenceposts(tc.maxSurge, &tc.maxUnavailable, tc.replicas)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.surge, surge, tc)
+		assert.Equal(t, tc.unavailable, unavailable, tc)
+	}
+	_, _, err := resolveFenceposts(nil, intOrStringPtr(percent("a lot")), 3)
+	assert.Error(t, err)
+}
+
+func intOrStringPtr(value intstr.IntOrString) *intstr.IntOrString {
+	return &value
+}
@@ -20,6 +20,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/qri-io/jsonschema"
 	"github.com/sirupsen/logrus"
@@ -27,17 +28,43 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/fairwindsops/polaris/pkg/config"
 	"github.com/fairwindsops/polaris/pkg/kube"
 )
 
 type schemaTestCase struct {
-	Target           config.TargetKind
-	Resource         kube.GenericResource
-	IsInitContainer  bool
-	Container        *corev1.Container
-	ResourceProvider *kube.ResourceProvider
+	Target            config.TargetKind
+	Resource          kube.GenericResource
+	ContainerClass    config.ContainerClass
+	Container         *corev1.Container
+	ResourceProvider  *kube.ResourceProvider
+	KubernetesVersion config.KubernetesVersion
+	Config            *config.Configuration
+	Audit             *auditContext
+}
+
+// auditContext holds what checks derive from the whole ResourceProvider, so it's computed once per audit
+// instead of once per resource. Each part is computed the first time a check needs it.
+type auditContext struct {
+	provider         *kube.ResourceProvider
+	crdSchemasOnce   sync.Once
+	crdSchemas       map[schema.GroupVersionKind]*openAPINode
+	rbacSubjectsOnce sync.Once
+	rbacSubjects     []RBACSubject
+}
+
+func newAuditContext(resourceProvider *kube.ResourceProvider) *auditContext {
+	return &auditContext{provider: resourceProvider}
+}
+
+// getProvider returns the audited ResourceProvider. A nil auditContext has none, e.g. in the admission controller.
+func (audit *auditContext) getProvider() *kube.ResourceProvider {
+	if audit == nil {
+		return nil
+	}
+	return audit.provider
 }
 
 // ShortString supplies some fields of a schemaTestCase suitable for brief
@@ -80,7 +107,18 @@ func resolveCheck(conf *config.Configuration, checkID string, test schemaTestCas
 	if !conf.IsActionable(check.ID, test.Resource.ObjectMeta, containerName) {
 		return nil, nil
 	}
-	if !check.IsActionable(test.Target, test.Resource.Kind, test.IsInitContainer) {
+	if !check.IsApplicableToVersion(test.KubernetesVersion) {
+		return nil, nil
+	}
+	check = check.ForKubernetesVersion(test.KubernetesVersion)
+	if !check.IsActionable(test.Target, test.Resource.Kind, test.ContainerClass) {
+		return nil, nil
+	}
+	// Pod-level checks would pass vacuously on the empty pod spec of kinds that only have containers
+	if test.Target == config.TargetPodSpec && test.Resource.HasContainersOnly() {
+		return nil, nil
+	}
+	if applies := applicabilityMapper[checkID]; applies != nil && !applies(test) {
 		return nil, nil
 	}
 	templateInput, err := getTemplateInput(test)
@@ -94,6 +132,26 @@ func resolveCheck(conf *config.Configuration, checkID string, test schemaTestCas
 	return checkPtr, nil
 }
 
+// getKubernetesVersion returns the Kubernetes version checks should be resolved against.
+// An explicitly configured version takes precedence over the one reported by the cluster.
+func getKubernetesVersion(conf *config.Configuration, resourceProvider *kube.ResourceProvider) config.KubernetesVersion {
+	if conf.KubernetesVersion != "" {
+		version, err := config.ParseKubernetesVersion(conf.KubernetesVersion)
+		if err == nil {
+			return version
+		}
+		logrus.Warnf("ignoring invalid Kubernetes version %s: %v", conf.KubernetesVersion, err)
+	}
+	if resourceProvider != nil {
+		version, err := config.ParseKubernetesVersion(resourceProvider.ServerVersion)
+		if err == nil {
+			return version
+		}
+		logrus.Debugf("could not parse server version %s: %v", resourceProvider.ServerVersion, err)
+	}
+	return config.KubernetesVersion{}
+}
+
 // getTemplateInput augments a schemaTestCase.Resource.Resource.Object with
 // Polaris built-in variables. The result can be used as input for
 // CheckSchema.TemplateForResource().
@@ -118,6 +176,16 @@ func getTemplateInput(test schemaTestCase) (map[string]interface{}, error) {
 				return nil, err
 			}
 		}
+		if !test.KubernetesVersion.IsZero() {
+			versionMap := map[string]interface{}{
+				"Major": int64(test.KubernetesVersion.Major),
+				"Minor": int64(test.KubernetesVersion.Minor),
+			}
+			err := unstructured.SetNestedMap(templateInput, versionMap, "Polaris", "KubernetesVersion")
+			if err != nil {
+				return nil, err
+			}
+		}
 		if test.Target == config.TargetContainer {
 			containerMap, err := kube.SerializeContainer(test.Container)
 			if err != nil {
@@ -127,24 +195,25 @@ func getTemplateInput(test schemaTestCase) (map[string]interface{}, error) {
 			if err != nil {
 				return nil, err
 			}
+			err = unstructured.SetNestedField(templateInput, string(test.ContainerClass), "Polaris", "ContainerClass")
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 	logrus.Debugf("the go template input for schema test-case %s is: %v", test.ShortString(), templateInput)
 	return templateInput, nil
 }
 
-func makeResult(conf *config.Configuration, check *config.SchemaCheck, passes bool, issues []jsonschema.ValError) ResultMessage {
-	details := []string{}
-	for _, issue := range issues {
-		details = append(details, issue.Message)
-	}
+// makeResult builds the result of a check. Only checks implemented in Go have details, since
+// JSON Schema issues describe the schema rather than the resource.
+func makeResult(conf *config.Configuration, check *config.SchemaCheck, passes bool, details []string) ResultMessage {
 	result := ResultMessage{
 		ID:       check.ID,
 		Severity: conf.Checks[check.ID],
 		Category: check.Category,
 		Success:  passes,
-		// FIXME: need to fix the tests before adding this back
-		//Details: details,
+		Details:  details,
 	}
 	if passes {
 		result.Message = check.SuccessMessage
@@ -173,12 +242,17 @@ func hasExemptionAnnotation(objMeta metaV1.Object, checkID string) bool {
 
 // ApplyAllSchemaChecksToResourceProvider applies all available checks to a ResourceProvider
 func ApplyAllSchemaChecksToResourceProvider(conf *config.Configuration, resourceProvider *kube.ResourceProvider) ([]Result, error) {
-	results := []Result{}
 	if resourceProvider == nil {
 		return nil, errors.New("No resource provider set, cannot apply schema checks")
 	}
+	return applyAllSchemaChecksToResourceProvider(conf, newAuditContext(resourceProvider))
+}
+
+func applyAllSchemaChecksToResourceProvider(conf *config.Configuration, audit *auditContext) ([]Result, error) {
+	resourceProvider := audit.getProvider()
+	results := []Result{}
 	for _, resources := range resourceProvider.Resources {
-		kindResults, err := ApplyAllSchemaChecksToAllResources(conf, resourceProvider, resources)
+		kindResults, err := applyAllSchemaChecksToAllResources(conf, audit, resources)
 		if err != nil {
 			return results, err
 		}
@@ -189,9 +263,13 @@ func ApplyAllSchemaChecksToResourceProvider(conf *config.Configuration, resource
 
 // ApplyAllSchemaChecksToAllResources applies available checks to a list of resources
 func ApplyAllSchemaChecksToAllResources(conf *config.Configuration, resourceProvider *kube.ResourceProvider, resources []kube.GenericResource) ([]Result, error) {
+	return applyAllSchemaChecksToAllResources(conf, newAuditContext(resourceProvider), resources)
+}
+
+func applyAllSchemaChecksToAllResources(conf *config.Configuration, audit *auditContext, resources []kube.GenericResource) ([]Result, error) {
 	results := []Result{}
 	for _, resource := range resources {
-		result, err := ApplyAllSchemaChecks(conf, resourceProvider, resource)
+		result, err := applyAllSchemaChecks(conf, audit, resource)
 		if err != nil {
 			return results, err
 		}
@@ -204,36 +282,52 @@ func ApplyAllSchemaChecksToAllResources(conf *config.Configuration, resourceProv
 
 // ApplyAllSchemaChecks applies available checks to a single resource
 func ApplyAllSchemaChecks(conf *config.Configuration, resourceProvider *kube.ResourceProvider, resource kube.GenericResource) (Result, error) {
+	return applyAllSchemaChecks(conf, newAuditContext(resourceProvider), resource)
+}
+
+func applyAllSchemaChecks(conf *config.Configuration, audit *auditContext, resource kube.GenericResource) (Result, error) {
 	if resource.PodSpec == nil {
-		return applyNonControllerSchemaChecks(conf, resourceProvider, resource)
+		return applyNonControllerSchemaChecks(conf, audit, resource)
 	}
-	return applyControllerSchemaChecks(conf, resourceProvider, resource)
+	return applyControllerSchemaChecks(conf, audit, resource)
 }
 
-func applyNonControllerSchemaChecks(conf *config.Configuration, resourceProvider *kube.ResourceProvider, resource kube.GenericResource) (Result, error) {
+func applyNonControllerSchemaChecks(conf *config.Configuration, audit *auditContext, resource kube.GenericResource) (Result, error) {
+	resourceProvider := audit.getProvider()
 	finalResult := Result{
 		Kind:      resource.Kind,
 		Name:      resource.ObjectMeta.GetName(),
 		Namespace: resource.ObjectMeta.GetNamespace(),
 	}
-	resultSet, err := applyTopLevelSchemaChecks(conf, resourceProvider, resource, false)
+	resultSet, err := applyTopLevelSchemaChecks(conf, audit, resource, false)
+	if err != nil {
+		return finalResult, err
+	}
 	finalResult.Results = resultSet
+	finalResult.setRisk(conf, resourceProvider, resource)
+	if finalResult.Owner, err = getOwner(conf, resourceProvider, resource); err != nil {
+		return finalResult, err
+	}
+	if conf.ComplianceReport {
+		finalResult.Exemptions, err = getExemptions(conf, audit, resource)
+	}
 	return finalResult, err
 }
 
-func applyControllerSchemaChecks(conf *config.Configuration, resourceProvider *kube.ResourceProvider, resource kube.GenericResource) (Result, error) {
+func applyControllerSchemaChecks(conf *config.Configuration, audit *auditContext, resource kube.GenericResource) (Result, error) {
+	resourceProvider := audit.getProvider()
 	finalResult := Result{
 		Kind:      resource.Kind,
 		Name:      resource.ObjectMeta.GetName(),
 		Namespace: resource.ObjectMeta.GetNamespace(),
 	}
-	resultSet, err := applyTopLevelSchemaChecks(conf, resourceProvider, resource, true)
+	resultSet, err := applyTopLevelSchemaChecks(conf, audit, resource, true)
 	if err != nil {
 		return finalResult, err
 	}
 	finalResult.Results = resultSet
 
-	nonControllerResults, err := applyTopLevelSchemaChecks(conf, resourceProvider, resource, false)
+	nonControllerResults, err := applyTopLevelSchemaChecks(conf, audit, resource, false)
 	if err != nil {
 		return finalResult, err
 	}
@@ -244,7 +338,7 @@ func applyControllerSchemaChecks(conf *config.Configuration, resourceProvider *k
 		finalResult.Results[key] = val
 	}
 
-	podRS, err := applyPodSchemaChecks(conf, resourceProvider, resource)
+	podRS, err := applyPodSchemaChecks(conf, audit, resource)
 	if err != nil {
 		return finalResult, err
 	}
@@ -255,35 +349,63 @@ func applyControllerSchemaChecks(conf *config.Configuration, resourceProvider *k
 	finalResult.PodResult = &podRes
 
 	for _, container := range resource.PodSpec.InitContainers {
-		results, err := applyContainerSchemaChecks(conf, resourceProvider, resource, &container, true)
+		results, err := applyContainerSchemaChecks(conf, audit, resource, &container, config.ContainerClassInit)
 		if err != nil {
 			return finalResult, err
 		}
 		cRes := ContainerResult{
-			Name:    container.Name,
-			Results: results,
+			Name:           container.Name,
+			ContainerClass: config.ContainerClassInit,
+			Results:        results,
 		}
 		podRes.ContainerResults = append(podRes.ContainerResults, cRes)
 	}
 	for _, container := range resource.PodSpec.Containers {
-		results, err := applyContainerSchemaChecks(conf, resourceProvider, resource, &container, false)
+		results, err := applyContainerSchemaChecks(conf, audit, resource, &container, config.ContainerClassContainer)
 		if err != nil {
 			return finalResult, err
 		}
 		cRes := ContainerResult{
-			Name:    container.Name,
-			Results: results,
+			Name:           container.Name,
+			ContainerClass: config.ContainerClassContainer,
+			Results:        results,
 		}
 		podRes.ContainerResults = append(podRes.ContainerResults, cRes)
 	}
-
-	return finalResult, nil
+	for _, ephemeralContainer := range resource.PodSpec.EphemeralContainers {
+		container := corev1.Container(ephemeralContainer.EphemeralContainerCommon)
+		results, err := applyContainerSchemaChecks(conf, audit, resource, &container, config.ContainerClassEphemeral)
+		if err != nil {
+			return finalResult, err
+		}
+		cRes := ContainerResult{
+			Name:           container.Name,
+			ContainerClass: config.ContainerClassEphemeral,
+			Results:        results,
+		}
+		podRes.ContainerResults = append(podRes.ContainerResults, cRes)
+	}
+	finalResult.PodSecurityLevel = finalResult.getPodSecurityLevel()
+	if _, ok := finalResult.Results["missingNetworkPolicy"]; ok && resourceProvider != nil {
+		coverage := getNetworkPolicyCoverage(resource, getNetworkPolicies(resourceProvider.Resources[networkPolicyKind]))
+		finalResult.NetworkPolicy = &coverage
+	}
+	finalResult.ServiceAccount = getWorkloadServiceAccount(audit, resource)
+	finalResult.setRisk(conf, resourceProvider, resource)
+	if finalResult.Owner, err = getOwner(conf, resourceProvider, resource); err != nil {
+		return finalResult, err
+	}
+	if conf.ComplianceReport {
+		finalResult.Exemptions, err = getExemptions(conf, audit, resource)
+	}
+	return finalResult, err
 }
 
-func applyTopLevelSchemaChecks(conf *config.Configuration, resources *kube.ResourceProvider, res kube.GenericResource, isController bool) (ResultSet, error) {
+func applyTopLevelSchemaChecks(conf *config.Configuration, audit *auditContext, res kube.GenericResource, isController bool) (ResultSet, error) {
 	test := schemaTestCase{
-		ResourceProvider: resources,
+		ResourceProvider: audit.getProvider(),
 		Resource:         res,
+		Audit:            audit,
 	}
 	if isController {
 		test.Target = config.TargetController
@@ -291,28 +413,82 @@ func applyTopLevelSchemaChecks(conf *config.Configuration, resources *kube.Resou
 	return applySchemaChecks(conf, test)
 }
 
-func applyPodSchemaChecks(conf *config.Configuration, resources *kube.ResourceProvider, controller kube.GenericResource) (ResultSet, error) {
+func applyPodSchemaChecks(conf *config.Configuration, audit *auditContext, controller kube.GenericResource) (ResultSet, error) {
 	test := schemaTestCase{
 		Target:           config.TargetPodSpec,
-		ResourceProvider: resources,
+		ResourceProvider: audit.getProvider(),
 		Resource:         controller,
+		Audit:            audit,
 	}
 	return applySchemaChecks(conf, test)
 }
 
-func applyContainerSchemaChecks(conf *config.Configuration, resources *kube.ResourceProvider, controller kube.GenericResource, container *corev1.Container, isInit bool) (ResultSet, error) {
+func applyContainerSchemaChecks(conf *config.Configuration, audit *auditContext, controller kube.GenericResource, container *corev1.Container, containerClass config.ContainerClass) (ResultSet, error) {
 	test := schemaTestCase{
 		Target:           config.TargetContainer,
-		ResourceProvider: resources,
+		ResourceProvider: audit.getProvider(),
 		Resource:         controller,
 		Container:        container,
-		IsInitContainer:  isInit,
+		ContainerClass:   containerClass,
+		Audit:            audit,
 	}
 	return applySchemaChecks(conf, test)
 }
 
+// getExemptions lists the checks that apply to the resource or one of its containers,
+// but were skipped because of an exemption
+func getExemptions(conf *config.Configuration, audit *auditContext, resource kube.GenericResource) ([]string, error) {
+	if conf.DisallowExemptions {
+		return nil, nil
+	}
+	tests := []schemaTestCase{{Resource: resource}}
+	if resource.PodSpec != nil {
+		tests = append(tests,
+			schemaTestCase{Target: config.TargetController, Resource: resource},
+			schemaTestCase{Target: config.TargetPodSpec, Resource: resource})
+		for idx := range resource.PodSpec.InitContainers {
+			tests = append(tests, schemaTestCase{Target: config.TargetContainer, Resource: resource, Container: &resource.PodSpec.InitContainers[idx], ContainerClass: config.ContainerClassInit})
+		}
+		for idx := range resource.PodSpec.Containers {
+			tests = append(tests, schemaTestCase{Target: config.TargetContainer, Resource: resource, Container: &resource.PodSpec.Containers[idx], ContainerClass: config.ContainerClassContainer})
+		}
+		for _, ephemeralContainer := range resource.PodSpec.EphemeralContainers {
+			container := corev1.Container(ephemeralContainer.EphemeralContainerCommon)
+			tests = append(tests, schemaTestCase{Target: config.TargetContainer, Resource: resource, Container: &container, ContainerClass: config.ContainerClassEphemeral})
+		}
+	}
+	withoutExemptions := *conf
+	withoutExemptions.DisallowExemptions = true
+	exemptions := []string{}
+	for _, test := range tests {
+		test.ResourceProvider = audit.getProvider()
+		test.Audit = audit
+		test.KubernetesVersion = getKubernetesVersion(conf, test.ResourceProvider)
+		for _, checkID := range getSortedKeys(conf.Checks) {
+			if funk.ContainsString(exemptions, checkID) {
+				continue
+			}
+			check, err := resolveCheck(conf, checkID, test)
+			if err != nil || check != nil {
+				continue
+			}
+			check, err = resolveCheck(&withoutExemptions, checkID, test)
+			if err != nil {
+				return nil, err
+			}
+			if check != nil {
+				exemptions = append(exemptions, checkID)
+			}
+		}
+	}
+	sort.Strings(exemptions)
+	return exemptions, nil
+}
+
 func applySchemaChecks(conf *config.Configuration, test schemaTestCase) (ResultSet, error) {
 	results := ResultSet{}
+	test.KubernetesVersion = getKubernetesVersion(conf, test.ResourceProvider)
+	test.Config = conf
 	checkIDs := getSortedKeys(conf.Checks)
 	for _, checkID := range checkIDs {
 		result, err := applySchemaCheck(conf, checkID, test)
@@ -336,41 +512,33 @@ func applySchemaCheck(conf *config.Configuration, checkID string, test schemaTes
 	var passes bool
 	var issues []jsonschema.ValError
 	var prefix string
+	var details []string
 	if check.SchemaTarget != "" {
 		if check.SchemaTarget == config.TargetPodSpec && check.Target == config.TargetContainer {
 			podCopy := *test.Resource.PodSpec
 			podCopy.InitContainers = []corev1.Container{}
 			podCopy.Containers = []corev1.Container{*test.Container}
-			containerIndex := funk.IndexOf(test.Resource.PodSpec.Containers, func(value corev1.Container) bool {
-				return value.Name == test.Container.Name
-			})
-			prefix = getJSONSchemaPrefix(test.Resource.Kind)
-			if prefix != "" {
-				prefix += "/containers/" + strconv.Itoa(containerIndex)
-			}
+			prefix = getContainerJSONSchemaPrefix(test.Resource, test.Container, test.ContainerClass)
 			passes, issues, err = check.CheckPodSpec(&podCopy)
 		} else {
 			return nil, fmt.Errorf("Unknown combination of target (%s) and schema target (%s)", check.Target, check.SchemaTarget)
 		}
 	} else if check.Target == config.TargetPodSpec {
 		passes, issues, err = check.CheckPodSpec(test.Resource.PodSpec)
-		prefix = getJSONSchemaPrefix(test.Resource.Kind)
+		prefix = getJSONSchemaPrefix(test.Resource)
 	} else if check.Target == config.TargetPodTemplate {
 		passes, issues, err = check.CheckPodTemplate(test.Resource.PodTemplate)
-		prefix = getJSONSchemaPrefix(test.Resource.Kind)
+		prefix = getJSONSchemaPrefix(test.Resource)
 	} else if check.Target == config.TargetContainer {
-		containerIndex := funk.IndexOf(test.Resource.PodSpec.Containers, func(value corev1.Container) bool {
-			return value.Name == test.Container.Name
-		})
-		prefix = getJSONSchemaPrefix(test.Resource.Kind)
-		if prefix != "" {
-			prefix += "/containers/" + strconv.Itoa(containerIndex)
-		}
+		prefix = getContainerJSONSchemaPrefix(test.Resource, test.Container, test.ContainerClass)
 		passes, issues, err = check.CheckContainer(test.Container)
 	} else if check.Validator.SchemaURI != "" {
 		passes, issues, err = check.CheckObject(test.Resource.Resource.Object)
 	} else if validatorMapper[checkID] != nil {
 		passes, issues, err = validatorMapper[checkID](test)
+		for _, issue := range issues {
+			details = append(details, issue.Message)
+		}
 	} else {
 		passes, issues, err = true, []jsonschema.ValError{}, nil
 	}
@@ -411,7 +579,7 @@ func applySchemaCheck(conf *config.Configuration, checkID string, test schemaTes
 		logrus.Debugf("there were no issues validating the schema for test-case %s", test.ShortString())
 
 	}
-	result := makeResult(conf, check, passes, issues)
+	result := makeResult(conf, check, passes, details)
 	if funk.Contains(conf.Mutations, checkID) && len(check.Mutations) > 0 {
 		mutations := funk.Map(check.Mutations, func(mutation config.Mutation) config.Mutation {
 			mutationCopy := deepCopyMutation(mutation)
@@ -419,6 +587,12 @@ func applySchemaCheck(conf *config.Configuration, checkID string, test schemaTes
 			return mutationCopy
 		}).([]config.Mutation)
 		result.Mutations = mutations
+	} else if funk.Contains(conf.Mutations, checkID) && mutationMapper[checkID] != nil && !passes {
+		mutations, err := mutationMapper[checkID](test)
+		if err != nil {
+			return nil, err
+		}
+		result.Mutations = mutations
 	}
 	return &result, nil
 }
@@ -442,7 +616,11 @@ func deepCopyMutation(source config.Mutation) config.Mutation {
 	return destination
 }
 
-func getJSONSchemaPrefix(kind string) (prefix string) {
+func getJSONSchemaPrefix(resource kube.GenericResource) (prefix string) {
+	if resource.PodSpecPath != "" {
+		return resource.PodSpecPath
+	}
+	kind := resource.Kind
 	if kind == "CronJob" {
 		prefix = "/spec/jobTemplate/spec/template/spec"
 	} else if kind == "Pod" {
@@ -453,3 +631,33 @@ func getJSONSchemaPrefix(kind string) (prefix string) {
 	}
 	return prefix
 }
+
+func getContainerJSONSchemaPrefix(resource kube.GenericResource, container *corev1.Container, containerClass config.ContainerClass) string {
+	var containerIndex int
+	var field string
+	switch containerClass {
+	case config.ContainerClassInit:
+		field = "initContainers"
+		containerIndex = funk.IndexOf(resource.PodSpec.InitContainers, func(value corev1.Container) bool {
+			return value.Name == container.Name
+		})
+	case config.ContainerClassEphemeral:
+		field = "ephemeralContainers"
+		containerIndex = funk.IndexOf(resource.PodSpec.EphemeralContainers, func(value corev1.EphemeralContainer) bool {
+			return value.Name == container.Name
+		})
+	default:
+		field = "containers"
+		containerIndex = funk.IndexOf(resource.PodSpec.Containers, func(value corev1.Container) bool {
+			return value.Name == container.Name
+		})
+		if containerIndex >= 0 && containerIndex < len(resource.ContainerPaths) {
+			return resource.ContainerPaths[containerIndex]
+		}
+	}
+	prefix := getJSONSchemaPrefix(resource)
+	if prefix != "" {
+		prefix += "/" + field + "/" + strconv.Itoa(containerIndex)
+	}
+	return prefix
+}
@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	conf "github.com/fairwindsops/polaris/pkg/config"
+	"github.com/fairwindsops/polaris/pkg/kube"
 
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
@@ -158,14 +159,14 @@ func TestValidateResourcesInit(t *testing.T) {
 	assert.NoError(t, err, "Expected no error when parsing config")
 
 	var results ResultSet
-	results, err = applyContainerSchemaChecks(&parsedConf, nil, controller, emptyContainer, false)
+	results, err = applyContainerSchemaChecks(&parsedConf, nil, controller, emptyContainer, conf.ContainerClassContainer)
 	if err != nil {
 		panic(err)
 	}
 	assert.Equal(t, uint(1), results.GetSummary().Dangers)
 	assert.Equal(t, uint(1), results.GetSummary().Warnings)
 
-	results, err = applyContainerSchemaChecks(&parsedConf, nil, controller, emptyContainer, true)
+	results, err = applyContainerSchemaChecks(&parsedConf, nil, controller, emptyContainer, conf.ContainerClassInit)
 	if err != nil {
 		panic(err)
 	}
@@ -275,3 +276,181 @@ func TestValidateCustomCheckExemptions(t *testing.T) {
 	}
 	testValidate(t, &container, &customCheckExemptions, "notexempt", expectedDangers, expectedWarnings, expectedSuccesses)
 }
+
+var versionedCheckConf = `
+checks:
+  hostUsers: danger
+customChecks:
+  hostUsers:
+    successMessage: Pod runs in a user namespace
+    failureMessage: Pod should run in a user namespace
+    category: Security
+    target: PodSpec
+    minKubernetesVersion: "1.25"
+    schema:
+      type: object
+      required: ["hostUsers"]
+`
+
+func TestKubernetesVersionAwareChecks(t *testing.T) {
+	parsedConf, err := conf.Parse([]byte(versionedCheckConf))
+	assert.NoError(t, err)
+	workload := getEmptyWorkload(t, "foo")
+	provider := &kube.ResourceProvider{ServerVersion: "1.24"}
+
+	results, err := applyPodSchemaChecks(&parsedConf, newAuditContext(provider), workload)
+	assert.NoError(t, err)
+	assert.Len(t, results, 0)
+
+	provider.ServerVersion = "1.27+"
+	results, err = applyPodSchemaChecks(&parsedConf, newAuditContext(provider), workload)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), results.GetSummary().Dangers)
+
+	// An explicit version takes precedence over the server version
+	parsedConf.KubernetesVersion = "1.24"
+	results, err = applyPodSchemaChecks(&parsedConf, newAuditContext(provider), workload)
+	assert.NoError(t, err)
+	assert.Len(t, results, 0)
+
+	// Unknown versions run every check
+	parsedConf.KubernetesVersion = ""
+	provider.ServerVersion = "unknown"
+	results, err = applyPodSchemaChecks(&parsedConf, newAuditContext(provider), workload)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), results.GetSummary().Dangers)
+}
+
+func TestPodSpecPathsMutations(t *testing.T) {
+	c := conf.Configuration{
+		Checks: map[string]conf.Severity{
+			"pullPolicyNotAlways": conf.SeverityWarning,
+			"hostIPCSet":          conf.SeverityDanger,
+		},
+		Mutations: []string{"pullPolicyNotAlways", "hostIPCSet"},
+		PodSpecPaths: map[string]conf.PodSpecPaths{
+			"tekton.dev/Task": {
+				Containers: []string{"spec.steps"},
+			},
+		},
+	}
+	provider, err := kube.CreateResourceProviderFromYaml(`
+apiVersion: tekton.dev/v1
+kind: Task
+metadata:
+  name: build
+spec:
+  steps:
+    - name: compile
+      image: golang:1.22
+      imagePullPolicy: Always
+    - name: publish
+      image: alpine:3.19
+`)
+	assert.NoError(t, err)
+	assert.NoError(t, provider.ApplyPodSpecPaths(c.PodSpecPaths))
+
+	results, err := ApplyAllSchemaChecksToResourceProvider(&c, provider)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	containerResults := results[0].PodResult.ContainerResults
+	assert.Len(t, containerResults, 2)
+	assert.True(t, containerResults[0].Results["pullPolicyNotAlways"].Success)
+	publish := containerResults[1].Results["pullPolicyNotAlways"]
+	assert.False(t, publish.Success)
+	assert.Equal(t, "/spec/steps/1/imagePullPolicy", publish.Mutations[0].Path)
+	// Pod-level checks don't apply, since there is no pod spec to check or mutate
+	assert.NotContains(t, results[0].PodResult.Results, "hostIPCSet")
+}
+
+func TestEphemeralContainers(t *testing.T) {
+	c := conf.Configuration{
+		Checks: map[string]conf.Severity{
+			"pullPolicyNotAlways":  conf.SeverityWarning,
+			"livenessProbeMissing": conf.SeverityWarning,
+		},
+		Mutations: []string{"pullPolicyNotAlways"},
+	}
+	provider, err := kube.CreateResourceProviderFromYaml(`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: nginx
+spec:
+  initContainers:
+    - name: setup
+      image: busybox:1.36
+  containers:
+    - name: nginx
+      image: nginx:1.25
+      imagePullPolicy: Always
+  ephemeralContainers:
+    - name: debugger
+      image: busybox:1.36
+`)
+	assert.NoError(t, err)
+
+	results, err := ApplyAllSchemaChecksToResourceProvider(&c, provider)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	containerResults := results[0].PodResult.ContainerResults
+	assert.Len(t, containerResults, 3)
+
+	assert.Equal(t, "setup", containerResults[0].Name)
+	assert.Equal(t, conf.ContainerClassInit, containerResults[0].ContainerClass)
+	assert.Equal(t, "/spec/initContainers/0/imagePullPolicy", containerResults[0].Results["pullPolicyNotAlways"].Mutations[0].Path)
+
+	assert.Equal(t, "nginx", containerResults[1].Name)
+	assert.Equal(t, conf.ContainerClassContainer, containerResults[1].ContainerClass)
+	assert.Contains(t, containerResults[1].Results, "livenessProbeMissing")
+
+	debugger := containerResults[2]
+	assert.Equal(t, "debugger", debugger.Name)
+	assert.Equal(t, conf.ContainerClassEphemeral, debugger.ContainerClass)
+	assert.NotContains(t, debugger.Results, "livenessProbeMissing")
+	assert.False(t, debugger.Results["pullPolicyNotAlways"].Success)
+	assert.Equal(t, "/spec/ephemeralContainers/0/imagePullPolicy", debugger.Results["pullPolicyNotAlways"].Mutations[0].Path)
+}
+
+func TestPodOverhead(t *testing.T) {
+	c := conf.Configuration{
+		Checks: map[string]conf.Severity{
+			"podOverheadMismatch": conf.SeverityWarning,
+		},
+	}
+	provider, err := kube.CreateResourceProviderFromYaml(`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: runc
+spec:
+  containers:
+    - name: nginx
+      image: nginx:1.25
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: sandboxed
+spec:
+  overhead:
+    cpu: 250m
+  containers:
+    - name: nginx
+      image: nginx:1.25
+`)
+	assert.NoError(t, err)
+
+	results, err := ApplyAllSchemaChecksToResourceProvider(&c, provider)
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	for _, result := range results {
+		if result.Name == "runc" {
+			// Pods without overhead are left out rather than passing
+			assert.NotContains(t, result.Results, "podOverheadMismatch")
+		} else {
+			assert.Contains(t, result.Results, "podOverheadMismatch")
+			assert.False(t, result.Results["podOverheadMismatch"].Success)
+		}
+	}
+}
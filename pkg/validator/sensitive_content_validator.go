@@ -0,0 +1,274 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/qri-io/jsonschema"
+	"github.com/thoas/go-funk"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// Tokens shorter than this are too short for their entropy to mean anything
+	minEntropyTokenLength = 24
+	// Random base64 strings of minEntropyTokenLength characters are above this, while words and identifiers are below
+	minTokenEntropy = 4.0
+	// Random strings switch between lower case, upper case and digits about every other character, while
+	// identifiers made of words like MyApplicationConfiguration2024 have high entropy but only switch between words
+	minCharClassChanges = 0.35
+)
+
+// secretPattern matches a kind of credential. When the expression has a group, only the group is
+// redacted in the evidence, e.g. the password of a URL.
+type secretPattern struct {
+	name       string
+	expression *regexp.Regexp
+	// showMatch is set for patterns that don't match the secret itself, e.g. the header of a private key
+	showMatch bool
+}
+
+var secretPatterns = []secretPattern{
+	{name: "a private key", expression: regexp.MustCompile(`-----BEGIN[A-Z ]*PRIVATE KEY(?: BLOCK)?-----`), showMatch: true},
+	{name: "an AWS access key ID", expression: regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`)},
+	{name: "a Google API key", expression: regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}\b`)},
+	{name: "an Azure storage account key", expression: regexp.MustCompile(`AccountKey=([A-Za-z0-9+/]{40,}={0,2})`)},
+	{name: "a GitHub token", expression: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{name: "a GitLab token", expression: regexp.MustCompile(`\bglpat-[A-Za-z0-9_\-]{20,}`)},
+	{name: "a Slack token", expression: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9\-]{10,}`)},
+	{name: "a Slack webhook URL", expression: regexp.MustCompile(`https://hooks\.slack\.com/services/T[A-Z0-9]+/B[A-Z0-9]+/([A-Za-z0-9]+)`)},
+	{name: "a Stripe API key", expression: regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{24,}\b`)},
+	{name: "a JSON Web Token", expression: regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]{10,}\.eyJ[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}`)},
+	// Passwords that are variable references, e.g. $(DB_PASSWORD) or ${DB_PASSWORD}, are expanded from elsewhere
+	{name: "a URL with a password", expression: regexp.MustCompile(`\b[a-zA-Z][a-zA-Z0-9+.\-]*://[^:/\s@]*:([^$@/\s{][^@/\s]*)@`)},
+}
+
+var entropyTokenSplitter = regexp.MustCompile(`[^A-Za-z0-9+/=_\-]+`)
+var hexToken = regexp.MustCompile(`^[0-9a-fA-F\-]+$`)
+
+// pathSegment matches the segments of a path, e.g. the resource of an ARN
+var pathSegment = regexp.MustCompile(`[^/]+`)
+
+// pemBlock matches certificates, public keys and private keys, which are random but only private keys are secret
+var pemBlock = regexp.MustCompile(`(?s)-----BEGIN [A-Z0-9 ]+-----.*?-----END [A-Z0-9 ]+-----`)
+
+// base64PEMPrefix is "-----BEGIN" encoded in base64, e.g. certificate-authority-data in a kubeconfig
+const base64PEMPrefix = "LS0tLS1CRUdJTi"
+
+func init() {
+	registerCustomChecks("sensitiveContentDetected", sensitiveContentDetected)
+}
+
+// redactSecret keeps the first characters of a secret, enough to find it without disclosing it
+func redactSecret(secret string) string {
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return secret[:4] + "****"
+}
+
+// shannonEntropy returns the average number of bits of information per character
+func shannonEntropy(value string) float64 {
+	if value == "" {
+		return 0
+	}
+	counts := map[rune]int{}
+	for _, char := range value {
+		counts[char]++
+	}
+	length := float64(len(value))
+	entropy := 0.0
+	for _, count := range counts {
+		frequency := float64(count) / length
+		entropy -= frequency * math.Log2(frequency)
+	}
+	return entropy
+}
+
+// isHighEntropyToken returns true if a token looks like a randomly generated key
+func isHighEntropyToken(token string) bool {
+	if len(token) < minEntropyTokenLength || hexToken.MatchString(token) {
+		return false
+	}
+	if !strings.ContainsAny(token, "0123456789") ||
+		strings.ToLower(token) == token || strings.ToUpper(token) == token {
+		return false
+	}
+	return shannonEntropy(token) >= minTokenEntropy && charClassChanges(token) >= minCharClassChanges
+}
+
+// entropyCandidates returns the start and end of each part of a token whose entropy is tested. The key of a
+// key=value pair, e.g. --password=..., is skipped, and paths, which unlike base64 strings mix slashes with
+// dashes or underscores, are split into their segments.
+func entropyCandidates(token string) [][]int {
+	start := 0
+	// Equal signs at the end are base64 padding
+	if idx := strings.LastIndex(strings.TrimRight(token, "="), "="); idx >= 0 {
+		start = idx + 1
+	}
+	value := token[start:]
+	if !strings.Contains(value, "/") || !strings.ContainsAny(value, "-_") {
+		return [][]int{{start, len(token)}}
+	}
+	candidates := [][]int{}
+	for _, segment := range pathSegment.FindAllStringIndex(value, -1) {
+		candidates = append(candidates, []int{start + segment[0], start + segment[1]})
+	}
+	return candidates
+}
+
+// charClassChanges returns how often consecutive characters differ in being lower case, upper case, digits or symbols
+func charClassChanges(value string) float64 {
+	charClass := func(char rune) int {
+		switch {
+		case unicode.IsLower(char):
+			return 0
+		case unicode.IsUpper(char):
+			return 1
+		case unicode.IsDigit(char):
+			return 2
+		}
+		return 3
+	}
+	changes := 0
+	previous := -1
+	for _, char := range value {
+		class := charClass(char)
+		if previous >= 0 && class != previous {
+			changes++
+		}
+		previous = class
+	}
+	return float64(changes) / float64(len(value))
+}
+
+// findSecrets returns a redacted description of each credential found in a value
+func findSecrets(value string) []string {
+	findings := []string{}
+	remaining := pemBlock.ReplaceAllString(value, " ")
+	for _, pattern := range secretPatterns {
+		for _, match := range pattern.expression.FindAllStringSubmatchIndex(value, -1) {
+			evidence := value[match[0]:match[1]]
+			if len(match) > 2 && match[2] >= 0 {
+				evidence = value[match[0]:match[2]] + redactSecret(value[match[2]:match[3]]) + value[match[3]:match[1]]
+			} else if !pattern.showMatch {
+				evidence = redactSecret(evidence)
+			}
+			findings = append(findings, fmt.Sprintf("%s: %s", pattern.name, evidence))
+		}
+		// Matches are only reported once, not as high-entropy strings too
+		remaining = pattern.expression.ReplaceAllString(remaining, " ")
+	}
+	for _, token := range entropyTokenSplitter.Split(remaining, -1) {
+		if strings.HasPrefix(token, base64PEMPrefix) {
+			if decoded, err := base64.StdEncoding.DecodeString(token); err == nil && strings.Contains(string(decoded), "PRIVATE KEY") {
+				findings = append(findings, fmt.Sprintf("a base64 encoded private key: %s", redactSecret(token)))
+			}
+			continue
+		}
+		for _, candidate := range entropyCandidates(token) {
+			if secret := token[candidate[0]:candidate[1]]; isHighEntropyToken(secret) {
+				// The key of a key=value pair is kept to show where the secret is
+				evidence := redactSecret(secret)
+				if key := token[:candidate[0]]; strings.HasSuffix(key, "=") {
+					evidence = key + evidence
+				}
+				findings = append(findings, fmt.Sprintf("a high-entropy string: %s", evidence))
+			}
+		}
+	}
+	return findings
+}
+
+// getStringMap returns a map of strings, like unstructured.NestedStringMap, but accepting other values
+// since manifests often have unquoted numbers and booleans in ConfigMap data
+func getStringMap(obj map[string]interface{}, fields ...string) map[string]string {
+	values := map[string]string{}
+	m, _, _ := unstructured.NestedFieldNoCopy(obj, fields...)
+	mapValue, ok := m.(map[string]interface{})
+	if !ok {
+		return values
+	}
+	for key, value := range mapValue {
+		values[key] = fmt.Sprint(value)
+	}
+	return values
+}
+
+// scanStringMap scans the values of a map, e.g. ConfigMap data or annotations, in the order of their keys
+func scanStringMap(values map[string]string, location string, messages []string) []string {
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		// The last applied configuration duplicates the whole object, which is scanned anyway
+		if key == corev1.LastAppliedConfigAnnotation {
+			continue
+		}
+		for _, finding := range findSecrets(values[key]) {
+			messages = append(messages, fmt.Sprintf("%s %q contains %s", location, key, finding))
+		}
+	}
+	return messages
+}
+
+// sensitiveContentDetected looks for credentials in ConfigMap data, annotations, and the literal environment
+// variables, command and args of containers
+func sensitiveContentDetected(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	resource := test.Resource
+	// Secrets are where credentials belong
+	if resource.Kind == "Secret" || resource.Resource.Object == nil {
+		return true, nil, nil
+	}
+	messages := scanStringMap(resource.ObjectMeta.GetAnnotations(), "annotation", []string{})
+	if resource.Kind == "ConfigMap" {
+		messages = scanStringMap(getStringMap(resource.Resource.Object, "data"), "key", messages)
+	}
+	if podTemplate, ok := resource.PodTemplate.(map[string]interface{}); ok && resource.Kind != "Pod" {
+		messages = scanStringMap(getStringMap(podTemplate, "metadata", "annotations"), "pod annotation", messages)
+	}
+	if resource.PodSpec != nil {
+		for _, container := range getAllContainers(resource.PodSpec) {
+			env := map[string]string{}
+			for _, envVar := range container.Env {
+				if envVar.Value != "" {
+					env[envVar.Name] = envVar.Value
+				}
+			}
+			messages = scanStringMap(env, fmt.Sprintf("container %q env var", container.Name), messages)
+			for _, finding := range findSecrets(strings.Join(container.Command, " ")) {
+				messages = append(messages, fmt.Sprintf("container %q command contains %s", container.Name, finding))
+			}
+			for _, finding := range findSecrets(strings.Join(container.Args, " ")) {
+				messages = append(messages, fmt.Sprintf("container %q args contain %s", container.Name, finding))
+			}
+		}
+	}
+	issues := []jsonschema.ValError{}
+	for _, message := range funk.UniqString(messages) {
+		issues = append(issues, jsonschema.ValError{Message: message})
+	}
+	return len(issues) == 0, issues, nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+
+	"github.com/qri-io/jsonschema"
+	"github.com/thoas/go-funk"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+func init() {
+	registerCustomChecks("serviceAccountTokenReadsSecrets", serviceAccountTokenReadsSecrets)
+	registerCustomChecks("serviceAccountTokenEscalation", serviceAccountTokenEscalation)
+	// Workloads that don't mount a token, or whose ServiceAccount has no permissions, can't use the API
+	hasMountedServiceAccount := func(test schemaTestCase) bool {
+		return getMountedServiceAccount(test) != nil
+	}
+	registerCustomApplicability("serviceAccountTokenReadsSecrets", hasMountedServiceAccount)
+	registerCustomApplicability("serviceAccountTokenEscalation", hasMountedServiceAccount)
+}
+
+func getServiceAccountName(resource kube.GenericResource) string {
+	name := resource.PodSpec.ServiceAccountName
+	if name == "" {
+		name = resource.PodSpec.DeprecatedServiceAccount
+	}
+	if name == "" {
+		name = "default"
+	}
+	return name
+}
+
+// getWorkloadServiceAccount returns the RBAC analysis of the workload's ServiceAccount, without the
+// list of workloads using it
+func getWorkloadServiceAccount(audit *auditContext, resource kube.GenericResource) *RBACSubject {
+	if audit.getProvider() == nil || resource.PodSpec == nil {
+		return nil
+	}
+	subject := audit.getServiceAccountSubject(resource.ObjectMeta.GetNamespace(), getServiceAccountName(resource))
+	if subject != nil {
+		subject.Workloads = nil
+	}
+	return subject
+}
+
+// isServiceAccountTokenMounted returns true if the pods get a token for their ServiceAccount, either
+// automatically or through a projected volume
+func isServiceAccountTokenMounted(provider *kube.ResourceProvider, resource kube.GenericResource) bool {
+	podSpec := resource.PodSpec
+	for _, volume := range podSpec.Volumes {
+		if volume.Projected == nil {
+			continue
+		}
+		for _, source := range volume.Projected.Sources {
+			if source.ServiceAccountToken != nil {
+				return true
+			}
+		}
+	}
+	if podSpec.AutomountServiceAccountToken != nil {
+		return *podSpec.AutomountServiceAccountToken
+	}
+	serviceAccount := findResource(provider.Resources["ServiceAccount"], resource.ObjectMeta.GetNamespace(), getServiceAccountName(resource))
+	if serviceAccount != nil {
+		automount, found, _ := unstructured.NestedBool(serviceAccount.Resource.Object, "automountServiceAccountToken")
+		if found {
+			return automount
+		}
+	}
+	return true
+}
+
+// getMountedServiceAccount returns the workload's ServiceAccount if its token is mounted in the pods
+func getMountedServiceAccount(test schemaTestCase) *RBACSubject {
+	if test.Audit.getProvider() == nil || test.Resource.PodSpec == nil || !isServiceAccountTokenMounted(test.Audit.getProvider(), test.Resource) {
+		return nil
+	}
+	return getWorkloadServiceAccount(test.Audit, test.Resource)
+}
+
+func serviceAccountTokenReadsSecrets(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	serviceAccount := getMountedServiceAccount(test)
+	if serviceAccount == nil {
+		return true, nil, nil
+	}
+	issues := []jsonschema.ValError{}
+	messages := []string{}
+	for _, permission := range serviceAccount.Permissions {
+		if !rulesAllow([]rbacv1.PolicyRule{permission.Rule}, []string{"get", "list", "watch"}, "", "secrets") {
+			continue
+		}
+		message := fmt.Sprintf("%s can read secrets %s (%s)", serviceAccount.String(), permission.scope(), permission.Binding)
+		if funk.ContainsString(messages, message) {
+			continue
+		}
+		messages = append(messages, message)
+		issues = append(issues, jsonschema.ValError{
+			PropertyPath: "serviceAccountName",
+			InvalidValue: serviceAccount.Name,
+			Message:      message,
+		})
+	}
+	return len(issues) == 0, issues, nil
+}
+
+func serviceAccountTokenEscalation(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	serviceAccount := getMountedServiceAccount(test)
+	if serviceAccount == nil {
+		return true, nil, nil
+	}
+	issues := []jsonschema.ValError{}
+	for _, escalation := range serviceAccount.Escalations {
+		issues = append(issues, jsonschema.ValError{
+			PropertyPath: "serviceAccountName",
+			InvalidValue: serviceAccount.Name,
+			Message:      fmt.Sprintf("%s %s (%s)", serviceAccount.String(), escalation.Message, escalation.Binding),
+		})
+	}
+	return len(issues) == 0, issues, nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"fmt"
+
+	"github.com/qri-io/jsonschema"
+	"github.com/thoas/go-funk"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// storageClassAnnotation is the deprecated way of setting a PersistentVolumeClaim's StorageClass
+const storageClassAnnotation = "volume.beta.kubernetes.io/storage-class"
+
+func init() {
+	registerCustomChecks("hostPathVolumeSet", hostPathVolumeSet)
+	registerCustomChecks("hostPathVolumeWritable", hostPathVolumeWritable)
+	registerCustomChecks("statefulSetStorageClassMissing", statefulSetStorageClassMissing)
+	registerCustomApplicability("hostPathVolumeWritable", hasVolume(func(volume corev1.Volume) bool {
+		return volume.HostPath != nil
+	}))
+	registerCustomApplicability("emptyDirSizeLimitMissing", hasVolume(func(volume corev1.Volume) bool {
+		return volume.EmptyDir != nil
+	}))
+}
+
+// hasVolume returns an applicabilityFunction for checks that only apply to pods with a matching volume
+func hasVolume(matches func(volume corev1.Volume) bool) applicabilityFunction {
+	return func(test schemaTestCase) bool {
+		if test.Resource.PodSpec == nil {
+			return false
+		}
+		for _, volume := range test.Resource.PodSpec.Volumes {
+			if matches(volume) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// hostPathVolumeSet fails if the pod uses a hostPath volume
+func hostPathVolumeSet(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	if test.Resource.PodSpec == nil {
+		return true, nil, nil
+	}
+	issues := []jsonschema.ValError{}
+	for _, volume := range test.Resource.PodSpec.Volumes {
+		if volume.HostPath == nil {
+			continue
+		}
+		issues = append(issues, jsonschema.ValError{
+			PropertyPath: "volumes",
+			InvalidValue: volume.HostPath.Path,
+			Message:      fmt.Sprintf("volume %q uses hostPath %s", volume.Name, volume.HostPath.Path),
+		})
+	}
+	return len(issues) == 0, issues, nil
+}
+
+// hostPathVolumeWritable fails if a container mounts a hostPath volume without readOnly
+func hostPathVolumeWritable(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	if test.Resource.PodSpec == nil {
+		return true, nil, nil
+	}
+	hostPaths := map[string]string{}
+	for _, volume := range test.Resource.PodSpec.Volumes {
+		if volume.HostPath != nil {
+			hostPaths[volume.Name] = volume.HostPath.Path
+		}
+	}
+	issues := []jsonschema.ValError{}
+	for _, container := range getAllContainers(test.Resource.PodSpec) {
+		for _, mount := range container.VolumeMounts {
+			hostPath, ok := hostPaths[mount.Name]
+			if !ok || mount.ReadOnly {
+				continue
+			}
+			issues = append(issues, jsonschema.ValError{
+				PropertyPath: "volumeMounts",
+				InvalidValue: hostPath,
+				Message:      fmt.Sprintf("container %q mounts hostPath %s writable at %s", container.Name, hostPath, mount.MountPath),
+			})
+		}
+	}
+	return len(issues) == 0, issues, nil
+}
+
+// statefulSetStorageClassMissing fails if a volumeClaimTemplate relies on the default StorageClass, or uses one
+// that isn't in allowedStorageClasses
+func statefulSetStorageClassMissing(test schemaTestCase) (bool, []jsonschema.ValError, error) {
+	var allowed []string
+	if test.Config != nil {
+		allowed = test.Config.AllowedStorageClasses
+	}
+	templates, _ := getNestedSlice(test.Resource.Resource.Object, "spec", "volumeClaimTemplates")
+	issues := []jsonschema.ValError{}
+	for _, template := range templates {
+		templateMap, ok := template.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(templateMap, "metadata", "name")
+		storageClass, found, _ := unstructured.NestedString(templateMap, "spec", "storageClassName")
+		if !found {
+			storageClass, found, _ = unstructured.NestedString(templateMap, "metadata", "annotations", storageClassAnnotation)
+		}
+		// An empty storageClassName binds the claims to pre-provisioned volumes, without any StorageClass
+		if !found {
+			issues = append(issues, jsonschema.ValError{
+				PropertyPath: "spec.volumeClaimTemplates",
+				InvalidValue: name,
+				Message:      fmt.Sprintf("volumeClaimTemplate %q doesn't set storageClassName", name),
+			})
+		} else if storageClass != "" && len(allowed) > 0 && !funk.ContainsString(allowed, storageClass) {
+			issues = append(issues, jsonschema.ValError{
+				PropertyPath: "spec.volumeClaimTemplates",
+				InvalidValue: storageClass,
+				Message:      fmt.Sprintf("volumeClaimTemplate %q uses StorageClass %s, which is not in allowedStorageClasses", name, storageClass),
+			})
+		}
+	}
+	return len(issues) == 0, issues, nil
+}
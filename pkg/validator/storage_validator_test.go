@@ -0,0 +1,119 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	conf "github.com/fairwindsops/polaris/pkg/config"
+	"github.com/fairwindsops/polaris/pkg/kube"
+)
+
+const storageTestResources = `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  serviceName: db
+  selector:
+    matchLabels:
+      app: db
+  template:
+    metadata:
+      labels:
+        app: db
+    spec:
+      containers:
+        - name: db
+          image: postgres:16
+          volumeMounts:
+            - name: data
+              mountPath: /var/lib/postgresql/data
+            - name: host
+              mountPath: /host
+              readOnly: true
+        - name: backup
+          image: busybox:1.36
+          volumeMounts:
+            - name: host
+              mountPath: /backup
+            - name: docker
+              mountPath: /var/run/docker.sock
+      volumes:
+        - name: host
+          hostPath:
+            path: /mnt/backup
+        - name: docker
+          hostPath:
+            path: /var/run/docker.sock
+  volumeClaimTemplates:
+    - metadata:
+        name: data
+      spec:
+        storageClassName: gp2
+        resources:
+          requests:
+            storage: 10Gi
+    - metadata:
+        name: wal
+        annotations:
+          volume.beta.kubernetes.io/storage-class: fast
+      spec:
+        resources:
+          requests:
+            storage: 1Gi
+    - metadata:
+        name: scratch
+      spec:
+        resources:
+          requests:
+            storage: 1Gi
+`
+
+func TestStorageChecks(t *testing.T) {
+	c := conf.Configuration{
+		Checks: map[string]conf.Severity{
+			"statefulSetStorageClassMissing": conf.SeverityWarning,
+			"hostPathVolumeSet":              conf.SeverityWarning,
+			"hostPathVolumeWritable":         conf.SeverityDanger,
+			"sensitiveHostPathMounted":       conf.SeverityDanger,
+		},
+		AllowedStorageClasses: []string{"fast", "standard"},
+	}
+	provider, err := kube.CreateResourceProviderFromYaml(storageTestResources)
+	assert.NoError(t, err)
+	auditData, err := RunAudit(c, provider)
+	assert.NoError(t, err)
+	assert.Len(t, auditData.Results, 1)
+	results := auditData.Results[0].Results
+	assert.Equal(t, []string{
+		`volumeClaimTemplate "data" uses StorageClass gp2, which is not in allowedStorageClasses`,
+		`volumeClaimTemplate "scratch" doesn't set storageClassName`,
+	}, results["statefulSetStorageClassMissing"].Details)
+	assert.Equal(t, []string{
+		`container "backup" mounts hostPath /mnt/backup writable at /backup`,
+		`container "backup" mounts hostPath /var/run/docker.sock writable at /var/run/docker.sock`,
+	}, results["hostPathVolumeWritable"].Details)
+	assert.Equal(t, []string{
+		`volume "host" uses hostPath /mnt/backup`,
+		`volume "docker" uses hostPath /var/run/docker.sock`,
+	}, results["hostPathVolumeSet"].Details)
+	assert.Equal(t, []string{
+		`volume "docker" mounts sensitive host path /var/run/docker.sock`,
+	}, results["sensitiveHostPathMounted"].Details)
+}
@@ -70,7 +70,10 @@ func GetValidatedResults(kind string, decoder *admission.Decoder, req admission.
 		logrus.Errorf("Error unmarshaling JSON")
 		return nil, resource, err
 	}
-	if ownerReferences, ok := decoded["metadata"].(map[string]any)["ownerReferences"].([]any); ok && len(ownerReferences) > 0 {
+	if req.SubResource == "ephemeralcontainers" {
+		// Debug containers are only added to live pods, so they never show up on the owner
+		logrus.Infof("Ephemeral containers added to %s - running checks", req.Name)
+	} else if ownerReferences, ok := decoded["metadata"].(map[string]any)["ownerReferences"].([]any); ok && len(ownerReferences) > 0 {
 		allOwnersReferenceValid := true
 		dynamicClient, restMapper, _, _, err := kube.GetKubeClient(context.Background(), "")
 		if err != nil {
@@ -106,6 +109,12 @@ func GetValidatedResults(kind string, decoder *admission.Decoder, req admission.
 		logrus.Errorf("Failed to create resource: %v", err)
 		return nil, resource, err
 	}
+	if paths, ok := config.PodSpecPaths[resource.GroupKind()]; ok {
+		if err := resource.SetPodSpecPaths(paths); err != nil {
+			logrus.Errorf("Failed to find pod spec: %v", err)
+			return nil, resource, err
+		}
+	}
 	resourceResult, err := validator.ApplyAllSchemaChecks(&config, nil, resource)
 	if err != nil {
 		return nil, resource, err
@@ -152,9 +161,13 @@ func getFailureReason(result validator.Result) string {
 		}
 
 		for _, containerResult := range podResult.ContainerResults {
+			containerType := "Container"
+			if containerResult.ContainerClass == config.ContainerClassEphemeral {
+				containerType = "Ephemeral container"
+			}
 			for _, message := range containerResult.Results {
 				if !message.Success && message.Severity == config.SeverityDanger {
-					reason += fmt.Sprintf("- Container %s: %s\n", containerResult.Name, message.Message)
+					reason += fmt.Sprintf("- %s %s: %s\n", containerType, containerResult.Name, message.Message)
 				}
 			}
 		}
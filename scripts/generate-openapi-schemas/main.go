@@ -0,0 +1,195 @@
+// Copyright 2022 FairwindsOps, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// generate-openapi-schemas merges the OpenAPI documents of a Kubernetes release into the single OpenAPI v3
+// document the kubernetesSchema check embeds for that minor version, keeping only what validation needs.
+//
+// Usage:
+//
+//	go run ./scripts/generate-openapi-schemas -release 1.30
+//	go run ./scripts/generate-openapi-schemas -release 1.27 path/to/swagger.json
+//
+// Without files or URLs, the api/openapi-spec/v3 documents of the release branch are downloaded from GitHub.
+// OpenAPI v2 documents, like api/openapi-spec/swagger.json, are also accepted.
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	specDirectoryURL = "https://api.github.com/repos/kubernetes/kubernetes/contents/api/openapi-spec/v3?ref=release-%s"
+	outputDirectory  = "pkg/validator/openapi"
+)
+
+// keptFields are the schema fields used to validate manifests
+var keptFields = map[string]bool{
+	"type":                                 true,
+	"format":                               true,
+	"properties":                           true,
+	"required":                             true,
+	"items":                                true,
+	"additionalProperties":                 true,
+	"allOf":                                true,
+	"$ref":                                 true,
+	"x-kubernetes-group-version-kind":      true,
+	"x-kubernetes-int-or-string":           true,
+	"x-kubernetes-preserve-unknown-fields": true,
+	"x-kubernetes-embedded-resource":       true,
+}
+
+func main() {
+	release := flag.String("release", "", "Kubernetes minor version, e.g. 1.30")
+	flag.Parse()
+	if *release == "" {
+		logrus.Fatal("-release is required")
+	}
+	sources := flag.Args()
+	if len(sources) == 0 {
+		var err error
+		sources, err = listReleaseDocuments(*release)
+		if err != nil {
+			logrus.Fatalf("listing the OpenAPI documents of %s: %v", *release, err)
+		}
+	}
+	schemas := map[string]interface{}{}
+	for _, source := range sources {
+		contents, err := read(source)
+		if err != nil {
+			logrus.Fatalf("reading %s: %v", source, err)
+		}
+		doc := map[string]interface{}{}
+		if err := json.Unmarshal(contents, &doc); err != nil {
+			logrus.Fatalf("parsing %s: %v", source, err)
+		}
+		definitions, _ := doc["definitions"].(map[string]interface{})
+		if components, ok := doc["components"].(map[string]interface{}); ok {
+			definitions, _ = components["schemas"].(map[string]interface{})
+		}
+		for name, definition := range definitions {
+			schemas[name] = trim(definition)
+		}
+	}
+	output := map[string]interface{}{
+		"openapi": "3.0.0",
+		"info": map[string]interface{}{
+			"title":   "Kubernetes",
+			"version": "v" + *release,
+		},
+		"components": map[string]interface{}{
+			"schemas": schemas,
+		},
+	}
+	path := filepath.Join(outputDirectory, "v"+*release+".json.gz")
+	if err := write(path, output); err != nil {
+		logrus.Fatalf("writing %s: %v", path, err)
+	}
+	fmt.Printf("wrote %d schemas to %s\n", len(schemas), path)
+}
+
+// listReleaseDocuments returns the download URLs of the OpenAPI v3 documents of a release branch
+func listReleaseDocuments(release string) ([]string, error) {
+	contents, err := read(fmt.Sprintf(specDirectoryURL, release))
+	if err != nil {
+		return nil, err
+	}
+	files := []struct {
+		Name        string `json:"name"`
+		DownloadURL string `json:"download_url"`
+	}{}
+	if err := json.Unmarshal(contents, &files); err != nil {
+		return nil, err
+	}
+	urls := []string{}
+	for _, file := range files {
+		if strings.HasSuffix(file.Name, "_openapi.json") {
+			urls = append(urls, file.DownloadURL)
+		}
+	}
+	return urls, nil
+}
+
+func read(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "https://") {
+		return os.ReadFile(source)
+	}
+	resp, err := http.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// trim removes descriptions, defaults and other fields that don't affect validation, and points OpenAPI v2
+// references to the v3 components
+func trim(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		trimmed := map[string]interface{}{}
+		for key, field := range v {
+			if !keptFields[key] {
+				continue
+			}
+			switch key {
+			case "properties":
+				properties := map[string]interface{}{}
+				for name, property := range field.(map[string]interface{}) {
+					properties[name] = trim(property)
+				}
+				trimmed[key] = properties
+			case "$ref":
+				trimmed[key] = strings.Replace(field.(string), "#/definitions/", "#/components/schemas/", 1)
+			case "required", "x-kubernetes-group-version-kind":
+				trimmed[key] = field
+			default:
+				trimmed[key] = trim(field)
+			}
+		}
+		return trimmed
+	case []interface{}:
+		trimmed := make([]interface{}, len(v))
+		for idx, item := range v {
+			trimmed[idx] = trim(item)
+		}
+		return trimmed
+	}
+	return value
+}
+
+func write(path string, doc map[string]interface{}) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := gzip.NewWriter(file)
+	if err := json.NewEncoder(writer).Encode(doc); err != nil {
+		return err
+	}
+	return writer.Close()
+}
@@ -198,6 +198,7 @@ func SetupTestAPI(objects ...runtime.Object) (kubernetes.Interface, dynamic.Inte
 				{Name: "pods", Namespaced: true, Kind: "Pod"},
 				{Name: "serviceaccounts", Namespaced: true, Kind: "ServiceAccount"},
 				{Name: "configmaps", Namespaced: true, Kind: "ConfigMap"},
+				{Name: "services", Namespaced: true, Kind: "Service"},
 			},
 		},
 		{
@@ -42,6 +42,8 @@ func TestMutations(t *testing.T) {
 		}
 		for _, tc := range mutationTestCasesMap[mutationStr] {
 			newConfig := c
+			newConfig.ImagePolicy = tc.config.ImagePolicy
+			newConfig.KubernetesVersion = tc.config.KubernetesVersion
 			key := fmt.Sprintf("%s/%s", tc.check, strings.ReplaceAll(tc.filename, "failure", "mutated"))
 			mutatedYamlContent, ok := mutatedYamlContentMap[key]
 			assert.True(t, ok)
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 	"testing"
@@ -34,10 +35,15 @@ type testCase struct {
 	filename  string
 	resources *kube.ResourceProvider
 	failure   bool
-	config    config.Configuration
-	manifest  string
+	// notApplicable test cases expect the check to be left out of the results
+	notApplicable bool
+	config        config.Configuration
+	manifest      string
 }
 
+// kubernetesVersionPattern matches the Kubernetes version a test case runs against, e.g. success.k8s-1.29.yaml
+var kubernetesVersionPattern = regexp.MustCompile(`\.k8s-(\d+\.\d+)\.`)
+
 func initTestCases() ([]testCase, map[string]string, map[string][]testCase) {
 	checkToTest := os.Getenv("POLARIS_CHECK_TEST") // if set, only run tests for this check
 	_, baseDir, _, _ := runtime.Caller(0)
@@ -76,14 +82,23 @@ func initTestCases() ([]testCase, map[string]string, map[string][]testCase) {
 			configString += "\ncustomChecks:\n  " + check + ":\n"
 			configString += strings.Join(lines, "\n")
 		}
+		// config.yaml holds settings used by the check, e.g. its allow lists
+		extraConfig, err := os.ReadFile(checkDir + "/config.yaml")
+		if err == nil {
+			configString += "\n" + string(extraConfig)
+		}
 		c, err := config.Parse([]byte(configString))
 		if err != nil {
 			panic(err)
 		}
 		for _, tc := range cases {
-			if tc.Name() == "check.yaml" {
+			if tc.Name() == "check.yaml" || tc.Name() == "config.yaml" {
 				continue
 			}
+			if !strings.HasPrefix(tc.Name(), "success") && !strings.HasPrefix(tc.Name(), "failure") && !strings.HasPrefix(tc.Name(), "mutated") &&
+				!strings.HasPrefix(tc.Name(), "notapplicable") {
+				continue // other files are used by the check, e.g. a lock file referenced in config.yaml
+			}
 			yamlContent, err := os.ReadFile(checkDir + "/" + tc.Name())
 			if err != nil {
 				panic(err)
@@ -95,12 +110,16 @@ func initTestCases() ([]testCase, map[string]string, map[string][]testCase) {
 				panic(err)
 			}
 			testcase := testCase{
-				filename:  tc.Name(),
-				check:     check,
-				resources: resources,
-				failure:   strings.Contains(resourceFilename, "failure"),
-				config:    c,
-				manifest:  string(yamlContent),
+				filename:      tc.Name(),
+				check:         check,
+				resources:     resources,
+				failure:       strings.Contains(resourceFilename, "failure"),
+				notApplicable: strings.HasPrefix(resourceFilename, "notapplicable"),
+				config:        c,
+				manifest:      string(yamlContent),
+			}
+			if match := kubernetesVersionPattern.FindStringSubmatch(tc.Name()); match != nil {
+				testcase.config.KubernetesVersion = match[1]
 			}
 
 			if strings.Contains(tc.Name(), "mutated") {
@@ -130,6 +149,10 @@ func TestChecks(t *testing.T) {
 		auditData := validator.AuditData{Results: results}
 		summary := auditData.GetSummary()
 		total := summary.Successes + summary.Dangers
+		if tc.notApplicable {
+			assert.Equal(t, uint(0), total, "Check "+tc.check+" applied unexpectedly to "+tc.filename)
+			continue
+		}
 		msg := fmt.Sprintf("Check %s ran %d times instead of 1", tc.check, total)
 		if assert.LessOrEqual(t, uint(1), total, msg) {
 			if tc.failure {